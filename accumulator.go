@@ -31,4 +31,39 @@ type Accumulator interface {
 	SetPrecision(precision, interval time.Duration)
 
 	AddError(err error)
+
+	// WithTracking upgrades to a TrackingAccumulator with space for maxTracked
+	// metric groups to be undelivered at once.
+	WithTracking(maxTracked int) TrackingAccumulator
+}
+
+// TrackingID uniquely identifies a tracked group of metrics.
+type TrackingID uint64
+
+// DeliveryInfo is the result of the delivery of a tracked group of metrics.
+type DeliveryInfo interface {
+	// ID is the TrackingID of the group
+	ID() TrackingID
+
+	// Delivered returns true if all metrics of the group were written by
+	// every output, or were filtered or aggregated away. It is false if any
+	// metric was dropped by an output, eg. when its buffer overflowed.
+	Delivered() bool
+}
+
+// TrackingAccumulator is an Accumulator that reports when groups of metrics
+// have been fully processed by the outputs, so that inputs can acknowledge
+// the messages the metrics were read from.
+type TrackingAccumulator interface {
+	Accumulator
+
+	// AddTrackingMetricGroup adds a group of metrics, returning its
+	// TrackingID. The delivery of the group is reported on the Delivered
+	// channel once every metric of the group has been processed.
+	AddTrackingMetricGroup(group []Metric) TrackingID
+
+	// Delivered returns the channel the delivery of each group is reported
+	// on. Inputs must read it, and add no more than maxTracked groups which
+	// have not been reported yet.
+	Delivered() <-chan DeliveryInfo
 }
@@ -6,10 +6,12 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
 )
 
 type MetricMaker interface {
 	Name() string
+	LogName() string
 	MakeMetric(
 		measurement string,
 		fields map[string]interface{},
@@ -38,6 +40,11 @@ type accumulator struct {
 
 	precision time.Duration
 
+	// tick and tickEnd are the tick the input is gathered for, and the
+	// next one, when its timestamps are aligned to the tick.
+	tick    time.Time
+	tickEnd time.Time
+
 	errCount uint64
 }
 
@@ -82,7 +89,7 @@ func (ac *accumulator) AddError(err error) {
 	}
 	atomic.AddUint64(&ac.errCount, 1)
 	//TODO suppress/throttle consecutive duplicate errors?
-	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
+	log.Printf("E! Error in plugin [%s]: %s", ac.maker.LogName(), err)
 }
 
 // SetPrecision takes two time.Duration objects. If the first is non-zero,
@@ -106,6 +113,15 @@ func (ac *accumulator) SetPrecision(precision, interval time.Duration) {
 	}
 }
 
+// alignTo aligns the timestamps of the metrics to the tick the input is
+// gathered for, so that they do not depend on how long the input takes to
+// gather. Only the timestamps within the interval following the tick are
+// aligned, the others not being the time of the gather.
+func (ac *accumulator) alignTo(tick time.Time, interval time.Duration) {
+	ac.tick = tick
+	ac.tickEnd = tick.Add(interval)
+}
+
 func (ac accumulator) getTime(t []time.Time) time.Time {
 	var timestamp time.Time
 	if len(t) > 0 {
@@ -113,5 +129,53 @@ func (ac accumulator) getTime(t []time.Time) time.Time {
 	} else {
 		timestamp = time.Now()
 	}
+	if !ac.tick.IsZero() &&
+		!timestamp.Before(ac.tick) && timestamp.Before(ac.tickEnd) {
+		timestamp = ac.tick
+	}
 	return timestamp.Round(ac.precision)
 }
+
+func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+// AddTrackingMetricGroup makes the metrics of the group as AddFields would,
+// and adds them tracked as one group.
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	made := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		if m := a.maker.MakeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(), a.getTime([]time.Time{m.Time()})); m != nil {
+			made = append(made, m)
+		}
+	}
+
+	tracked, id := models.NewTrackingMetricGroup(made, a.onDelivered)
+	for _, m := range tracked {
+		a.metrics <- m
+	}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+func (a *trackingAccumulator) onDelivered(info telegraf.DeliveryInfo) {
+	select {
+	case a.delivered <- info:
+	default:
+		// the input added more groups than it has room for, and can't be
+		// waiting on their delivery.
+		log.Printf("E! Input [%s] has more undelivered metric groups than "+
+			"it tracks, dropping delivery notification", a.maker.LogName())
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -240,6 +241,31 @@ func TestDifferentPrecisions(t *testing.T) {
 		actual)
 }
 
+func TestAddAlignedToTick(t *testing.T) {
+	tick := time.Unix(1490000000, 0)
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics)
+	a.alignTo(tick, 10*time.Second)
+
+	fields := map[string]interface{}{"value": float64(101)}
+	a.AddFields("acctest", fields, nil, tick.Add(1500*time.Millisecond))
+	a.AddGauge("acctest", fields, nil, tick.Add(9*time.Second))
+	a.AddCounter("acctest", fields, nil, tick.Add(-time.Second))
+	a.AddFields("acctest", fields, nil, tick.Add(10*time.Second))
+
+	assert.Equal(t, tick, (<-metrics).Time())
+	assert.Equal(t, tick, (<-metrics).Time())
+	// timestamps outside of the interval of the gather are kept
+	assert.Equal(t, tick.Add(-time.Second), (<-metrics).Time())
+	assert.Equal(t, tick.Add(10*time.Second), (<-metrics).Time())
+
+	// metrics without timestamps are gathered within the interval
+	a.alignTo(time.Now().Add(-time.Second), time.Minute)
+	a.AddFields("acctest", fields, nil)
+	assert.Equal(t, a.tick.UnixNano(), (<-metrics).Time().UnixNano())
+}
+
 func TestAddGauge(t *testing.T) {
 	now := time.Now()
 	metrics := make(chan telegraf.Metric, 10)
@@ -308,9 +334,46 @@ func TestAddCounter(t *testing.T) {
 	assert.Equal(t, testm.Type(), telegraf.Counter)
 }
 
+func TestAddTrackingMetricGroup(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics).WithTracking(1)
+
+	now := time.Now()
+	m1, _ := telegraf.NewMetric("acctest",
+		map[string]string{"acc": "test"},
+		map[string]interface{}{"value": float64(101)}, now)
+	m2, _ := telegraf.NewCounterMetric("acctest",
+		map[string]string{"acc": "test"},
+		map[string]interface{}{"value": float64(102)}, now)
+	id := a.AddTrackingMetricGroup([]telegraf.Metric{m1, m2})
+
+	testm := <-metrics
+	assert.Equal(t,
+		fmt.Sprintf("acctest,acc=test value=101 %d", now.UnixNano()),
+		testm.String())
+	models.Accept(testm)
+
+	testm = <-metrics
+	assert.Equal(t,
+		fmt.Sprintf("acctest,acc=test value=102 %d", now.UnixNano()),
+		testm.String())
+	assert.Equal(t, telegraf.Counter, testm.Type())
+	assert.Len(t, a.Delivered(), 0)
+	models.Accept(testm)
+
+	info := <-a.Delivered()
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
+
 type TestMetricMaker struct {
 }
 
+func (tm *TestMetricMaker) LogName() string {
+	return tm.Name()
+}
+
 func (tm *TestMetricMaker) Name() string {
 	return "TestPlugin"
 }
@@ -1,7 +1,6 @@
 package agent
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"runtime"
@@ -50,28 +49,35 @@ func (a *Agent) Connect() error {
 		case telegraf.ServiceOutput:
 			if err := ot.Start(); err != nil {
 				log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
+					o.LogName(), err.Error())
 				return err
 			}
 		}
 
-		log.Printf("D! Attempting connection to output: %s\n", o.Name)
+		log.Printf("D! Attempting connection to output: %s\n", o.LogName())
 		err := o.Output.Connect()
 		if err != nil {
 			log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
+				"error was '%s' \n", o.LogName(), err)
 			time.Sleep(15 * time.Second)
 			err = o.Output.Connect()
 			if err != nil {
 				return err
 			}
 		}
-		log.Printf("D! Successfully connected to output: %s\n", o.Name)
+		log.Printf("D! Successfully connected to output: %s\n", o.LogName())
 	}
 	return nil
 }
 
-// Close closes the connection to all configured outputs
+// stopper is implemented by processors which need to be stopped, such as
+// the ones running external processes.
+type stopper interface {
+	Stop()
+}
+
+// Close closes the connection to all configured outputs, and stops the
+// processors which need to be stopped.
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.Config.Outputs {
@@ -81,6 +87,11 @@ func (a *Agent) Close() error {
 			ot.Stop()
 		}
 	}
+	for _, p := range a.Config.Processors {
+		if s, ok := p.Processor.(stopper); ok {
+			s.Stop()
+		}
+	}
 	return err
 }
 
@@ -89,7 +100,7 @@ func panicRecover(input *models.RunningInput) {
 		trace := make([]byte, 2048)
 		runtime.Stack(trace, true)
 		log.Printf("E! FATAL: Input [%s] panicked: %s, Stack:\n%s\n",
-			input.Name(), err, trace)
+			input.LogName(), err, trace)
 		log.Println("E! PLEASE REPORT THIS PANIC ON GITHUB with " +
 			"stack trace, configuration, and OS information: " +
 			"https://github.com/influxdata/telegraf/issues/new")
@@ -97,7 +108,8 @@ func panicRecover(input *models.RunningInput) {
 }
 
 // gatherer runs the inputs that have been configured with their own
-// reporting interval.
+// reporting interval. When the timestamps of the input are aligned, the input
+// is gathered on the multiples of its interval.
 func (a *Agent) gatherer(
 	shutdown chan struct{},
 	input *models.RunningInput,
@@ -106,24 +118,27 @@ func (a *Agent) gatherer(
 ) {
 	defer panicRecover(input)
 
+	if input.Config.AlignTimestamps {
+		for {
+			// the ticks missed while gathering are skipped, rather than
+			// gathering late, off the multiples of the interval.
+			tick := nextTick(time.Now(), interval)
+			timer := time.NewTimer(tick.Sub(time.Now()))
+			select {
+			case <-shutdown:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			a.gather(shutdown, input, interval, metricC, tick)
+		}
+	}
+
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
-		acc := NewAccumulator(input, metricC)
-		acc.SetPrecision(a.Config.Agent.Precision.Duration,
-			a.Config.Agent.Interval.Duration)
-		input.SetDebug(a.Config.Agent.Debug)
-		input.SetDefaultTags(a.Config.Tags)
-
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
-
-		start := time.Now()
-		gatherWithTimeout(shutdown, input, acc, interval)
-		elapsed := time.Since(start)
-
-		log.Printf("D! Input [%s] gathered metrics, (%s interval) in %s\n",
-			input.Name(), interval, elapsed)
+		a.gather(shutdown, input, interval, metricC, time.Now())
 
 		select {
 		case <-shutdown:
@@ -134,6 +149,42 @@ func (a *Agent) gatherer(
 	}
 }
 
+// gather gathers from the input once, for the given tick. The timestamps of
+// the metrics gathered are aligned to the tick if the input is configured
+// to.
+func (a *Agent) gather(
+	shutdown chan struct{},
+	input *models.RunningInput,
+	interval time.Duration,
+	metricC chan telegraf.Metric,
+	tick time.Time,
+) {
+	acc := NewAccumulator(input, metricC)
+	acc.SetPrecision(a.Config.Agent.Precision.Duration,
+		a.Config.Agent.Interval.Duration)
+	if input.Config.AlignTimestamps {
+		acc.alignTo(tick, interval)
+	}
+	input.SetDebug(a.Config.Agent.Debug)
+	input.SetDefaultTags(a.Config.Tags)
+
+	internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
+
+	start := time.Now()
+	gatherWithTimeout(shutdown, input, acc, interval)
+	elapsed := time.Since(start)
+
+	log.Printf("D! Input [%s] gathered metrics, (%s interval) in %s\n",
+		input.LogName(), interval, elapsed)
+}
+
+// nextTick returns the first multiple of the interval, since the epoch,
+// after now.
+func nextTick(now time.Time, interval time.Duration) time.Time {
+	i := int64(interval)
+	return time.Unix(0, now.UnixNano()-now.UnixNano()%i+i)
+}
+
 // gatherWithTimeout gathers from the given input, with the given timeout.
 //   when the given timeout is reached, gatherWithTimeout logs an error message
 //   but continues waiting for it to return. This is to avoid leaving behind
@@ -156,13 +207,13 @@ func gatherWithTimeout(
 		select {
 		case err := <-done:
 			if err != nil {
-				log.Printf("E! ERROR in input [%s]: %s", input.Name(), err)
+				log.Printf("E! ERROR in input [%s]: %s", input.LogName(), err)
 			}
 			return
 		case <-ticker.C:
 			log.Printf("E! ERROR: input [%s] took longer to collect than "+
 				"collection interval (%s)",
-				input.Name(), timeout)
+				input.LogName(), timeout)
 			continue
 		case <-shutdown:
 			return
@@ -170,59 +221,6 @@ func gatherWithTimeout(
 	}
 }
 
-// Test verifies that we can 'Gather' from all inputs with their configured
-// Config struct
-func (a *Agent) Test() error {
-	shutdown := make(chan struct{})
-	defer close(shutdown)
-	metricC := make(chan telegraf.Metric)
-
-	// dummy receiver for the point channel
-	go func() {
-		for {
-			select {
-			case <-metricC:
-				// do nothing
-			case <-shutdown:
-				return
-			}
-		}
-	}()
-
-	for _, input := range a.Config.Inputs {
-		acc := NewAccumulator(input, metricC)
-		acc.SetPrecision(a.Config.Agent.Precision.Duration,
-			a.Config.Agent.Interval.Duration)
-		input.SetTrace(true)
-		input.SetDefaultTags(a.Config.Tags)
-
-		fmt.Printf("* Plugin: %s, Collection 1\n", input.Name())
-		if input.Config.Interval != 0 {
-			fmt.Printf("* Internal: %s\n", input.Config.Interval)
-		}
-
-		if err := input.Input.Gather(acc); err != nil {
-			return err
-		}
-		if acc.errCount > 0 {
-			return fmt.Errorf("Errors encountered during processing")
-		}
-
-		// Special instructions for some inputs. cpu, for example, needs to be
-		// run twice in order to return cpu usage percentages.
-		switch input.Name() {
-		case "cpu", "mongodb", "procstat":
-			time.Sleep(500 * time.Millisecond)
-			fmt.Printf("* Plugin: %s, Collection 2\n", input.Name())
-			if err := input.Input.Gather(acc); err != nil {
-				return err
-			}
-		}
-
-	}
-	return nil
-}
-
 // flush writes a list of metrics to all configured outputs
 func (a *Agent) flush() {
 	var wg sync.WaitGroup
@@ -234,7 +232,7 @@ func (a *Agent) flush() {
 			err := output.Write()
 			if err != nil {
 				log.Printf("E! Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
+					output.LogName(), err.Error())
 			}
 		}(o)
 	}
@@ -269,20 +267,18 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 				var dropOriginal bool
 				if !m.IsAggregate() {
 					for _, agg := range a.Config.Aggregators {
-						if ok := agg.Add(copyMetric(m)); ok {
+						if ok := agg.Add(m.Copy()); ok {
 							dropOriginal = true
 						}
 					}
 				}
-				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
-							o.AddMetric(m)
-						} else {
-							o.AddMetric(copyMetric(m))
-						}
-					}
+				if dropOriginal || len(a.Config.Outputs) == 0 {
+					// the metric is done with, as far as its delivery is
+					// tracked.
+					models.Accept(m)
+					continue
 				}
+				addToOutputs(m, a.Config.Outputs)
 			}
 		}
 	}()
@@ -306,6 +302,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 			for _, processor := range a.Config.Processors {
 				mS = processor.Apply(mS...)
 			}
+			mS = models.TrackProcessed(metric, mS)
 			for _, m := range mS {
 				outMetricC <- m
 			}
@@ -336,7 +333,7 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 			input.SetDefaultTags(a.Config.Tags)
 			if err := p.Start(acc); err != nil {
 				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
+					input.LogName(), err.Error())
 				return err
 			}
 			defer p.Stop()
@@ -385,19 +382,3 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	wg.Wait()
 	return nil
 }
-
-func copyMetric(m telegraf.Metric) telegraf.Metric {
-	t := time.Time(m.Time())
-
-	tags := make(map[string]string)
-	fields := make(map[string]interface{})
-	for k, v := range m.Tags() {
-		tags[k] = v
-	}
-	for k, v := range m.Fields() {
-		fields[k] = v
-	}
-
-	out, _ := telegraf.NewMetric(m.Name(), tags, fields, t)
-	return out
-}
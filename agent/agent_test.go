@@ -2,6 +2,7 @@ package agent
 
 import (
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/internal/config"
 
@@ -109,3 +110,20 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+func TestNextTick(t *testing.T) {
+	interval := 10 * time.Second
+	tick := time.Unix(1490000000, 0)
+
+	assert.Equal(t, tick.Add(interval), nextTick(tick, interval))
+	assert.Equal(t, tick.Add(interval),
+		nextTick(tick.Add(time.Nanosecond), interval))
+	assert.Equal(t, tick.Add(interval),
+		nextTick(tick.Add(9*time.Second), interval))
+	// ticks missed while gathering are skipped
+	assert.Equal(t, tick.Add(3*interval),
+		nextTick(tick.Add(25*time.Second), interval))
+
+	assert.Equal(t, time.Unix(1490000040, 0),
+		nextTick(time.Unix(1490000011, 0), time.Minute))
+}
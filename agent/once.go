@@ -0,0 +1,262 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Test gathers from all inputs once, printing the metrics gathered, and
+// then prints the metrics each output would be written once they went
+// through the processors, the aggregators and the filters of the output.
+// The metrics are printed in the data format of the output, or in line
+// protocol for outputs without a data format. Service inputs are run for
+// the given wait duration.
+func (a *Agent) Test(wait time.Duration) error {
+	var outputs []*models.RunningOutput
+	var printers []*printOutput
+	for _, o := range a.Config.Outputs {
+		serializer := o.Serializer
+		if serializer == nil {
+			serializer, _ = serializers.NewInfluxSerializer()
+		}
+		printer := &printOutput{serializer: serializer}
+		ro := models.NewRunningOutput(o.Name, printer, o.Config,
+			a.Config.Agent.MetricBatchSize, a.Config.Agent.MetricBufferLimit)
+		ro.Quiet = true
+		outputs = append(outputs, ro)
+		printers = append(printers, printer)
+	}
+
+	if err := a.gatherOnce(wait, outputs, true); err != nil {
+		return err
+	}
+
+	for i, o := range outputs {
+		if err := o.Write(); err != nil {
+			return err
+		}
+		fmt.Printf("* Output: %s\n", o.LogName())
+		for _, line := range printers[i].lines {
+			fmt.Printf("> %s\n", line)
+		}
+	}
+	return nil
+}
+
+// Once gathers from all inputs once, running service inputs for the given
+// wait duration, and writes the metrics to the outputs, once they went
+// through the processors and the aggregators.
+func (a *Agent) Once(wait time.Duration) error {
+	if err := a.Connect(); err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if err := a.gatherOnce(wait, a.Config.Outputs, false); err != nil {
+		return err
+	}
+
+	var err error
+	for _, o := range a.Config.Outputs {
+		if werr := o.Write(); werr != nil {
+			log.Printf("E! Error writing to output [%s]: %s\n", o.LogName(), werr)
+			err = werr
+		}
+	}
+	return err
+}
+
+// gatherOnce gathers from all inputs once, running the service inputs for
+// wait, and adds the metrics to the outputs, once they went through the
+// processors and the aggregators. The metrics of the inputs are printed when
+// trace is set.
+func (a *Agent) gatherOnce(
+	wait time.Duration,
+	outputs []*models.RunningOutput,
+	trace bool,
+) error {
+	metricC, collected := collect()
+
+	var services []telegraf.ServiceInput
+	defer func() {
+		for _, service := range services {
+			service.Stop()
+		}
+	}()
+	for _, input := range a.Config.Inputs {
+		if service, ok := input.Input.(telegraf.ServiceInput); ok {
+			acc := NewAccumulator(input, metricC)
+			acc.SetPrecision(time.Nanosecond, 0)
+			input.SetTrace(trace)
+			input.SetDefaultTags(a.Config.Tags)
+			if err := service.Start(acc); err != nil {
+				return fmt.Errorf("Service for input %s failed to start: %s",
+					input.LogName(), err)
+			}
+			services = append(services, service)
+		}
+	}
+	if len(services) > 0 {
+		time.Sleep(wait)
+	}
+
+	for _, input := range a.Config.Inputs {
+		acc := NewAccumulator(input, metricC)
+		acc.SetPrecision(a.Config.Agent.Precision.Duration,
+			a.Config.Agent.Interval.Duration)
+		input.SetDefaultTags(a.Config.Tags)
+
+		if trace {
+			fmt.Printf("* Plugin: %s\n", input.LogName())
+			if input.Config.Interval != 0 {
+				fmt.Printf("* Internal: %s\n", input.Config.Interval)
+			}
+		}
+
+		// Inputs computing their metrics from the previous gather, such as
+		// cpu usage percentages, are gathered twice, the metrics of the
+		// first gather being discarded.
+		if d, ok := input.Input.(telegraf.DeltaInput); ok && d.GathersDeltas() {
+			discardC, discard := collect()
+			input.SetTrace(false)
+			err := input.Input.Gather(NewAccumulator(input, discardC))
+			discard()
+			if err != nil {
+				return err
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+
+		input.SetTrace(trace)
+		if err := input.Input.Gather(acc); err != nil {
+			return err
+		}
+		if acc.errCount > 0 {
+			return fmt.Errorf("Errors encountered during processing")
+		}
+	}
+
+	for _, service := range services {
+		service.Stop()
+	}
+	services = nil
+
+	a.process(collected(), outputs)
+
+	// the aggregates of all metrics are pushed at once, as if the period
+	// ended, and go through the processors to the outputs as well.
+	aggC, aggregated := collect()
+	for _, agg := range a.Config.Aggregators {
+		acc := NewAccumulator(agg, aggC)
+		acc.SetPrecision(a.Config.Agent.Precision.Duration,
+			a.Config.Agent.Interval.Duration)
+		agg.Push(acc)
+	}
+	a.process(aggregated(), outputs)
+	return nil
+}
+
+// process runs the metrics through the processors, applies them to the
+// aggregators, which are not running, and adds them to the outputs unless
+// an aggregator drops the original metrics.
+func (a *Agent) process(
+	metrics []telegraf.Metric,
+	outputs []*models.RunningOutput,
+) {
+	for _, metric := range metrics {
+		mS := []telegraf.Metric{metric}
+		for _, processor := range a.Config.Processors {
+			mS = processor.Apply(mS...)
+		}
+		for _, m := range models.TrackProcessed(metric, mS) {
+			var dropOriginal bool
+			if !m.IsAggregate() {
+				for _, agg := range a.Config.Aggregators {
+					if ok := agg.Apply(m.Copy()); ok {
+						dropOriginal = true
+					}
+				}
+			}
+			if dropOriginal || len(outputs) == 0 {
+				models.Accept(m)
+				continue
+			}
+			addToOutputs(m, outputs)
+		}
+	}
+}
+
+// addToOutputs adds the metric to the outputs, each of them getting its own
+// copy of the metric but the last one.
+func addToOutputs(m telegraf.Metric, outputs []*models.RunningOutput) {
+	for i, o := range outputs {
+		if i == len(outputs)-1 {
+			o.AddMetric(m)
+		} else {
+			o.AddMetric(models.TrackCopy(m, m.Copy()))
+		}
+	}
+}
+
+// collect returns a channel to accumulate metrics to, and a function
+// returning the metrics sent to the channel so far, to be called once.
+func collect() (chan telegraf.Metric, func() []telegraf.Metric) {
+	metricC := make(chan telegraf.Metric, 100)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	var metrics []telegraf.Metric
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case m := <-metricC:
+				metrics = append(metrics, m)
+			case <-stop:
+				for {
+					select {
+					case m := <-metricC:
+						metrics = append(metrics, m)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return metricC, func() []telegraf.Metric {
+		close(stop)
+		<-done
+		return metrics
+	}
+}
+
+// printOutput is the output which the outputs are replaced with in test
+// mode, keeping the metrics serialized in the data format of the output to
+// print them.
+type printOutput struct {
+	serializer serializers.Serializer
+	lines      []string
+}
+
+func (p *printOutput) Connect() error       { return nil }
+func (p *printOutput) Close() error         { return nil }
+func (p *printOutput) SampleConfig() string { return "" }
+func (p *printOutput) Description() string  { return "" }
+
+func (p *printOutput) Write(metrics []telegraf.Metric) error {
+	for _, metric := range metrics {
+		values, err := p.serializer.Serialize(metric)
+		if err != nil {
+			return err
+		}
+		p.lines = append(p.lines, values...)
+	}
+	return nil
+}
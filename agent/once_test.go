@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type onceInput struct{}
+
+func (i *onceInput) SampleConfig() string { return "" }
+func (i *onceInput) Description() string  { return "" }
+func (i *onceInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("cpu", map[string]interface{}{"usage": float64(1)}, nil)
+	acc.AddFields("mem", map[string]interface{}{"used": int64(2)}, nil)
+	return nil
+}
+
+// deltaInput only gathers its metric from its second gather on.
+type deltaInput struct {
+	onceInput
+	gathers int
+}
+
+func (i *deltaInput) GathersDeltas() bool { return true }
+func (i *deltaInput) Gather(acc telegraf.Accumulator) error {
+	i.gathers++
+	if i.gathers > 1 {
+		acc.AddFields("delta", map[string]interface{}{"rate": float64(1)}, nil)
+	} else {
+		acc.AddFields("first", map[string]interface{}{"count": int64(1)}, nil)
+	}
+	return nil
+}
+
+type onceServiceInput struct {
+	onceInput
+	stopped bool
+}
+
+func (i *onceServiceInput) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func (i *onceServiceInput) Start(acc telegraf.Accumulator) error {
+	acc.AddFields("disk", map[string]interface{}{"free": int64(3)}, nil)
+	return nil
+}
+
+func (i *onceServiceInput) Stop() {
+	i.stopped = true
+}
+
+type onceProcessor struct{}
+
+func (p *onceProcessor) SampleConfig() string { return "" }
+func (p *onceProcessor) Description() string  { return "" }
+func (p *onceProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.AddTag("processed", "true")
+	}
+	return in
+}
+
+type onceAggregator struct {
+	count int64
+}
+
+func (a *onceAggregator) SampleConfig() string { return "" }
+func (a *onceAggregator) Description() string  { return "" }
+func (a *onceAggregator) Add(in telegraf.Metric) {
+	a.count++
+}
+func (a *onceAggregator) Push(acc telegraf.Accumulator) {
+	acc.AddFields("count", map[string]interface{}{"count": a.count}, nil)
+}
+func (a *onceAggregator) Reset() {
+	a.count = 0
+}
+
+type onceOutput struct {
+	sync.Mutex
+	metrics []telegraf.Metric
+}
+
+func (o *onceOutput) Connect() error       { return nil }
+func (o *onceOutput) Close() error         { return nil }
+func (o *onceOutput) SampleConfig() string { return "" }
+func (o *onceOutput) Description() string  { return "" }
+func (o *onceOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func (o *onceOutput) names() map[string]map[string]string {
+	o.Lock()
+	defer o.Unlock()
+	names := make(map[string]map[string]string)
+	for _, m := range o.metrics {
+		names[m.Name()] = m.Tags()
+	}
+	return names
+}
+
+func TestAgent_Once(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	service := &onceServiceInput{}
+	c.Inputs = []*models.RunningInput{
+		{Input: &onceInput{}, Config: &models.InputConfig{Name: "once"}},
+		{Input: service, Config: &models.InputConfig{Name: "once_service"}},
+	}
+	c.Processors = models.RunningProcessors{{
+		Name:      "once",
+		Processor: &onceProcessor{},
+		Config:    &models.ProcessorConfig{Name: "once"},
+	}}
+
+	aggFilter := models.Filter{NamePass: []string{"cpu"}}
+	require.NoError(t, aggFilter.Compile())
+	c.Aggregators = []*models.RunningAggregator{
+		models.NewRunningAggregator(&onceAggregator{}, &models.AggregatorConfig{
+			Name:         "once",
+			DropOriginal: true,
+			Filter:       aggFilter,
+		}),
+	}
+
+	all := &onceOutput{}
+	outFilter := models.Filter{NameDrop: []string{"count"}}
+	require.NoError(t, outFilter.Compile())
+	filtered := &onceOutput{}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput("all", all,
+			&models.OutputConfig{Name: "all"}, 0, 0),
+		models.NewRunningOutput("filtered", filtered,
+			&models.OutputConfig{Name: "filtered", Filter: outFilter}, 0, 0),
+	}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Once(0))
+	assert.True(t, service.stopped)
+
+	// cpu is dropped by the aggregator, and the aggregate is processed too.
+	processed := map[string]string{"processed": "true"}
+	assert.Equal(t, map[string]map[string]string{
+		"mem":   processed,
+		"disk":  processed,
+		"count": processed,
+	}, all.names())
+	assert.Equal(t, map[string]map[string]string{
+		"mem":  processed,
+		"disk": processed,
+	}, filtered.names())
+}
+
+func TestAgent_OnceDeltaInput(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	input := &deltaInput{}
+	c.Inputs = []*models.RunningInput{
+		{Input: input, Config: &models.InputConfig{Name: "delta"}},
+	}
+	out := &onceOutput{}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput("out", out, &models.OutputConfig{Name: "out"}, 0, 0),
+	}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	require.NoError(t, a.Once(0))
+
+	// the metrics of the first gather are discarded.
+	assert.Equal(t, 2, input.gathers)
+	assert.Equal(t, map[string]map[string]string{
+		"delta": {},
+	}, out.names())
+}
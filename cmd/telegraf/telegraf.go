@@ -27,9 +27,20 @@ var fDebug = flag.Bool("debug", false,
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fTestWait = flag.Duration("test-wait", 0,
+	"time to run service inputs for in test and once mode")
+var fOnce = flag.Bool("once", false,
+	"gather metrics once, write them to the outputs, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fConfigHeaders = make(headerFlags)
+var fConfigPollInterval = flag.Duration("config-poll-interval", 0,
+	"how often to poll a remote configuration, reloading it once it changes")
+var fConfigCacheDir = flag.String("config-cache-dir", "",
+	"directory to cache remote configurations in (default $HOME/.telegraf/cache)")
+var fConfigCheck = flag.Bool("config-check", false,
+	"check the configuration strictly, print the errors found, and exit")
 var fVersion = flag.Bool("version", false, "display the version")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -60,6 +71,10 @@ var (
 )
 
 func init() {
+	flag.Var(fConfigHeaders, "config-header",
+		"header of the requests for a remote configuration, as "+
+			"'Authorization: Bearer $TOKEN', may be repeated")
+
 	// If commit or branch are not set, make that clear.
 	if commit == "" {
 		commit = "unknown"
@@ -78,11 +93,21 @@ Usage:
 The commands & flags are:
 
   config             print out full sample configuration to stdout
+  config check       check the configuration, and exit nonzero on errors
   version            print the version to stdout
 
-  --config <file>     configuration file to load
+  --config <file>     configuration file to load, or HTTP(S) URL to fetch it from
+  --config-header     header of the requests for a remote configuration,
+                      as 'Authorization: Bearer $TOKEN', may be repeated
+  --config-poll-interval  how often to poll a remote configuration for
+                      changes, reloading it once it changes
+  --config-cache-dir  directory to cache remote configurations in, to start
+                      from when they cannot be fetched
   --test              gather metrics once, print them to stdout, and exit
+  --test-wait         time to run service inputs for with --test or --once
+  --once              gather metrics once, write them to the outputs, and exit
   --config-directory  directory containing additional *.conf files
+  --config-check      check the configuration, and exit nonzero on errors
   --input-filter      filter the input plugins to enable, separator is :
   --output-filter     filter the output plugins to enable, separator is :
   --usage             print usage for a plugin, ie, 'telegraf --usage mysql'
@@ -97,18 +122,106 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
-  # run a single telegraf collection, outputing metrics to stdout
+  # check a config file and config directory, reporting unknown keys
+  telegraf --config telegraf.conf --config-directory telegraf.d config check
+
+  # run a single telegraf collection, outputing metrics to stdout, as they
+  # are gathered and as each output would receive them
   telegraf --config telegraf.conf -test
 
+  # run a single telegraf collection, listening for 10s with service inputs,
+  # and write the metrics to the outputs
+  telegraf --config telegraf.conf --once --test-wait 10s
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
+  # run telegraf with a remote config, reloading it when it changes
+  telegraf --config https://config.example.com/telegraf.conf \
+    --config-header 'Authorization: Bearer $CONFIG_TOKEN' --config-poll-interval 1m
+
   # run telegraf, enabling the cpu & memory input, and influxdb output plugins
   telegraf --config telegraf.conf --input-filter cpu:mem --output-filter influxdb
 `
 
 var stop chan struct{}
 
+// remote is the remote configuration, kept across reloads to poll it for
+// changes.
+var remote *config.Remote
+
+// headerFlags are the headers set with the repeated --config-header flag.
+// Environment variables in their values are expanded.
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	return ""
+}
+
+func (h headerFlags) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid header %q, must be 'Name: value'", value)
+	}
+	h[strings.TrimSpace(parts[0])] = os.ExpandEnv(strings.TrimSpace(parts[1]))
+	return nil
+}
+
+// newConfig returns a new config with the filters and the remote config,
+// if the config flag is an HTTP(S) URL.
+func newConfig(inputFilters, outputFilters []string) *config.Config {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if config.IsRemote(*fConfig) {
+		if remote == nil || remote.URL != *fConfig {
+			remote = config.NewRemote(*fConfig, fConfigHeaders, *fConfigCacheDir)
+			// new contents are only kept once they load with the filters,
+			// with some inputs and outputs.
+			remote.Validate = func(contents []byte) error {
+				c := config.NewConfig()
+				c.OutputFilters = outputFilters
+				c.InputFilters = inputFilters
+				if err := c.LoadConfigData(*fConfig, contents); err != nil {
+					return err
+				}
+				return checkPlugins(c)
+			}
+		}
+		c.Remote = remote
+	}
+	return c
+}
+
+// checkPlugins returns an error if the config has no outputs or no inputs.
+func checkPlugins(c *config.Config) error {
+	if len(c.Outputs) == 0 {
+		return fmt.Errorf("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return fmt.Errorf("Error: no inputs found, did you provide a valid config file?")
+	}
+	return nil
+}
+
+// loadAgent loads the config file and config directory, returning the agent
+// running them.
+func loadAgent(inputFilters, outputFilters []string) (*agent.Agent, error) {
+	c := newConfig(inputFilters, outputFilters)
+	if err := c.LoadConfig(*fConfig); err != nil {
+		return nil, err
+	}
+	if *fConfigDirectory != "" {
+		if err := c.LoadDirectory(*fConfigDirectory); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkPlugins(c); err != nil {
+		return nil, err
+	}
+	return agent.NewAgent(c)
+}
+
 var srvc service.Service
 
 type program struct{}
@@ -122,6 +235,9 @@ func reloadLoop(stop chan struct{}, s service.Service) {
 	}()
 	reload := make(chan bool, 1)
 	reload <- true
+	// next is the agent of the reloaded config, which is loaded before
+	// stopping the running one, to keep running it if the config is invalid.
+	var next *agent.Agent
 	for <-reload {
 		reload <- false
 		flag.Parse()
@@ -154,6 +270,10 @@ func reloadLoop(stop chan struct{}, s service.Service) {
 				fmt.Printf("Telegraf v%s (git: %s %s)\n", version, branch, commit)
 				return
 			case "config":
+				if len(args) > 1 && args[1] == "check" {
+					checkConfig(inputFilters, outputFilters)
+					return
+				}
 				config.PrintSampleConfig(
 					inputFilters,
 					outputFilters,
@@ -181,6 +301,9 @@ func reloadLoop(stop chan struct{}, s service.Service) {
 		case *fVersion:
 			fmt.Printf("Telegraf v%s (git: %s %s)\n", version, branch, commit)
 			return
+		case *fConfigCheck:
+			checkConfig(inputFilters, outputFilters)
+			return
 		case *fSampleConfig:
 			config.PrintSampleConfig(
 				inputFilters,
@@ -199,48 +322,43 @@ func reloadLoop(stop chan struct{}, s service.Service) {
 		}
 
 		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
-
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
+		ag := next
+		next = nil
+		if ag == nil {
+			var err error
+			ag, err = loadAgent(inputFilters, outputFilters)
 			if err != nil {
 				log.Fatal("E! " + err.Error())
 			}
 		}
-		if len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
-
-		ag, err := agent.NewAgent(c)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
 
 		// Setup logging
-		logger.SetupLogging(
-			ag.Config.Agent.Debug || *fDebug,
-			ag.Config.Agent.Quiet || *fQuiet,
-			ag.Config.Agent.Logfile,
-		)
+		logger.Setup(logger.Config{
+			Debug:               ag.Config.Agent.Debug || *fDebug,
+			Quiet:               ag.Config.Agent.Quiet || *fQuiet,
+			Logfile:             ag.Config.Agent.Logfile,
+			Format:              ag.Config.Agent.LogFormat,
+			RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize.Size,
+			RotationMaxArchives: ag.Config.Agent.LogfileRotationMaxArchives,
+		})
 
 		if *fTest {
-			err = ag.Test()
+			err := ag.Test(*fTestWait)
+			if err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
+		}
+
+		if *fOnce {
+			err := ag.Once(*fTestWait)
 			if err != nil {
 				log.Fatal("E! " + err.Error())
 			}
 			return
 		}
 
-		err = ag.Connect()
+		err := ag.Connect()
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
@@ -248,20 +366,51 @@ func reloadLoop(stop chan struct{}, s service.Service) {
 		shutdown := make(chan struct{})
 		signals := make(chan os.Signal)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
+		c := ag.Config
+		var configChanged <-chan struct{}
+		if c.Remote != nil && *fConfigPollInterval > 0 {
+			configChanged = c.Remote.Watch(*fConfigPollInterval, shutdown)
+		}
+		// reloadConfig loads the config again, and stops the running agent to
+		// run the new one, unless it fails to load.
+		reloadConfig := func() bool {
+			a, err := loadAgent(inputFilters, outputFilters)
+			if err != nil {
+				log.Printf("E! Error reloading Telegraf config, keeping "+
+					"the running one: %s", err)
+				return false
+			}
+			next = a
+			<-reload
+			reload <- true
+			close(shutdown)
+			return true
+		}
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == os.Interrupt {
-					close(shutdown)
-				}
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config\n")
-					<-reload
-					reload <- true
+			for {
+				select {
+				case sig := <-signals:
+					if sig == os.Interrupt {
+						close(shutdown)
+						return
+					}
+					if sig == syscall.SIGHUP {
+						log.Printf("I! Reloading Telegraf config\n")
+						if reloadConfig() {
+							return
+						}
+					}
+				case <-configChanged:
+					log.Printf("I! Config %s changed, reloading Telegraf config\n",
+						c.Remote.URL)
+					if reloadConfig() {
+						return
+					}
+					configChanged = c.Remote.Watch(*fConfigPollInterval, shutdown)
+				case <-stop:
 					close(shutdown)
+					return
 				}
-			case <-stop:
-				close(shutdown)
 			}
 		}()
 
@@ -282,9 +431,44 @@ func reloadLoop(stop chan struct{}, s service.Service) {
 		}
 
 		ag.Run(shutdown)
+		ag.Close()
 	}
 }
 
+// checkConfig loads the config file and config directory strictly, reporting
+// unknown keys and invalid settings, and exits nonzero if there are errors.
+func checkConfig(inputFilters, outputFilters []string) {
+	c := newConfig(inputFilters, outputFilters)
+	c.Strict = true
+
+	var failed bool
+	if err := c.LoadConfig(*fConfig); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		failed = true
+	}
+	if *fConfigDirectory != "" {
+		if err := c.LoadDirectory(*fConfigDirectory); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if !failed && len(c.Outputs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no outputs found")
+		failed = true
+	}
+	if !failed && len(c.Inputs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no inputs found")
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+
+	fmt.Printf("Configuration is valid: %d inputs, %d outputs, "+
+		"%d processors, %d aggregators\n", len(c.Inputs), len(c.Outputs),
+		len(c.Processors), len(c.Aggregators))
+}
+
 func usageExit(rc int) {
 	fmt.Println(usage)
 	os.Exit(rc)
@@ -29,3 +29,15 @@ type ServiceInput interface {
 	// Stop stops the services and closes any necessary channels and connections
 	Stop()
 }
+
+// DeltaInput is an Input computing some of its metrics from the values it
+// gathered the previous time, such as usage percentages of counters, so that
+// its first gather is incomplete.
+type DeltaInput interface {
+	Input
+
+	// GathersDeltas reports whether the metrics are computed from the
+	// previous gather. When gathering once, in test mode or with --once, the
+	// input is then gathered a first time and the metrics discarded.
+	GathersDeltas() bool
+}
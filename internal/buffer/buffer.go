@@ -47,18 +47,21 @@ func (b *Buffer) Total() int {
 	return b.total
 }
 
-// Add adds metrics to the buffer.
-func (b *Buffer) Add(metrics ...telegraf.Metric) {
+// Add adds metrics to the buffer, returning the oldest metrics dropped to
+// make room for them if the buffer was full.
+func (b *Buffer) Add(metrics ...telegraf.Metric) []telegraf.Metric {
+	var dropped []telegraf.Metric
 	for i, _ := range metrics {
 		b.total++
 		select {
 		case b.buf <- metrics[i]:
 		default:
 			b.drops++
-			<-b.buf
+			dropped = append(dropped, <-b.buf)
 			b.buf <- metrics[i]
 		}
 	}
+	return dropped
 }
 
 // Batch returns a batch of metrics of size batchSize.
@@ -59,7 +59,8 @@ func TestDroppingMetrics(t *testing.T) {
 	assert.Equal(t, b.Total(), 10)
 
 	// Add 5 more and verify they were dropped
-	b.Add(metricList...)
+	dropped := b.Add(metricList...)
+	assert.Equal(t, metricList, dropped)
 	assert.False(t, b.IsEmpty())
 	assert.Equal(t, b.Len(), 10)
 	assert.Equal(t, b.Drops(), 5)
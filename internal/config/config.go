@@ -18,6 +18,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -56,6 +57,18 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// Strict makes loading fail on the keys of plugin tables which are not
+	// settings of the plugins, on settings of the wrong type, which are
+	// otherwise ignored, and on plugins of the same type with the same alias.
+	Strict bool
+
+	// Remote fetches the configuration when LoadConfig is given an HTTP(S)
+	// URL. A remote without headers is used if it is not set.
+	Remote *Remote
+
+	// aliases are the aliases of the plugins loaded, by plugin type
+	aliases map[string]bool
 }
 
 func NewConfig() *Config {
@@ -65,6 +78,8 @@ func NewConfig() *Config {
 			Interval:      internal.Duration{Duration: 10 * time.Second},
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
+
+			LogfileRotationMaxArchives: 5,
 		},
 
 		Tags:          make(map[string]string),
@@ -135,6 +150,16 @@ type AgentConfig struct {
 	// Logfile specifies the file to send logs to
 	Logfile string
 
+	// LogFormat is the format of the log messages, "text" or "json"
+	LogFormat string
+
+	// LogfileRotationMaxSize is the size at which the log file is rotated,
+	// the log file is not rotated when it is zero
+	LogfileRotationMaxSize internal.Size
+
+	// LogfileRotationMaxArchives is the number of rotated log files kept
+	LogfileRotationMaxArchives int
+
 	// Quiet is the option for running in quiet mode
 	Quiet        bool
 	Hostname     string
@@ -145,7 +170,7 @@ type AgentConfig struct {
 func (c *Config) InputNames() []string {
 	var name []string
 	for _, input := range c.Inputs {
-		name = append(name, input.Name())
+		name = append(name, input.LogName())
 	}
 	return name
 }
@@ -154,7 +179,7 @@ func (c *Config) InputNames() []string {
 func (c *Config) OutputNames() []string {
 	var name []string
 	for _, output := range c.Outputs {
-		name = append(name, output.Name)
+		name = append(name, output.LogName())
 	}
 	return name
 }
@@ -243,6 +268,14 @@ var header = `# Telegraf Configuration
   quiet = false
   ## Specify the log file name. The empty string means to log to stderr.
   logfile = ""
+  ## Format of the log messages, "text" or "json". The json format writes
+  ## each message as an object with time, level, plugin and msg fields.
+  log_format = "text"
+  ## Rotate the log file once it reaches this size, ie, "10MB". The log file
+  ## is not rotated when the size is 0.
+  logfile_rotation_max_size = 0
+  ## Number of rotated log files to keep, as logfile.1, logfile.2, ...
+  logfile_rotation_max_archives = 5
 
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
@@ -504,7 +537,10 @@ func PrintOutputConfig(name string) error {
 	return nil
 }
 
+// LoadDirectory loads the *.conf files of the given directory, returning
+// the errors of all of them.
 func (c *Config) LoadDirectory(path string) error {
+	var errs []string
 	walkfn := func(thispath string, info os.FileInfo, _ error) error {
 		if info.IsDir() {
 			return nil
@@ -513,13 +549,18 @@ func (c *Config) LoadDirectory(path string) error {
 		if len(name) < 6 || name[len(name)-5:] != ".conf" {
 			return nil
 		}
-		err := c.LoadConfig(thispath)
-		if err != nil {
-			return err
+		if err := c.LoadConfig(thispath); err != nil {
+			errs = append(errs, err.Error())
 		}
 		return nil
 	}
-	return filepath.Walk(path, walkfn)
+	if err := filepath.Walk(path, walkfn); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
 }
 
 // Try to find a default config file at these locations (in order):
@@ -534,6 +575,10 @@ func getDefaultConfigPath() (string, error) {
 	if runtime.GOOS == "windows" {
 		etcfile = `C:\Program Files\Telegraf\telegraf.conf`
 	}
+	if IsRemote(envfile) {
+		log.Printf("I! Using config URL: %s", envfile)
+		return envfile, nil
+	}
 	for _, path := range []string{envfile, homefile, etcfile} {
 		if _, err := os.Stat(path); err == nil {
 			log.Printf("I! Using config file: %s", path)
@@ -546,7 +591,9 @@ func getDefaultConfigPath() (string, error) {
 		" in $TELEGRAF_CONFIG_PATH, %s, or %s", homefile, etcfile)
 }
 
-// LoadConfig loads the given config file and applies it to c
+// LoadConfig loads the given config file, or the config at the given HTTP(S)
+// URL, and applies it to c. The errors of all plugins are returned, one per
+// line.
 func (c *Config) LoadConfig(path string) error {
 	var err error
 	if path == "" {
@@ -554,10 +601,31 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	tbl, err := parseFile(path)
+	var tbl *ast.Table
+	if IsRemote(path) {
+		tbl, err = c.parseRemote(path)
+	} else {
+		tbl, err = parseFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
+	return c.loadTable(path, tbl)
+}
+
+// LoadConfigData applies the given contents of the config at path to c, as
+// LoadConfig does with the contents it reads.
+func (c *Config) LoadConfigData(path string, data []byte) error {
+	tbl, err := parseContents(data)
 	if err != nil {
 		return fmt.Errorf("Error parsing %s, %s", path, err)
 	}
+	return c.loadTable(path, tbl)
+}
+
+// loadTable applies the parsed config at path to c.
+func (c *Config) loadTable(path string, tbl *ast.Table) error {
+	var err error
 
 	// Parse tags tables first:
 	for _, tableName := range []string{"tags", "global_tags"} {
@@ -573,12 +641,22 @@ func (c *Config) LoadConfig(path string) error {
 		}
 	}
 
+	// The errors of the agent and of all plugins are reported, rather than
+	// only the first one:
+	var errs []string
+
 	// Parse agent table:
 	if val, ok := tbl.Fields["agent"]; ok {
 		subTable, ok := val.(*ast.Table)
 		if !ok {
 			return fmt.Errorf("%s: invalid configuration", path)
 		}
+		if c.Strict {
+			if err = checkKeys(subTable, c.Agent); err != nil {
+				errs = append(errs,
+					fmt.Sprintf("Error parsing %s, [agent] %s", path, err))
+			}
+		}
 		if err = config.UnmarshalTable(subTable, c.Agent); err != nil {
 			log.Printf("E! Could not parse [agent] config\n")
 			return fmt.Errorf("Error parsing %s, %s", path, err)
@@ -600,12 +678,12 @@ func (c *Config) LoadConfig(path string) error {
 				// legacy [outputs.influxdb] support
 				case *ast.Table:
 					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
+						errs = append(errs, fmt.Sprintf("Error parsing %s, %s", path, err))
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addOutput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							errs = append(errs, fmt.Sprintf("Error parsing %s, %s", path, err))
 						}
 					}
 				default:
@@ -619,12 +697,12 @@ func (c *Config) LoadConfig(path string) error {
 				// legacy [inputs.cpu] support
 				case *ast.Table:
 					if err = c.addInput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
+						errs = append(errs, fmt.Sprintf("Error parsing %s, %s", path, err))
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addInput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							errs = append(errs, fmt.Sprintf("Error parsing %s, %s", path, err))
 						}
 					}
 				default:
@@ -638,7 +716,7 @@ func (c *Config) LoadConfig(path string) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addProcessor(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							errs = append(errs, fmt.Sprintf("Error parsing %s, %s", path, err))
 						}
 					}
 				default:
@@ -652,7 +730,7 @@ func (c *Config) LoadConfig(path string) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addAggregator(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							errs = append(errs, fmt.Sprintf("Error parsing %s, %s", path, err))
 						}
 					}
 				default:
@@ -664,11 +742,16 @@ func (c *Config) LoadConfig(path string) error {
 		// identifiers are present
 		default:
 			if err = c.addInput(name, subTable); err != nil {
-				return fmt.Errorf("Error parsing %s, %s", path, err)
+				errs = append(errs, fmt.Sprintf("Error parsing %s, %s", path, err))
 			}
 		}
 	}
 
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
 	if len(c.Processors) > 1 {
 		sort.Sort(c.Processors)
 	}
@@ -690,6 +773,23 @@ func parseFile(fpath string) (*ast.Table, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseContents(contents)
+}
+
+// parseRemote fetches and parses the config at the given URL with the remote
+// of c.
+func (c *Config) parseRemote(url string) (*ast.Table, error) {
+	if c.Remote == nil || c.Remote.URL != url {
+		c.Remote = NewRemote(url, nil, "")
+	}
+	contents, err := c.Remote.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	return parseContents(contents)
+}
+
+func parseContents(contents []byte) (*ast.Table, error) {
 	// ugh windows why
 	contents = trimBOM(contents)
 
@@ -711,16 +811,34 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	}
 	aggregator := creator()
 
+	if err := c.checkSettings("aggregator", name, table,
+		filterSettings, aggregatorSettings); err != nil {
+		return err
+	}
+
 	conf, err := buildAggregator(name, table)
 	if err != nil {
 		return err
 	}
 
+	if err := c.checkAlias("aggregator", name, conf.Alias); err != nil {
+		return err
+	}
+
+	if err := c.checkKeys("aggregator", name, table, aggregator); err != nil {
+		return err
+	}
+
 	if err := config.UnmarshalTable(table, aggregator); err != nil {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	ra := models.NewRunningAggregator(aggregator, conf)
+	if err := initPlugin("aggregator", name, table, aggregator); err != nil {
+		return err
+	}
+
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -731,19 +849,31 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	}
 	processor := creator()
 
+	if err := c.checkSettings("processor", name, table,
+		filterSettings, processorSettings); err != nil {
+		return err
+	}
+
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
 		return err
 	}
 
+	if err := c.checkAlias("processor", name, processorConfig.Alias); err != nil {
+		return err
+	}
+
+	if err := c.checkKeys("processor", name, table, processor); err != nil {
+		return err
+	}
+
 	if err := config.UnmarshalTable(table, processor); err != nil {
 		return err
 	}
 
-	rf := &models.RunningProcessor{
-		Name:      name,
-		Processor: processor,
-		Config:    processorConfig,
+	rf := models.NewRunningProcessor(processor, processorConfig)
+	if err := initPlugin("processor", name, table, processor); err != nil {
+		return err
 	}
 
 	c.Processors = append(c.Processors, rf)
@@ -760,11 +890,21 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	}
 	output := creator()
 
+	settings := [][]string{filterSettings, outputSettings, cardinalitySettings}
+	if _, ok := output.(serializers.SerializerOutput); ok {
+		settings = append(settings, serializerSettings)
+	}
+	if err := c.checkSettings("output", name, table, settings...); err != nil {
+		return err
+	}
+
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
+	var serializer serializers.Serializer
 	switch t := output.(type) {
 	case serializers.SerializerOutput:
-		serializer, err := buildSerializer(name, table)
+		var err error
+		serializer, err = buildSerializer(name, table)
 		if err != nil {
 			return err
 		}
@@ -776,12 +916,25 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return err
 	}
 
+	if err := c.checkAlias("output", name, outputConfig.Alias); err != nil {
+		return err
+	}
+
+	if err := c.checkKeys("output", name, table, output); err != nil {
+		return err
+	}
+
 	if err := config.UnmarshalTable(table, output); err != nil {
 		return err
 	}
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	ro.Serializer = serializer
+	if err := initPlugin("output", name, table, output); err != nil {
+		return err
+	}
+
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -801,6 +954,14 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 	input := creator()
 
+	settings := [][]string{filterSettings, inputSettings, cardinalitySettings}
+	if _, ok := input.(parsers.ParserInput); ok {
+		settings = append(settings, parserSettings)
+	}
+	if err := c.checkSettings("input", name, table, settings...); err != nil {
+		return err
+	}
+
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
 	switch t := input.(type) {
@@ -809,6 +970,9 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		if err != nil {
 			return err
 		}
+		if err := initPlugin("parser of input", name, table, parser); err != nil {
+			return err
+		}
 		t.SetParser(parser)
 	}
 
@@ -817,18 +981,92 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return err
 	}
 
+	if err := c.checkAlias("input", name, pluginConfig.Alias); err != nil {
+		return err
+	}
+
+	if err := c.checkKeys("input", name, table, input); err != nil {
+		return err
+	}
+
 	if err := config.UnmarshalTable(table, input); err != nil {
 		return err
 	}
 
-	rp := &models.RunningInput{
-		Input:  input,
-		Config: pluginConfig,
+	rp := models.NewRunningInput(input, pluginConfig)
+	if err := initPlugin("input", name, table, input); err != nil {
+		return err
 	}
+
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
 
+// checkSettings checks the types of the given settings of the plugin table,
+// when loading the configuration strictly.
+func (c *Config) checkSettings(
+	kind, name string,
+	table *ast.Table,
+	settings ...[]string,
+) error {
+	if !c.Strict {
+		return nil
+	}
+	if err := checkSettings(table, settings...); err != nil {
+		return fmt.Errorf("%s %s (line %d): %s", kind, name, table.Line, err)
+	}
+	return nil
+}
+
+// checkKeys checks that the keys left in the plugin table, once the
+// settings handled by the configuration are removed, are settings of the
+// plugin, when loading the configuration strictly.
+func (c *Config) checkKeys(
+	kind, name string,
+	table *ast.Table,
+	plugin interface{},
+) error {
+	if !c.Strict {
+		return nil
+	}
+	if err := checkKeys(table, plugin); err != nil {
+		return fmt.Errorf("%s %s (line %d): %s", kind, name, table.Line, err)
+	}
+	return nil
+}
+
+// checkAlias checks that no other plugin of the same type has the same alias,
+// when loading the configuration strictly, as their logs would be mixed up.
+func (c *Config) checkAlias(kind, name, alias string) error {
+	if !c.Strict || alias == "" {
+		return nil
+	}
+	if c.aliases == nil {
+		c.aliases = make(map[string]bool)
+	}
+
+	key := kind + "." + name + "::" + alias
+	if c.aliases[key] {
+		return fmt.Errorf("%s %s: duplicate alias %q", kind, name, alias)
+	}
+	c.aliases[key] = true
+	return nil
+}
+
+// initPlugin initializes the plugin configured by the given table, if it
+// implements telegraf.Initializer.
+func initPlugin(kind, name string, table *ast.Table, plugin interface{}) error {
+	p, ok := plugin.(telegraf.Initializer)
+	if !ok {
+		return nil
+	}
+	if err := p.Init(); err != nil {
+		return fmt.Errorf("Could not initialize %s %s (line %d): %s",
+			kind, name, table.Line, err)
+	}
+	return nil
+}
+
 // buildAggregator parses Aggregator specific items from the ast.Table,
 // builds the filter and returns a
 // models.AggregatorConfig to be inserted into models.RunningAggregator
@@ -847,6 +1085,19 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		Period: time.Second * 30,
 	}
 
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	var err error
+	if conf.LogLevel, err = buildLogLevel(tbl); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["period"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -918,6 +1169,7 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "period")
 	delete(tbl.Fields, "delay")
 	delete(tbl.Fields, "drop_original")
@@ -925,7 +1177,6 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "tags")
-	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
 		return conf, err
@@ -946,6 +1197,19 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	var err error
+	if conf.LogLevel, err = buildLogLevel(tbl); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["order"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Integer); ok {
@@ -958,8 +1222,8 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "order")
-	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
 		return conf, err
@@ -971,6 +1235,96 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 // (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop) to
 // be inserted into the models.OutputConfig/models.InputConfig
 // to be used for glob filtering on tags and measurements
+// buildLogLevel parses the log level of a plugin from the ast.Table, which
+// is zero for plugins logging at the level of the agent.
+func buildLogLevel(tbl *ast.Table) (logger.Level, error) {
+	defer delete(tbl.Fields, "log_level")
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				return logger.ParseLevel(str.Value)
+			}
+		}
+	}
+	return 0, nil
+}
+
+// buildCardinalityLimit parses the cardinality limit of an input or an
+// output from the ast.Table. The cardinality is not limited unless
+// cardinality_limit is set.
+func buildCardinalityLimit(tbl *ast.Table) (models.CardinalityLimit, error) {
+	limit := models.CardinalityLimit{
+		Window:      time.Hour,
+		Action:      models.CardinalityDrop,
+		Placeholder: "other",
+	}
+
+	if node, ok := tbl.Fields["cardinality_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				max, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return limit, err
+				}
+				limit.MaxSeries = max
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["cardinality_window"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return limit, err
+				}
+				limit.Window = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["cardinality_action"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				limit.Action = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["cardinality_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						limit.Tags = append(limit.Tags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["cardinality_placeholder"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				limit.Placeholder = str.Value
+			}
+		}
+	}
+
+	delete(tbl.Fields, "cardinality_limit")
+	delete(tbl.Fields, "cardinality_window")
+	delete(tbl.Fields, "cardinality_action")
+	delete(tbl.Fields, "cardinality_tags")
+	delete(tbl.Fields, "cardinality_placeholder")
+
+	if limit.MaxSeries > 0 {
+		if err := limit.Validate(); err != nil {
+			return limit, err
+		}
+	}
+	return limit, nil
+}
+
 func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	f := models.Filter{}
 
@@ -1109,6 +1463,23 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 // models.InputConfig to be inserted into models.RunningInput
 func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	cp := &models.InputConfig{Name: name}
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.Alias = str.Value
+			}
+		}
+	}
+
+	var err error
+	if cp.LogLevel, err = buildLogLevel(tbl); err != nil {
+		return nil, err
+	}
+
+	if cp.CardinalityLimit, err = buildCardinalityLimit(tbl); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["interval"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1122,6 +1493,17 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["align_timestamps"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				cp.AlignTimestamps, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1155,12 +1537,13 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "align_timestamps")
 	delete(tbl.Fields, "tags")
-	var err error
 	cp.Filter, err = buildFilter(tbl)
 	if err != nil {
 		return cp, err
@@ -1293,6 +1676,24 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		Name:   name,
 		Filter: filter,
 	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Alias = str.Value
+			}
+		}
+	}
+	delete(tbl.Fields, "alias")
+
+	if oc.LogLevel, err = buildLogLevel(tbl); err != nil {
+		return nil, err
+	}
+
+	if oc.CardinalityLimit, err = buildCardinalityLimit(tbl); err != nil {
+		return nil, err
+	}
+
 	// Outputs don't support FieldDrop/FieldPass, so set to NameDrop/NamePass
 	if len(oc.Filter.FieldDrop) > 0 {
 		oc.Filter.NameDrop = oc.Filter.FieldDrop
@@ -1,11 +1,15 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
@@ -13,6 +17,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
@@ -174,3 +179,137 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+type initTestInput struct {
+	Servers []string
+
+	initialized bool
+}
+
+func (i *initTestInput) SampleConfig() string                  { return "" }
+func (i *initTestInput) Description() string                   { return "" }
+func (i *initTestInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func (i *initTestInput) Init() error {
+	if len(i.Servers) == 0 {
+		return errors.New("no servers configured")
+	}
+	i.initialized = true
+	return nil
+}
+
+func init() {
+	inputs.Add("init_test", func() telegraf.Input { return &initTestInput{} })
+}
+
+func TestConfig_InitPlugins(t *testing.T) {
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig("./testdata/init_plugin.toml"))
+	assert.Len(t, c.Inputs, 1)
+	assert.True(t, c.Inputs[0].Input.(*initTestInput).initialized)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/invalid_init_plugin.toml")
+	assert.EqualError(t, err, "Error parsing ./testdata/invalid_init_plugin.toml, "+
+		"Could not initialize input init_test (line 2): no servers configured")
+}
+
+type strictTestInput struct {
+	Log     telegraf.Logger `toml:"-"`
+	Servers []string
+	Timeout internal.Duration
+	Auth    struct {
+		Username string
+		Password string
+	} `toml:"auth"`
+}
+
+func (i *strictTestInput) SampleConfig() string                  { return "" }
+func (i *strictTestInput) Description() string                   { return "" }
+func (i *strictTestInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func init() {
+	inputs.Add("strict_test", func() telegraf.Input { return &strictTestInput{} })
+}
+
+func TestConfig_LoadStrict(t *testing.T) {
+	// unknown keys are ignored, unless loading strictly
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig("./testdata/strict.toml"))
+
+	c = NewConfig()
+	c.Strict = true
+	err := c.LoadConfig("./testdata/strict.toml")
+	assert.EqualError(t, err, "Error parsing ./testdata/strict.toml, "+
+		"[agent] unknown keys: flush_intervall (line 3)\n"+
+		"Error parsing ./testdata/strict.toml, "+
+		"input strict_test (line 5): invalid settings: "+
+		"namepass (line 8) must be an array of strings")
+
+	c = NewConfig()
+	c.Strict = true
+	err = c.LoadConfig("./testdata/strict_keys.toml")
+	assert.EqualError(t, err, "Error parsing ./testdata/strict_keys.toml, "+
+		"input strict_test (line 1): unknown keys: "+
+		"auth.pasword (line 6), server_timeout (line 3)")
+}
+
+func TestConfig_LoadAlias(t *testing.T) {
+	c := NewConfig()
+	assert.NoError(t, c.LoadConfig("./testdata/alias.toml"))
+	assert.Len(t, c.Inputs, 2)
+	assert.Equal(t, "primary", c.Inputs[0].Config.Alias)
+	assert.Equal(t, "inputs.strict_test::primary", c.Inputs[0].LogName())
+
+	c = NewConfig()
+	c.Strict = true
+	err := c.LoadConfig("./testdata/alias.toml")
+	assert.EqualError(t, err, "Error parsing ./testdata/alias.toml, "+
+		"input strict_test: duplicate alias \"primary\"")
+}
+
+func TestConfig_LoadLogLevel(t *testing.T) {
+	c := NewConfig()
+	c.Strict = true
+	assert.NoError(t, c.LoadConfig("./testdata/log_level.toml"))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, logger.DEBUG, c.Inputs[0].Config.LogLevel)
+	input := c.Inputs[0].Input.(*strictTestInput)
+	require.NotNil(t, input.Log)
+	assert.Equal(t, "inputs.strict_test::debug", input.Log.(*models.Logger).Name)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/invalid_log_level.toml")
+	assert.EqualError(t, err, "Error parsing ./testdata/invalid_log_level.toml, "+
+		"invalid log level \"verbose\", must be one of "+
+		"\"debug\", \"info\", \"warn\" or \"error\"")
+}
+
+func TestConfig_LoadCardinalityLimit(t *testing.T) {
+	c := NewConfig()
+	c.Strict = true
+	assert.NoError(t, c.LoadConfig("./testdata/cardinality.toml"))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, models.CardinalityLimit{
+		MaxSeries:   1000,
+		Window:      10 * time.Minute,
+		Action:      models.CardinalityReplaceTags,
+		Tags:        []string{"request_id", "url"},
+		Placeholder: "other",
+	}, c.Inputs[0].Config.CardinalityLimit)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/invalid_cardinality.toml")
+	assert.EqualError(t, err, "Error parsing ./testdata/invalid_cardinality.toml, "+
+		"cardinality_action \"strip_tags\" needs cardinality_tags")
+}
+
+func TestConfig_LoadAlignTimestamps(t *testing.T) {
+	c := NewConfig()
+	c.Strict = true
+	assert.NoError(t, c.LoadConfig("./testdata/align_timestamps.toml"))
+	require.Len(t, c.Inputs, 2)
+	assert.True(t, c.Inputs[0].Config.AlignTimestamps)
+	assert.Equal(t, time.Minute, c.Inputs[0].Config.Interval)
+	assert.False(t, c.Inputs[1].Config.AlignTimestamps)
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsRemote returns whether the configuration path is an HTTP(S) URL.
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") ||
+		strings.HasPrefix(path, "https://")
+}
+
+// Remote is a configuration file served over HTTP(S). The last contents
+// fetched are cached locally, to load the configuration from when the URL
+// cannot be fetched, such as when telegraf starts while the server is down.
+type Remote struct {
+	URL string
+	// Headers are set on the requests, such as an Authorization header.
+	Headers map[string]string
+	// CacheDir is the directory the contents are cached in, which is
+	// $HOME/.telegraf/cache by default.
+	CacheDir string
+	// Validate checks new contents before they are kept and cached, so that
+	// invalid contents are neither loaded nor reported as a change. By
+	// default, the contents must load into a new Config.
+	Validate func(contents []byte) error
+
+	client *http.Client
+
+	sync.Mutex
+	etag     string
+	contents []byte
+}
+
+// NewRemote returns the remote configuration at the given URL.
+func NewRemote(url string, headers map[string]string, cacheDir string) *Remote {
+	if cacheDir == "" {
+		cacheDir = os.ExpandEnv("${HOME}/.telegraf/cache")
+	}
+	return &Remote{
+		URL:      url,
+		Headers:  headers,
+		CacheDir: cacheDir,
+		Validate: func(contents []byte) error {
+			return NewConfig().LoadConfigData(url, contents)
+		},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Fetch returns the contents of the configuration. If the URL cannot be
+// fetched, the contents fetched last are returned, or the cached ones.
+func (r *Remote) Fetch() ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, err := r.fetch(); err != nil {
+		if r.contents != nil {
+			log.Printf("W! Error fetching config %s, using the last one "+
+				"fetched: %s", r.URL, err)
+			return r.contents, nil
+		}
+		contents, cerr := ioutil.ReadFile(r.cachePath())
+		if cerr != nil {
+			return nil, err
+		}
+		log.Printf("W! Error fetching config %s, using the cached one "+
+			"%s: %s", r.URL, r.cachePath(), err)
+		r.contents = contents
+	}
+	return r.contents, nil
+}
+
+// Changed fetches the configuration, returning whether its contents changed
+// since they were fetched last.
+func (r *Remote) Changed() (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.fetch()
+}
+
+// Watch polls the configuration every interval, until done is closed, and
+// sends on the returned channel once its contents change. Errors fetching
+// the configuration are logged, and polling goes on.
+func (r *Remote) Watch(interval time.Duration, done <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ok, err := r.Changed()
+				if err != nil {
+					log.Printf("E! Error polling config %s: %s", r.URL, err)
+					continue
+				}
+				if ok {
+					changed <- struct{}{}
+					return
+				}
+			}
+		}
+	}()
+	return changed
+}
+
+// fetch gets the configuration, conditionally on the ETag of the contents
+// fetched last, keeping and caching the new contents, if they changed and
+// are valid.
+func (r *Remote) fetch() (bool, error) {
+	req, err := http.NewRequest("GET", r.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+	if r.etag != "" && r.contents != nil {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && r.contents != nil:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("%s returned HTTP status %s", r.URL, resp.Status)
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	// servers which do not support ETags always return the contents, so
+	// they are compared to tell whether they changed.
+	if r.contents != nil && bytes.Equal(contents, r.contents) {
+		r.etag = resp.Header.Get("ETag")
+		return false, nil
+	}
+	if r.Validate != nil {
+		if err := r.Validate(contents); err != nil {
+			return false, fmt.Errorf("invalid config %s: %s", r.URL, err)
+		}
+	}
+	changed := r.contents != nil
+	r.etag = resp.Header.Get("ETag")
+	r.contents = contents
+
+	if err := r.cache(contents); err != nil {
+		log.Printf("W! Unable to cache config %s in %s: %s",
+			r.URL, r.CacheDir, err)
+	}
+	return changed, nil
+}
+
+// cache writes the contents to the cache file, replacing it at once. The
+// file is only readable by its owner, as configurations hold credentials.
+func (r *Remote) cache(contents []byte) error {
+	if err := os.MkdirAll(r.CacheDir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(r.CacheDir, "config")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.cachePath())
+}
+
+func (r *Remote) cachePath() string {
+	return filepath.Join(r.CacheDir,
+		fmt.Sprintf("%x.conf", sha256.Sum256([]byte(r.URL))))
+}
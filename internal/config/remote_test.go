@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configServer serves a config, with an ETag unless noETag is set, and
+// requires the Authorization header.
+type configServer struct {
+	sync.Mutex
+	config   string
+	version  int
+	noETag   bool
+	requests int
+}
+
+func (s *configServer) set(config string) {
+	s.Lock()
+	defer s.Unlock()
+	s.config = config
+	s.version++
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests++
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if !s.noETag {
+		etag := fmt.Sprintf(`"%d"`, s.version)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+	}
+	fmt.Fprint(w, s.config)
+}
+
+const remoteConfig = `
+[[inputs.strict_test]]
+  servers = ["localhost"]
+`
+
+func newTestRemote(t *testing.T, url string) (*Remote, func()) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	r := NewRemote(url, map[string]string{"Authorization": "Bearer token"}, dir)
+	return r, func() { os.RemoveAll(dir) }
+}
+
+func TestConfig_LoadRemote(t *testing.T) {
+	s := &configServer{}
+	s.set(remoteConfig)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	r, cleanup := newTestRemote(t, ts.URL)
+	defer cleanup()
+
+	c := NewConfig()
+	c.Remote = r
+	require.NoError(t, c.LoadConfig(ts.URL))
+	require.Len(t, c.Inputs, 1)
+	assert.Equal(t, []string{"localhost"},
+		c.Inputs[0].Input.(*strictTestInput).Servers)
+
+	// without the headers of the remote
+	c = NewConfig()
+	err := c.LoadConfig(ts.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized")
+}
+
+func TestRemote_Changed(t *testing.T) {
+	for _, noETag := range []bool{false, true} {
+		s := &configServer{noETag: noETag}
+		s.set(remoteConfig)
+		ts := httptest.NewServer(s)
+
+		r, cleanup := newTestRemote(t, ts.URL)
+		_, err := r.Fetch()
+		require.NoError(t, err)
+
+		changed, err := r.Changed()
+		require.NoError(t, err)
+		assert.False(t, changed)
+
+		s.set(remoteConfig + "  timeout = \"5s\"\n")
+		changed, err = r.Changed()
+		require.NoError(t, err)
+		assert.True(t, changed)
+
+		contents, err := r.Fetch()
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), "timeout")
+
+		ts.Close()
+		cleanup()
+	}
+}
+
+func TestRemote_Cache(t *testing.T) {
+	s := &configServer{}
+	s.set(remoteConfig)
+	ts := httptest.NewServer(s)
+
+	r, cleanup := newTestRemote(t, ts.URL)
+	defer cleanup()
+	_, err := r.Fetch()
+	require.NoError(t, err)
+	ts.Close()
+
+	// the last contents fetched are used while the server is down
+	contents, err := r.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, remoteConfig, string(contents))
+
+	// and so are the cached ones after a restart
+	restarted := NewRemote(ts.URL, r.Headers, r.CacheDir)
+	contents, err = restarted.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, remoteConfig, string(contents))
+
+	// with no cache, the error is returned
+	uncached, cleanup2 := newTestRemote(t, ts.URL)
+	defer cleanup2()
+	_, err = uncached.Fetch()
+	assert.Error(t, err)
+}
+
+func TestRemote_Invalid(t *testing.T) {
+	s := &configServer{}
+	s.set(remoteConfig)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	r, cleanup := newTestRemote(t, ts.URL)
+	defer cleanup()
+	_, err := r.Fetch()
+	require.NoError(t, err)
+
+	// invalid contents are neither reported as a change, nor kept, nor cached
+	s.set("[[inputs.undefined]]\n")
+	changed, err := r.Changed()
+	require.Error(t, err)
+	assert.False(t, changed)
+
+	contents, err := r.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, remoteConfig, string(contents))
+
+	cached, err := ioutil.ReadFile(r.cachePath())
+	require.NoError(t, err)
+	assert.Equal(t, remoteConfig, string(cached))
+}
+
+func TestRemote_Watch(t *testing.T) {
+	s := &configServer{}
+	s.set(remoteConfig)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	r, cleanup := newTestRemote(t, ts.URL)
+	defer cleanup()
+	_, err := r.Fetch()
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	defer close(done)
+	changed := r.Watch(10*time.Millisecond, done)
+
+	select {
+	case <-changed:
+		t.Fatal("config changed before it was updated")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.set(remoteConfig + "  timeout = \"5s\"\n")
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not detected")
+	}
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// The settings of plugin tables which the configuration handles itself,
+// rather than the plugins.
+var (
+	filterSettings = []string{"namepass", "namedrop", "fieldpass", "fielddrop",
+		"pass", "drop", "tagpass", "tagdrop", "tagexclude", "taginclude"}
+	inputSettings = []string{"alias", "log_level", "interval",
+		"align_timestamps", "name_prefix", "name_suffix", "name_override",
+		"tags"}
+	outputSettings     = []string{"alias", "log_level"}
+	aggregatorSettings = []string{"alias", "log_level", "period", "delay",
+		"drop_original", "name_prefix", "name_suffix", "name_override", "tags"}
+	processorSettings  = []string{"alias", "log_level", "order"}
+	parserSettings     = []string{"data_format", "separator", "templates", "tag_keys", "data_type"}
+	serializerSettings = []string{"data_format", "prefix", "template"}
+)
+
+// cardinalitySettings are the settings of the cardinality limit of inputs and
+// outputs.
+var cardinalitySettings = []string{"cardinality_limit", "cardinality_window",
+	"cardinality_action", "cardinality_tags", "cardinality_placeholder"}
+
+// settingTypes are the types of the settings handled by the configuration.
+// A setting of another type is ignored when loading the configuration, so
+// it is reported when checking it strictly.
+var settingTypes = map[string]string{
+	"namepass":      "an array of strings",
+	"namedrop":      "an array of strings",
+	"fieldpass":     "an array of strings",
+	"fielddrop":     "an array of strings",
+	"pass":          "an array of strings",
+	"drop":          "an array of strings",
+	"tagpass":       "a table",
+	"tagdrop":       "a table",
+	"tagexclude":    "an array of strings",
+	"taginclude":    "an array of strings",
+	"alias":         "a string",
+	"log_level":     "a string",
+	"interval":      "a string",
+	"period":        "a string",
+	"delay":         "a string",
+	"drop_original": "a boolean",
+	"name_prefix":   "a string",
+	"name_suffix":   "a string",
+	"name_override": "a string",
+	"tags":          "a table",
+	"order":         "an integer",
+	"data_format":   "a string",
+	"separator":     "a string",
+	"templates":     "an array of strings",
+	"tag_keys":      "an array of strings",
+	"data_type":     "a string",
+	"prefix":        "a string",
+	"template":      "a string",
+
+	"cardinality_limit":       "an integer",
+	"cardinality_window":      "a string",
+	"cardinality_action":      "a string",
+	"cardinality_tags":        "an array of strings",
+	"cardinality_placeholder": "a string",
+
+	"align_timestamps": "a boolean",
+}
+
+// checkSettings returns an error listing the settings of tbl, among the
+// given ones, which are not of the type the configuration expects.
+func checkSettings(tbl *ast.Table, settings ...[]string) error {
+	var invalid []string
+	for _, keys := range settings {
+		for _, key := range keys {
+			node, ok := tbl.Fields[key]
+			if !ok || hasSettingType(node, settingTypes[key]) {
+				continue
+			}
+			invalid = append(invalid, fmt.Sprintf("%s (line %d) must be %s",
+				key, nodeLine(node), settingTypes[key]))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid settings: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+func hasSettingType(node interface{}, typ string) bool {
+	if typ == "a table" {
+		tbl, ok := node.(*ast.Table)
+		if !ok {
+			return false
+		}
+		// the values of tagpass and tagdrop are arrays of strings, and the
+		// values of tags are strings.
+		for _, value := range tbl.Fields {
+			if !hasSettingType(value, "an array of strings") &&
+				!hasSettingType(value, "a string") {
+				return false
+			}
+		}
+		return true
+	}
+
+	kv, ok := node.(*ast.KeyValue)
+	if !ok {
+		return false
+	}
+	switch typ {
+	case "a string":
+		_, ok = kv.Value.(*ast.String)
+	case "a boolean":
+		_, ok = kv.Value.(*ast.Boolean)
+	case "an integer":
+		_, ok = kv.Value.(*ast.Integer)
+	case "an array of strings":
+		var ary *ast.Array
+		if ary, ok = kv.Value.(*ast.Array); ok {
+			for _, elem := range ary.Value {
+				if _, ok = elem.(*ast.String); !ok {
+					break
+				}
+			}
+		}
+	}
+	return ok
+}
+
+// checkKeys returns an error listing the keys of tbl, and of its sub-tables,
+// which are not settings of the plugin v, such as misspelled keys. These
+// keys are otherwise ignored.
+func checkKeys(tbl *ast.Table, v interface{}) error {
+	unknown := unknownKeys(tbl, reflect.TypeOf(v), "")
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown keys: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func unknownKeys(tbl *ast.Table, t reflect.Type, prefix string) []string {
+	t = elemType(t)
+	if t.Kind() != reflect.Struct {
+		// maps and interfaces accept any key
+		return nil
+	}
+
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var unknown []string
+	for _, key := range keys {
+		field, ok := findField(t, key)
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("%s%s (line %d)",
+				prefix, key, nodeLine(tbl.Fields[key])))
+			continue
+		}
+
+		switch node := tbl.Fields[key].(type) {
+		case *ast.Table:
+			unknown = append(unknown,
+				unknownKeys(node, field.Type, prefix+key+".")...)
+		case []*ast.Table:
+			for _, sub := range node {
+				unknown = append(unknown,
+					unknownKeys(sub, field.Type, prefix+key+".")...)
+			}
+		}
+	}
+	return unknown
+}
+
+// findField returns the field of the struct type t which the key sets. Keys
+// match the name given by the toml tag of a field, or the name of the field
+// ignoring case and underscores, as when unmarshalling the table.
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	normKey := normalizeKey(key)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			if embedded := elemType(field.Type); embedded.Kind() == reflect.Struct {
+				if f, ok := findField(embedded, key); ok {
+					return f, true
+				}
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if tag == "-" {
+			// not a setting, such as the logger of the plugin
+			continue
+		}
+		if tag == key {
+			return field, true
+		}
+		if normalizeKey(field.Name) == normKey {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.Replace(key, "_", "", -1))
+}
+
+// elemType returns the type of the values of t, stripping pointers, slices
+// and arrays.
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+func nodeLine(node interface{}) int {
+	switch n := node.(type) {
+	case *ast.KeyValue:
+		return n.Line
+	case *ast.Table:
+		return n.Line
+	case []*ast.Table:
+		if len(n) > 0 {
+			return n[0].Line
+		}
+	}
+	return 0
+}
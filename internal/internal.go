@@ -67,6 +67,51 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 	return nil
 }
 
+// Size just wraps an int64, a number of bytes
+type Size struct {
+	Size int64
+}
+
+// sizeUnits are the units of a size, by decreasing length.
+var sizeUnits = []struct {
+	unit  string
+	bytes int64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// UnmarshalTOML parses the size from the TOML config file, either as an
+// integer number of bytes, or as a string with a unit, ie, "10MB" or "1GiB".
+func (s *Size) UnmarshalTOML(b []byte) error {
+	str := string(bytes.Trim(b, `'`))
+	if uq, err := strconv.Unquote(str); err == nil {
+		str = uq
+	}
+	str = strings.TrimSpace(str)
+
+	multiplier := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(str, u.unit) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u.unit))
+			multiplier = u.bytes
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %s", b)
+	}
+	s.Size = size * multiplier
+	return nil
+}
+
 // ReadLines reads contents from a file and splits them by new lines.
 // A convenience wrapper to ReadLinesOffsetN(filename, 0, -1).
 func ReadLines(filename string) ([]string, error) {
@@ -155,6 +200,49 @@ func GetTLSConfig(
 	return t, nil
 }
 
+// GetServerTLSConfig gets a tls.Config object for a server from the given
+// cert and key files. If any CA files are given, clients are required to
+// present a certificate signed by one of them.
+// If the cert and key are blank, returns a nil pointer.
+func GetServerTLSConfig(
+	TLSCert, TLSKey string,
+	TLSAllowedCACerts []string,
+) (*tls.Config, error) {
+	if TLSCert == "" && TLSKey == "" {
+		if len(TLSAllowedCACerts) != 0 {
+			return nil, errors.New("tls_allowed_cacerts requires tls_cert and tls_key")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(TLSCert, TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not load TLS server key/certificate from %s:%s: %s",
+			TLSKey, TLSCert, err)
+	}
+	t := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if len(TLSAllowedCACerts) != 0 {
+		caCertPool := x509.NewCertPool()
+		for _, path := range TLSAllowedCACerts {
+			caCert, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("Could not load TLS CA: %s", err)
+			}
+			if !caCertPool.AppendCertsFromPEM(caCert) {
+				return nil, fmt.Errorf("Could not parse any certificates in %s", path)
+			}
+		}
+		t.ClientCAs = caCertPool
+		t.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return t, nil
+}
+
 // SnakeCase converts the given string to snake case following the Golang format:
 // acronyms are converted to lower-case and preceded by an underscore.
 func SnakeCase(in string) string {
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// The actions taken on the metrics of new series once the cardinality limit
+// of a plugin is exceeded.
+const (
+	// CardinalityDrop drops the metrics.
+	CardinalityDrop = "drop"
+	// CardinalityStripTags removes the offending tags from the metrics.
+	CardinalityStripTags = "strip_tags"
+	// CardinalityReplaceTags replaces the values of the offending tags of
+	// the metrics with a placeholder.
+	CardinalityReplaceTags = "replace_tags"
+)
+
+const (
+	// how often the series which expired are removed, at most.
+	cardinalitySweepInterval = time.Second
+	// how often a warning is logged, at most, while the limit is exceeded.
+	cardinalityWarnInterval = time.Minute
+)
+
+// CardinalityLimit is the configuration of the cardinality limiter of an
+// input or an output.
+type CardinalityLimit struct {
+	// MaxSeries is the number of distinct series, by metric HashID, allowed
+	// within the window. The cardinality is not limited when it is zero.
+	MaxSeries int
+	// Window is the duration a series is kept for after its last metric.
+	Window time.Duration
+	// Action is the action taken on the metrics of new series once
+	// MaxSeries is reached.
+	Action string
+	// Tags are the offending tags, which are stripped or replaced.
+	Tags []string
+	// Placeholder is the value the offending tags are replaced with.
+	Placeholder string
+}
+
+// Validate returns an error if the action of the limit is unknown, or if it
+// needs offending tags and there are none.
+func (c CardinalityLimit) Validate() error {
+	switch c.Action {
+	case CardinalityDrop:
+		return nil
+	case CardinalityStripTags, CardinalityReplaceTags:
+		if len(c.Tags) == 0 {
+			return fmt.Errorf("cardinality_action %q needs cardinality_tags",
+				c.Action)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown cardinality_action %q, must be one of "+
+		"%q, %q or %q", c.Action,
+		CardinalityDrop, CardinalityStripTags, CardinalityReplaceTags)
+}
+
+// CardinalityLimiter tracks the distinct series of the metrics of a plugin
+// within a sliding window, and limits them once there are MaxSeries of them.
+// Only the series within the limit are tracked, so its memory is bounded.
+type CardinalityLimiter struct {
+	sync.Mutex
+
+	limit CardinalityLimit
+	log   telegraf.Logger
+
+	// series are the last times a metric of each series was seen.
+	series    map[uint64]time.Time
+	lastSweep time.Time
+
+	limited     int64
+	lastWarning time.Time
+
+	now func() time.Time
+}
+
+// NewCardinalityLimiter returns the cardinality limiter of a plugin, logging
+// warnings with the given logger.
+func NewCardinalityLimiter(
+	limit CardinalityLimit,
+	log telegraf.Logger,
+) *CardinalityLimiter {
+	return &CardinalityLimiter{
+		limit:  limit,
+		log:    log,
+		series: make(map[uint64]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Apply applies the limit to the metric, stripping or replacing its offending
+// tags if it is a metric of a new series and the limit is exceeded. It
+// returns false if the metric should be dropped, either as the action of the
+// limit, or because the metric has none of the offending tags.
+func (l *CardinalityLimiter) Apply(m telegraf.Metric) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	if l.track(m.HashID(), now) {
+		return true
+	}
+
+	l.limited++
+	l.warn(now)
+
+	var changed bool
+	for _, key := range l.limit.Tags {
+		if !m.HasTag(key) {
+			continue
+		}
+		switch l.limit.Action {
+		case CardinalityStripTags:
+			m.RemoveTag(key)
+			changed = true
+		case CardinalityReplaceTags:
+			m.AddTag(key, l.limit.Placeholder)
+			changed = true
+		}
+	}
+	// the series of the metrics without their offending tags are not
+	// limited, as their cardinality is the one of their other tags, but
+	// they are tracked while there is room for them.
+	if changed {
+		l.track(m.HashID(), now)
+	}
+	return changed
+}
+
+// Limited returns the number of metrics of new series limited so far.
+func (l *CardinalityLimiter) Limited() int64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.limited
+}
+
+// track returns whether the series is tracked, adding it if there is room
+// for it, once the series which expired are removed.
+func (l *CardinalityLimiter) track(id uint64, now time.Time) bool {
+	if _, ok := l.series[id]; ok {
+		l.series[id] = now
+		return true
+	}
+
+	if len(l.series) >= l.limit.MaxSeries {
+		l.sweep(now)
+	}
+	if len(l.series) >= l.limit.MaxSeries {
+		return false
+	}
+	l.series[id] = now
+	return true
+}
+
+// sweep removes the series which were not seen within the window.
+func (l *CardinalityLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < cardinalitySweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for id, seen := range l.series {
+		if now.Sub(seen) > l.limit.Window {
+			delete(l.series, id)
+		}
+	}
+}
+
+func (l *CardinalityLimiter) warn(now time.Time) {
+	if now.Sub(l.lastWarning) < cardinalityWarnInterval {
+		return
+	}
+	l.lastWarning = now
+	action := "dropping"
+	switch l.limit.Action {
+	case CardinalityStripTags:
+		action = "stripping the tags of"
+	case CardinalityReplaceTags:
+		action = "replacing the tags of"
+	}
+	l.log.Warnf("Cardinality limit of %d series within %s exceeded, "+
+		"%s the metrics of new series. Total limited metrics: %d.",
+		l.limit.MaxSeries, l.limit.Window, action, l.limited)
+}
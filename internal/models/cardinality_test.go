@@ -0,0 +1,203 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestMetric(host, requestID string) telegraf.Metric {
+	m, _ := telegraf.NewMetric("http",
+		map[string]string{"host": host, "request_id": requestID},
+		map[string]interface{}{"value": int64(1)},
+		time.Now())
+	return m
+}
+
+type testInput struct{}
+
+func (i *testInput) SampleConfig() string                  { return "" }
+func (i *testInput) Description() string                   { return "" }
+func (i *testInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func newTestLimiter(limit CardinalityLimit, now *time.Time) *CardinalityLimiter {
+	l := NewCardinalityLimiter(limit, testutil.Logger{Name: "inputs.test"})
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestCardinalityLimiter_Drop(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(CardinalityLimit{
+		MaxSeries: 2,
+		Window:    time.Hour,
+		Action:    CardinalityDrop,
+	}, &now)
+
+	assert.True(t, l.Apply(requestMetric("a", "1")))
+	assert.True(t, l.Apply(requestMetric("a", "2")))
+	assert.False(t, l.Apply(requestMetric("a", "3")))
+	// metrics of the series already seen still pass
+	assert.True(t, l.Apply(requestMetric("a", "1")))
+	assert.Equal(t, int64(1), l.Limited())
+	assert.Len(t, l.series, 2)
+}
+
+func TestCardinalityLimiter_Window(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(CardinalityLimit{
+		MaxSeries: 2,
+		Window:    time.Minute,
+		Action:    CardinalityDrop,
+	}, &now)
+
+	assert.True(t, l.Apply(requestMetric("a", "1")))
+	now = now.Add(30 * time.Second)
+	assert.True(t, l.Apply(requestMetric("a", "2")))
+	assert.False(t, l.Apply(requestMetric("a", "3")))
+
+	// the first series expires, making room for a new one
+	now = now.Add(45 * time.Second)
+	assert.True(t, l.Apply(requestMetric("a", "3")))
+	assert.False(t, l.Apply(requestMetric("a", "1")))
+}
+
+func TestCardinalityLimiter_StripTags(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(CardinalityLimit{
+		MaxSeries: 1,
+		Window:    time.Hour,
+		Action:    CardinalityStripTags,
+		Tags:      []string{"request_id"},
+	}, &now)
+
+	assert.True(t, l.Apply(requestMetric("a", "1")))
+	for i := 2; i < 10; i++ {
+		m := requestMetric("a", fmt.Sprint(i))
+		require.True(t, l.Apply(m))
+		assert.Equal(t, map[string]string{"host": "a"}, m.Tags())
+	}
+	assert.Equal(t, int64(8), l.Limited())
+	assert.Len(t, l.series, 1)
+
+	// metrics without the offending tags are dropped
+	m, _ := telegraf.NewMetric("http", map[string]string{"host": "b"},
+		map[string]interface{}{"value": int64(1)}, now)
+	assert.False(t, l.Apply(m))
+}
+
+func TestCardinalityLimiter_ReplaceTags(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(CardinalityLimit{
+		MaxSeries:   2,
+		Window:      time.Hour,
+		Action:      CardinalityReplaceTags,
+		Tags:        []string{"request_id"},
+		Placeholder: "other",
+	}, &now)
+
+	assert.True(t, l.Apply(requestMetric("a", "1")))
+	m := requestMetric("a", "2")
+	assert.True(t, l.Apply(m))
+	assert.Equal(t, "2", m.Tags()["request_id"])
+
+	m = requestMetric("a", "3")
+	assert.True(t, l.Apply(m))
+	assert.Equal(t, map[string]string{"host": "a", "request_id": "other"},
+		m.Tags())
+}
+
+func TestCardinalityLimit_Validate(t *testing.T) {
+	assert.NoError(t, CardinalityLimit{Action: CardinalityDrop}.Validate())
+	assert.Error(t, CardinalityLimit{Action: CardinalityStripTags}.Validate())
+	assert.Error(t, CardinalityLimit{Action: "truncate"}.Validate())
+}
+
+func TestRunningOutput_CardinalityLimit(t *testing.T) {
+	conf := &OutputConfig{
+		Name: "test",
+		CardinalityLimit: CardinalityLimit{
+			MaxSeries: 2,
+			Window:    time.Hour,
+			Action:    CardinalityDrop,
+		},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	for i := 0; i < 5; i++ {
+		ro.AddMetric(requestMetric("a", fmt.Sprint(i%3)))
+	}
+	require.NoError(t, ro.Write())
+	// the metrics of the third series are dropped
+	assert.Len(t, m.Metrics(), 4)
+	assert.Equal(t, int64(1), ro.CardinalityLimited())
+}
+
+func TestRunningOutput_CardinalityLimitTracking(t *testing.T) {
+	conf := &OutputConfig{
+		Name: "test",
+		CardinalityLimit: CardinalityLimit{
+			MaxSeries: 1,
+			Window:    time.Hour,
+			Action:    CardinalityDrop,
+		},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	delivered := make(chan telegraf.DeliveryInfo, 1)
+	group, _ := NewTrackingMetricGroup(
+		[]telegraf.Metric{requestMetric("a", "0"), requestMetric("a", "1")},
+		func(info telegraf.DeliveryInfo) {
+			delivered <- info
+		})
+	for _, metric := range group {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 1)
+
+	// dropping a metric over the limit does not fail the delivery
+	require.Len(t, delivered, 1)
+	assert.True(t, (<-delivered).Delivered())
+}
+
+func TestRunningInput_CardinalityLimit(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name: "test",
+		CardinalityLimit: CardinalityLimit{
+			MaxSeries:   2,
+			Window:      time.Hour,
+			Action:      CardinalityReplaceTags,
+			Tags:        []string{"request_id"},
+			Placeholder: "other",
+		},
+	})
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		m := ri.MakeMetric("http",
+			map[string]interface{}{"value": int64(1)},
+			map[string]string{"request_id": fmt.Sprint(i)},
+			telegraf.Untyped,
+			time.Now())
+		require.NotNil(t, m)
+		ids = append(ids, m.Tags()["request_id"])
+	}
+	// the tags of the metrics of new series are replaced once there are 2
+	assert.Equal(t, []string{"0", "1", "other", "other"}, ids)
+	assert.Equal(t, int64(2), ri.CardinalityLimited())
+}
+
+func TestRunningInput_NoCardinalityLimit(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{Name: "test"})
+	assert.Equal(t, int64(0), ri.CardinalityLimited())
+}
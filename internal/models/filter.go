@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/filter"
 )
 
@@ -132,6 +133,52 @@ func (f *Filter) Apply(
 	return true
 }
 
+// ApplyMetric applies the filter to the metric in place, removing the fields
+// and tags which don't pass. It returns false if the metric should be
+// dropped, in which case it may have been modified.
+func (f *Filter) ApplyMetric(metric telegraf.Metric) bool {
+	if !f.isActive {
+		return true
+	}
+
+	// check if the measurement name should pass
+	if !f.shouldNamePass(metric.Name()) {
+		return false
+	}
+
+	// check if the tags should pass
+	if !f.tagsPass(metric.GetTag) {
+		return false
+	}
+
+	// filter fields
+	var drop []string
+	for _, field := range metric.FieldList() {
+		if !f.shouldFieldPass(field.Key) {
+			drop = append(drop, field.Key)
+		}
+	}
+	if len(drop) == len(metric.FieldList()) {
+		return false
+	}
+	for _, key := range drop {
+		metric.RemoveField(key)
+	}
+
+	// filter tags
+	drop = drop[:0]
+	for _, tag := range metric.TagList() {
+		if !f.shouldTagPass(tag.Key) {
+			drop = append(drop, tag.Key)
+		}
+	}
+	for _, key := range drop {
+		metric.RemoveTag(key)
+	}
+
+	return true
+}
+
 func (f *Filter) IsActive() bool {
 	return f.isActive
 }
@@ -175,12 +222,20 @@ func (f *Filter) shouldFieldPass(key string) bool {
 // shouldTagsPass returns true if the metric should pass, false if should drop
 // based on the tagdrop/tagpass filter parameters
 func (f *Filter) shouldTagsPass(tags map[string]string) bool {
+	return f.tagsPass(func(key string) (string, bool) {
+		value, ok := tags[key]
+		return value, ok
+	})
+}
+
+// tagsPass is shouldTagsPass for a metric which tags are returned by getTag.
+func (f *Filter) tagsPass(getTag func(key string) (string, bool)) bool {
 	if f.TagPass != nil {
 		for _, pat := range f.TagPass {
 			if pat.filter == nil {
 				continue
 			}
-			if tagval, ok := tags[pat.Name]; ok {
+			if tagval, ok := getTag(pat.Name); ok {
 				if pat.filter.Match(tagval) {
 					return true
 				}
@@ -194,7 +249,7 @@ func (f *Filter) shouldTagsPass(tags map[string]string) bool {
 			if pat.filter == nil {
 				continue
 			}
-			if tagval, ok := tags[pat.Name]; ok {
+			if tagval, ok := getTag(pat.Name); ok {
 				if pat.filter.Match(tagval) {
 					return false
 				}
@@ -209,19 +264,21 @@ func (f *Filter) shouldTagsPass(tags map[string]string) bool {
 // Apply TagInclude and TagExclude filters.
 // modifies the tags map in-place.
 func (f *Filter) filterTags(tags map[string]string) {
-	if f.tagInclude != nil {
-		for k, _ := range tags {
-			if !f.tagInclude.Match(k) {
-				delete(tags, k)
-			}
+	for k, _ := range tags {
+		if !f.shouldTagPass(k) {
+			delete(tags, k)
 		}
 	}
+}
 
-	if f.tagExclude != nil {
-		for k, _ := range tags {
-			if f.tagExclude.Match(k) {
-				delete(tags, k)
-			}
-		}
+// shouldTagPass returns true if the tag should be kept, based on the
+// taginclude/tagexclude filter parameters
+func (f *Filter) shouldTagPass(key string) bool {
+	if f.tagInclude != nil && !f.tagInclude.Match(key) {
+		return false
+	}
+	if f.tagExclude != nil && f.tagExclude.Match(key) {
+		return false
 	}
+	return true
 }
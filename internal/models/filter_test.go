@@ -2,7 +2,9 @@ package models
 
 import (
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -357,3 +359,36 @@ func TestFilter_FilterTagsMatches(t *testing.T) {
 		"mytag": "foobar",
 	}, pretags)
 }
+
+func TestFilter_ApplyMetric(t *testing.T) {
+	f := Filter{
+		NameDrop:   []string{"mem"},
+		FieldDrop:  []string{"usage_busy"},
+		TagExclude: []string{"datacenter"},
+	}
+	require.NoError(t, f.Compile())
+
+	m, _ := telegraf.NewMetric("cpu",
+		map[string]string{"host": "localhost", "datacenter": "us-east-1"},
+		map[string]interface{}{"usage_idle": float64(99), "usage_busy": float64(1)},
+		time.Now(),
+	)
+	assert.True(t, f.ApplyMetric(m))
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"usage_idle": float64(99)}, m.Fields())
+
+	m, _ = telegraf.NewMetric("mem",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"used": float64(99)},
+		time.Now(),
+	)
+	assert.False(t, f.ApplyMetric(m))
+
+	// a metric without any field left is dropped
+	m, _ = telegraf.NewMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage_busy": float64(1)},
+		time.Now(),
+	)
+	assert.False(t, f.ApplyMetric(m))
+}
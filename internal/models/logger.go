@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+)
+
+// Logger is the logger of a plugin, tagging its messages with the name of
+// the plugin in logs, as "[inputs.mysql::alias]".
+type Logger struct {
+	Name string
+	// Level is the level of the messages logged, overriding the level of the
+	// agent unless it is zero.
+	Level logger.Level
+}
+
+// NewLogger returns the logger of the plugin of the given name in logs,
+// logging its messages of the given level and above. A zero level logs the
+// messages at the level of the agent.
+func NewLogger(name string, level logger.Level) *Logger {
+	return &Logger{Name: name, Level: level}
+}
+
+// Errorf logs an error message, formatted as by fmt.Printf.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.print(logger.ERROR, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error message, formatted as by fmt.Print.
+func (l *Logger) Error(args ...interface{}) {
+	l.print(logger.ERROR, fmt.Sprint(args...))
+}
+
+// Warnf logs a warning message, formatted as by fmt.Printf.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.print(logger.WARN, fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warning message, formatted as by fmt.Print.
+func (l *Logger) Warn(args ...interface{}) {
+	l.print(logger.WARN, fmt.Sprint(args...))
+}
+
+// Infof logs an information message, formatted as by fmt.Printf.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.print(logger.INFO, fmt.Sprintf(format, args...))
+}
+
+// Info logs an information message, formatted as by fmt.Print.
+func (l *Logger) Info(args ...interface{}) {
+	l.print(logger.INFO, fmt.Sprint(args...))
+}
+
+// Debugf logs a debug message, formatted as by fmt.Printf.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.print(logger.DEBUG, fmt.Sprintf(format, args...))
+}
+
+// Debug logs a debug message, formatted as by fmt.Print.
+func (l *Logger) Debug(args ...interface{}) {
+	l.print(logger.DEBUG, fmt.Sprint(args...))
+}
+
+// print writes the message if its level is enabled, at the level of the
+// logger or else at the level of the agent.
+func (l *Logger) print(level logger.Level, msg string) {
+	if l.Level != 0 {
+		if level < l.Level {
+			return
+		}
+	} else if !logger.Enabled(level) {
+		return
+	}
+	logger.Print(fmt.Sprintf("%s [%s] %s", level.Prefix(), l.Name, msg))
+}
+
+var loggerType = reflect.TypeOf((*telegraf.Logger)(nil)).Elem()
+
+// SetLoggerOnPlugin sets the logger on the exported Log field of the
+// plugin, of type telegraf.Logger, if the plugin has one.
+func SetLoggerOnPlugin(plugin interface{}, l telegraf.Logger) {
+	v := reflect.ValueOf(plugin)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	field := v.Elem().FieldByName("Log")
+	if !field.IsValid() || !field.CanSet() || field.Type() != loggerType {
+		return
+	}
+	field.Set(reflect.ValueOf(l))
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loggingInput struct {
+	Log telegraf.Logger `toml:"-"`
+}
+
+func (i *loggingInput) SampleConfig() string                  { return "" }
+func (i *loggingInput) Description() string                   { return "" }
+func (i *loggingInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func TestNewRunningInputSetsLogger(t *testing.T) {
+	input := &loggingInput{}
+	NewRunningInput(input, &InputConfig{Name: "mysql", Alias: "replica"})
+	require.NotNil(t, input.Log)
+	assert.Equal(t, "inputs.mysql::replica", input.Log.(*Logger).Name)
+}
+
+func TestSetLoggerOnPluginWithoutLogField(t *testing.T) {
+	// plugins without a Log field of type telegraf.Logger are left alone
+	SetLoggerOnPlugin(&struct{ Log string }{}, &Logger{})
+	SetLoggerOnPlugin(struct{}{}, &Logger{})
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetFlags(0)
+	log.SetOutput(&buf)
+	defer func() {
+		log.SetFlags(flags)
+		log.SetOutput(os.Stderr)
+	}()
+
+	l := NewLogger("outputs.file::debug", logger.DEBUG)
+	l.Errorf("%d%%", 100)
+	l.Debug("debug", 1)
+
+	assert.Equal(t, "E! [outputs.file::debug] 100%\n"+
+		"D! [outputs.file::debug] debug1\n", buf.String())
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetFlags(0)
+	log.SetOutput(&buf)
+	defer func() {
+		log.SetFlags(flags)
+		log.SetOutput(os.Stderr)
+	}()
+
+	// loggers of plugins of the same name keep their own levels.
+	quiet := NewLogger("outputs.file", logger.ERROR)
+	verbose := NewLogger("outputs.file", logger.DEBUG)
+	agent := NewLogger("outputs.file", 0)
+	quiet.Info("quiet")
+	verbose.Debug("verbose")
+	agent.Debug("agent")
+	agent.Info("agent")
+
+	assert.Equal(t, "D! [outputs.file] verbose\n"+
+		"I! [outputs.file] agent\n", buf.String())
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
 )
 
 type RunningAggregator struct {
@@ -20,17 +21,21 @@ func NewRunningAggregator(
 	a telegraf.Aggregator,
 	conf *AggregatorConfig,
 ) *RunningAggregator {
-	return &RunningAggregator{
+	r := &RunningAggregator{
 		a:       a,
 		Config:  conf,
 		metrics: make(chan telegraf.Metric, 100),
 	}
+	SetLoggerOnPlugin(a, NewLogger(r.LogName(), conf.LogLevel))
+	return r
 }
 
 // AggregatorConfig containing configuration parameters for the running
 // aggregator plugin.
 type AggregatorConfig struct {
-	Name string
+	Name     string
+	Alias    string
+	LogLevel logger.Level
 
 	DropOriginal      bool
 	NameOverride      string
@@ -47,6 +52,11 @@ func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// LogName returns the name of the aggregator in logs, along with its alias.
+func (r *RunningAggregator) LogName() string {
+	return logName("aggregators", r.Config.Name, r.Config.Alias)
+}
+
 func (r *RunningAggregator) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
@@ -77,25 +87,47 @@ func (r *RunningAggregator) MakeMetric(
 
 // Add applies the given metric to the aggregator.
 // Before applying to the plugin, it will run any defined filters on the metric.
+// The metric is modified by the filters, so it must be a copy only the
+// aggregator uses.
 // Apply returns true if the original metric should be dropped.
 func (r *RunningAggregator) Add(in telegraf.Metric) bool {
-	if r.Config.Filter.IsActive() {
-		// check if the aggregator should apply this metric
-		name := in.Name()
-		fields := in.Fields()
-		tags := in.Tags()
-		t := in.Time()
-		if ok := r.Config.Filter.Apply(name, fields, tags); !ok {
-			// aggregator should not apply this metric
-			return false
-		}
-
-		in, _ = telegraf.NewMetric(name, tags, fields, t)
+	if !r.filter(in) {
+		// aggregator should not apply this metric
+		return false
 	}
 
 	r.metrics <- in
 	return r.Config.DropOriginal
 }
+
+// Apply is like Add, but applies the metric to the aggregator right away,
+// whatever its time, for an aggregator which is not running. The aggregates
+// are then pushed with Push.
+func (r *RunningAggregator) Apply(in telegraf.Metric) bool {
+	if !r.filter(in) {
+		return false
+	}
+
+	r.add(in)
+	return r.Config.DropOriginal
+}
+
+// Push pushes the aggregates of the metrics applied so far to acc, and
+// resets the aggregator, for an aggregator which is not running.
+func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
+	r.push(acc)
+	r.reset()
+}
+
+// filter runs any defined filters on the metric, and returns whether the
+// aggregator should apply it.
+func (r *RunningAggregator) filter(in telegraf.Metric) bool {
+	if r.Config.Filter.IsActive() {
+		return r.Config.Filter.ApplyMetric(in)
+	}
+	return true
+}
+
 func (r *RunningAggregator) add(in telegraf.Metric) {
 	r.a.Add(in)
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
 )
 
 type RunningInput struct {
@@ -14,23 +15,50 @@ type RunningInput struct {
 	trace       bool
 	debug       bool
 	defaultTags map[string]string
+	limiter     *CardinalityLimiter
+}
+
+// NewRunningInput returns the running input of the given input, setting
+// its logger.
+func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
+	r := &RunningInput{
+		Input:  input,
+		Config: config,
+	}
+	log := NewLogger(r.LogName(), config.LogLevel)
+	SetLoggerOnPlugin(input, log)
+	if config.CardinalityLimit.MaxSeries > 0 {
+		r.limiter = NewCardinalityLimiter(config.CardinalityLimit, log)
+	}
+	return r
 }
 
 // InputConfig containing a name, interval, and filter
 type InputConfig struct {
 	Name              string
+	Alias             string
+	LogLevel          logger.Level
 	NameOverride      string
 	MeasurementPrefix string
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+	CardinalityLimit  CardinalityLimit
 	Interval          time.Duration
+	// AlignTimestamps aligns the timestamps of the metrics gathered to the
+	// multiple of the interval the input is gathered at.
+	AlignTimestamps bool
 }
 
 func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// LogName returns the name of the input in logs, along with its alias.
+func (r *RunningInput) LogName() string {
+	return logName("inputs", r.Config.Name, r.Config.Alias)
+}
+
 // MakeMetric either returns a metric, or returns nil if the metric doesn't
 // need to be created (because of filtering, an error, etc.)
 func (r *RunningInput) MakeMetric(
@@ -56,6 +84,10 @@ func (r *RunningInput) MakeMetric(
 		t,
 	)
 
+	if m != nil && r.limiter != nil && !r.limiter.Apply(m) {
+		return nil
+	}
+
 	if r.trace && m != nil {
 		fmt.Println("> " + m.String())
 	}
@@ -63,6 +95,15 @@ func (r *RunningInput) MakeMetric(
 	return m
 }
 
+// CardinalityLimited returns the number of metrics of new series limited by
+// the cardinality limit of the input so far.
+func (r *RunningInput) CardinalityLimited() int64 {
+	if r.limiter == nil {
+		return 0
+	}
+	return r.limiter.Limited()
+}
+
 func (r *RunningInput) Debug() bool {
 	return r.debug
 }
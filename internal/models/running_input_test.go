@@ -350,3 +350,15 @@ func TestMakeMetricNameSuffix(t *testing.T) {
 		fmt.Sprintf("RITest_foobar value=101i %d", now.UnixNano()),
 	)
 }
+
+func TestRunningInputLogName(t *testing.T) {
+	ri := RunningInput{
+		Config: &InputConfig{
+			Name: "mysql",
+		},
+	}
+	assert.Equal(t, "inputs.mysql", ri.LogName())
+
+	ri.Config.Alias = "replica"
+	assert.Equal(t, "inputs.mysql::replica", ri.LogName())
+}
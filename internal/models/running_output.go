@@ -1,11 +1,12 @@
 package models
 
 import (
-	"log"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
 const (
@@ -25,8 +26,14 @@ type RunningOutput struct {
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	// Serializer is the serializer of the output, when it writes metrics
+	// in a configurable data format.
+	Serializer serializers.Serializer
+
 	metrics     *buffer.Buffer
 	failMetrics *buffer.Buffer
+	log         telegraf.Logger
+	limiter     *CardinalityLimiter
 }
 
 func NewRunningOutput(
@@ -51,47 +58,69 @@ func NewRunningOutput(
 		MetricBufferLimit: bufferLimit,
 		MetricBatchSize:   batchSize,
 	}
+	ro.log = NewLogger(ro.LogName(), conf.LogLevel)
+	SetLoggerOnPlugin(output, ro.log)
+	if conf.CardinalityLimit.MaxSeries > 0 {
+		ro.limiter = NewCardinalityLimiter(conf.CardinalityLimit, ro.log)
+	}
 	return ro
 }
 
 // AddMetric adds a metric to the output. This function can also write cached
 // points if FlushBufferWhenFull is true.
 func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
-	// Filter any tagexclude/taginclude parameters before adding metric
+	// Filter any tagexclude/taginclude parameters before adding metric. Each
+	// output has its own copy of the metric, which is filtered in place.
 	if ro.Config.Filter.IsActive() {
-		// In order to filter out tags, we need to create a new metric, since
-		// metrics are immutable once created.
-		name := metric.Name()
-		tags := metric.Tags()
-		fields := metric.Fields()
-		t := metric.Time()
-		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
+		if ok := ro.Config.Filter.ApplyMetric(metric); !ok {
+			Accept(metric)
 			return
 		}
-		// error is not possible if creating from another metric, so ignore.
-		metric, _ = telegraf.NewMetric(name, tags, fields, t)
+	}
+	// metrics over the cardinality limit are dropped on purpose, as filtered
+	// ones are, rather than failing their delivery.
+	if ro.limiter != nil && !ro.limiter.Apply(metric) {
+		Accept(metric)
+		return
 	}
 
-	ro.metrics.Add(metric)
+	rejectAll(ro.metrics.Add(metric))
 	if ro.metrics.Len() == ro.MetricBatchSize {
 		batch := ro.metrics.Batch(ro.MetricBatchSize)
 		err := ro.write(batch)
 		if err != nil {
-			ro.failMetrics.Add(batch...)
+			rejectAll(ro.failMetrics.Add(batch...))
 		}
 	}
 }
 
+// CardinalityLimited returns the number of metrics of new series limited by
+// the cardinality limit of the output so far.
+func (ro *RunningOutput) CardinalityLimited() int64 {
+	if ro.limiter == nil {
+		return 0
+	}
+	return ro.limiter.Limited()
+}
+
+// LogName returns the name of the output in logs, along with its alias.
+func (ro *RunningOutput) LogName() string {
+	return logName("outputs", ro.Name, ro.Config.Alias)
+}
+
 // Write writes all cached points to this output.
 func (ro *RunningOutput) Write() error {
 	if !ro.Quiet {
-		log.Printf("I! Output [%s] buffer fullness: %d / %d metrics. "+
+		ro.log.Infof("Buffer fullness: %d / %d metrics. "+
 			"Total gathered metrics: %d. Total dropped metrics: %d.",
-			ro.Name,
 			ro.failMetrics.Len()+ro.metrics.Len(),
 			ro.MetricBufferLimit,
 			ro.metrics.Total(),
 			ro.metrics.Drops()+ro.failMetrics.Drops())
+		if ro.limiter != nil {
+			ro.log.Infof("Total cardinality limited metrics: %d.",
+				ro.limiter.Limited())
+		}
 	}
 
 	var err error
@@ -115,7 +144,7 @@ func (ro *RunningOutput) Write() error {
 				err = ro.write(batch)
 			}
 			if err != nil {
-				ro.failMetrics.Add(batch...)
+				rejectAll(ro.failMetrics.Add(batch...))
 			}
 		}
 	}
@@ -127,7 +156,7 @@ func (ro *RunningOutput) Write() error {
 		err = ro.write(batch)
 	}
 	if err != nil {
-		ro.failMetrics.Add(batch...)
+		rejectAll(ro.failMetrics.Add(batch...))
 		return err
 	}
 	return nil
@@ -141,16 +170,39 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
 	if err == nil {
+		for _, m := range metrics {
+			Accept(m)
+		}
 		if !ro.Quiet {
-			log.Printf("I! Output [%s] wrote batch of %d metrics in %s\n",
-				ro.Name, len(metrics), elapsed)
+			ro.log.Infof("Wrote batch of %d metrics in %s",
+				len(metrics), elapsed)
 		}
 	}
 	return err
 }
 
+// rejectAll rejects metrics dropped from a full buffer.
+func rejectAll(dropped []telegraf.Metric) {
+	for _, m := range dropped {
+		Reject(m)
+	}
+}
+
 // OutputConfig containing name and filter
 type OutputConfig struct {
-	Name   string
-	Filter Filter
+	Name     string
+	Alias    string
+	LogLevel logger.Level
+	Filter   Filter
+
+	CardinalityLimit CardinalityLimit
+}
+
+// logName returns the name of a plugin in logs, as "inputs.mysql", or as
+// "inputs.mysql::alias" for a plugin with an alias.
+func logName(kind, name, alias string) string {
+	if alias == "" {
+		return kind + "." + name
+	}
+	return kind + "." + name + "::" + alias
 }
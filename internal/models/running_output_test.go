@@ -140,7 +140,8 @@ func TestRunningOutput_TagIncludeNoMatch(t *testing.T) {
 	m := &mockOutput{}
 	ro := NewRunningOutput("test", m, conf, 1000, 10000)
 
-	ro.AddMetric(first5[0])
+	// the output filters the metric in place, so it is given a copy.
+	ro.AddMetric(first5[0].Copy())
 	assert.Len(t, m.Metrics(), 0)
 
 	err := ro.Write()
@@ -161,7 +162,7 @@ func TestRunningOutput_TagExcludeMatch(t *testing.T) {
 	m := &mockOutput{}
 	ro := NewRunningOutput("test", m, conf, 1000, 10000)
 
-	ro.AddMetric(first5[0])
+	ro.AddMetric(first5[0].Copy())
 	assert.Len(t, m.Metrics(), 0)
 
 	err := ro.Write()
@@ -182,7 +183,7 @@ func TestRunningOutput_TagExcludeNoMatch(t *testing.T) {
 	m := &mockOutput{}
 	ro := NewRunningOutput("test", m, conf, 1000, 10000)
 
-	ro.AddMetric(first5[0])
+	ro.AddMetric(first5[0].Copy())
 	assert.Len(t, m.Metrics(), 0)
 
 	err := ro.Write()
@@ -203,7 +204,7 @@ func TestRunningOutput_TagIncludeMatch(t *testing.T) {
 	m := &mockOutput{}
 	ro := NewRunningOutput("test", m, conf, 1000, 10000)
 
-	ro.AddMetric(first5[0])
+	ro.AddMetric(first5[0].Copy())
 	assert.Len(t, m.Metrics(), 0)
 
 	err := ro.Write()
@@ -540,3 +541,60 @@ func (m *perfOutput) Write(metrics []telegraf.Metric) error {
 	}
 	return nil
 }
+
+func TestRunningOutputTracking(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			NameDrop: []string{"metric1"},
+		},
+	}
+	assert.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	delivered := make(chan telegraf.DeliveryInfo, 1)
+	group, _ := NewTrackingMetricGroup(first5, func(info telegraf.DeliveryInfo) {
+		delivered <- info
+	})
+	for _, metric := range group {
+		ro.AddMetric(metric)
+	}
+	assert.Len(t, delivered, 0)
+
+	m.failWrite = true
+	assert.Error(t, ro.Write())
+	assert.Len(t, delivered, 0)
+
+	m.failWrite = false
+	assert.NoError(t, ro.Write())
+	require.Len(t, delivered, 1)
+	assert.True(t, (<-delivered).Delivered())
+	assert.Len(t, m.Metrics(), 4)
+}
+
+func TestRunningOutputTrackingBufferFull(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 5, 5)
+
+	delivered := make(chan telegraf.DeliveryInfo, 1)
+	group, _ := NewTrackingMetricGroup(first5, func(info telegraf.DeliveryInfo) {
+		delivered <- info
+	})
+	for _, metric := range group {
+		ro.AddMetric(metric)
+	}
+	assert.Len(t, delivered, 0)
+
+	// the tracked metrics are dropped from the full buffer.
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.Len(t, delivered, 1)
+	assert.False(t, (<-delivered).Delivered())
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
 )
 
 type RunningProcessor struct {
@@ -10,6 +11,21 @@ type RunningProcessor struct {
 	Config    *ProcessorConfig
 }
 
+// NewRunningProcessor returns the running processor of the given processor,
+// setting its logger.
+func NewRunningProcessor(
+	processor telegraf.Processor,
+	config *ProcessorConfig,
+) *RunningProcessor {
+	rp := &RunningProcessor{
+		Name:      config.Name,
+		Processor: processor,
+		Config:    config,
+	}
+	SetLoggerOnPlugin(processor, NewLogger(rp.LogName(), config.LogLevel))
+	return rp
+}
+
 type RunningProcessors []*RunningProcessor
 
 func (rp RunningProcessors) Len() int           { return len(rp) }
@@ -18,9 +34,16 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 
 // FilterConfig containing a name and filter
 type ProcessorConfig struct {
-	Name   string
-	Order  int64
-	Filter Filter
+	Name     string
+	Alias    string
+	LogLevel logger.Level
+	Order    int64
+	Filter   Filter
+}
+
+// LogName returns the name of the processor in logs, along with its alias.
+func (rp *RunningProcessor) LogName() string {
+	return logName("processors", rp.Config.Name, rp.Config.Alias)
 }
 
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+var lastTrackingID uint64
+
+func newTrackingID() telegraf.TrackingID {
+	return telegraf.TrackingID(atomic.AddUint64(&lastTrackingID, 1))
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d *deliveryInfo) ID() telegraf.TrackingID {
+	return d.id
+}
+
+func (d *deliveryInfo) Delivered() bool {
+	return d.delivered
+}
+
+// trackingData is shared by all metrics of a tracked group, and all of their
+// copies. It counts the metrics of the group still to be processed.
+type trackingData struct {
+	id       telegraf.TrackingID
+	refs     int32
+	rejected int32
+	notify   func(telegraf.DeliveryInfo)
+}
+
+func (d *trackingData) incr() {
+	atomic.AddInt32(&d.refs, 1)
+}
+
+func (d *trackingData) decr() {
+	if atomic.AddInt32(&d.refs, -1) == 0 {
+		d.notify(&deliveryInfo{
+			id:        d.id,
+			delivered: atomic.LoadInt32(&d.rejected) == 0,
+		})
+	}
+}
+
+// trackingMetric is a metric of a tracked group.
+type trackingMetric struct {
+	telegraf.Metric
+	d *trackingData
+}
+
+// NewTrackingMetricGroup returns the metrics of the group wrapped so that
+// notify is called once all of them have been accepted or rejected. notify
+// is called right away when the group is empty.
+func NewTrackingMetricGroup(
+	group []telegraf.Metric,
+	notify func(telegraf.DeliveryInfo),
+) ([]telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{
+		id:     newTrackingID(),
+		refs:   int32(len(group)),
+		notify: notify,
+	}
+	if len(group) == 0 {
+		notify(&deliveryInfo{id: d.id, delivered: true})
+		return nil, d.id
+	}
+
+	tracked := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		tracked = append(tracked, &trackingMetric{Metric: unwrap(m), d: d})
+	}
+	return tracked, d.id
+}
+
+func unwrap(m telegraf.Metric) telegraf.Metric {
+	if tm, ok := m.(*trackingMetric); ok {
+		return tm.Metric
+	}
+	return m
+}
+
+// Accept marks the metric as processed, if it is tracked. Metrics are
+// accepted once written by an output, or when they are filtered, aggregated
+// or cardinality limited away.
+func Accept(m telegraf.Metric) {
+	if tm, ok := m.(*trackingMetric); ok {
+		tm.d.decr()
+	}
+}
+
+// Reject marks the metric as processed but not delivered, if it is tracked.
+func Reject(m telegraf.Metric) {
+	if tm, ok := m.(*trackingMetric); ok {
+		atomic.StoreInt32(&tm.d.rejected, 1)
+		tm.d.decr()
+	}
+}
+
+// TrackCopy returns dup, a copy of orig, tracked along with orig when orig
+// is tracked. The copy must be accepted or rejected in addition to orig.
+func TrackCopy(orig, dup telegraf.Metric) telegraf.Metric {
+	tm, ok := orig.(*trackingMetric)
+	if !ok {
+		return dup
+	}
+	tm.d.incr()
+	return &trackingMetric{Metric: unwrap(dup), d: tm.d}
+}
+
+// TrackProcessed tracks the metrics processors returned for orig along with
+// it, when orig is tracked. orig is accepted if the processors didn't return
+// it.
+func TrackProcessed(orig telegraf.Metric, out []telegraf.Metric) []telegraf.Metric {
+	if _, ok := orig.(*trackingMetric); !ok {
+		return out
+	}
+
+	var kept bool
+	for i, m := range out {
+		if m == orig && !kept {
+			kept = true
+			continue
+		}
+		out[i] = TrackCopy(orig, m)
+	}
+	if !kept {
+		Accept(orig)
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGroup(n int) ([]telegraf.Metric, telegraf.TrackingID, chan telegraf.DeliveryInfo) {
+	var group []telegraf.Metric
+	for i := 0; i < n; i++ {
+		group = append(group, testutil.TestMetric(i))
+	}
+	delivered := make(chan telegraf.DeliveryInfo, 1)
+	tracked, id := NewTrackingMetricGroup(group, func(info telegraf.DeliveryInfo) {
+		delivered <- info
+	})
+	return tracked, id, delivered
+}
+
+func TestTrackingAccept(t *testing.T) {
+	group, id, delivered := newTestGroup(2)
+	require.Len(t, group, 2)
+
+	Accept(group[0])
+	assert.Len(t, delivered, 0)
+	Accept(group[1])
+
+	require.Len(t, delivered, 1)
+	info := <-delivered
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
+
+func TestTrackingReject(t *testing.T) {
+	group, id, delivered := newTestGroup(2)
+
+	Reject(group[0])
+	Accept(group[1])
+
+	require.Len(t, delivered, 1)
+	info := <-delivered
+	assert.Equal(t, id, info.ID())
+	assert.False(t, info.Delivered())
+}
+
+func TestTrackingEmptyGroup(t *testing.T) {
+	group, id, delivered := newTestGroup(0)
+	assert.Len(t, group, 0)
+
+	require.Len(t, delivered, 1)
+	info := <-delivered
+	assert.Equal(t, id, info.ID())
+	assert.True(t, info.Delivered())
+}
+
+func TestTrackingIDs(t *testing.T) {
+	_, id1, _ := newTestGroup(1)
+	_, id2, _ := newTestGroup(1)
+	assert.NotEqual(t, id1, id2)
+}
+
+func TestTrackCopy(t *testing.T) {
+	group, _, delivered := newTestGroup(1)
+
+	dup := TrackCopy(group[0], testutil.TestMetric(0))
+	Accept(group[0])
+	assert.Len(t, delivered, 0)
+	Accept(dup)
+	assert.Len(t, delivered, 1)
+
+	// copies of untracked metrics are untracked.
+	m := testutil.TestMetric(0)
+	assert.Equal(t, m, TrackCopy(testutil.TestMetric(1), m))
+}
+
+func TestTrackProcessed(t *testing.T) {
+	// the processors passed the metric through, and added another.
+	group, _, delivered := newTestGroup(1)
+	out := TrackProcessed(group[0], []telegraf.Metric{group[0], testutil.TestMetric(1)})
+	require.Len(t, out, 2)
+	assert.Equal(t, group[0], out[0])
+	Accept(out[0])
+	assert.Len(t, delivered, 0)
+	Accept(out[1])
+	assert.Len(t, delivered, 1)
+
+	// the processors replaced the metric.
+	group, _, delivered = newTestGroup(1)
+	out = TrackProcessed(group[0], []telegraf.Metric{testutil.TestMetric(1)})
+	require.Len(t, out, 1)
+	assert.Len(t, delivered, 0)
+	Accept(out[0])
+	assert.Len(t, delivered, 1)
+
+	// the processors dropped the metric.
+	group, _, delivered = newTestGroup(1)
+	out = TrackProcessed(group[0], nil)
+	assert.Len(t, out, 0)
+	assert.Len(t, delivered, 1)
+}
@@ -1,27 +1,201 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"time"
+)
+
+// Level is the level of a log message, given by its prefix: "D!", "I!",
+// "W!" or "E!".
+type Level int
 
-	"github.com/influxdata/wlog"
+const (
+	DEBUG Level = iota + 1
+	INFO
+	WARN
+	ERROR
 )
 
+var levelNames = map[Level]string{
+	DEBUG: "debug",
+	INFO:  "info",
+	WARN:  "warn",
+	ERROR: "error",
+}
+
+var levelPrefixes = map[Level]string{
+	DEBUG: "D!",
+	INFO:  "I!",
+	WARN:  "W!",
+	ERROR: "E!",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Prefix returns the prefix of the messages of the level, as "D!".
+func (l Level) Prefix() string {
+	return levelPrefixes[l]
+}
+
+// ParseLevel returns the level of the given name: "debug", "info", "warn"
+// or "error".
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.ToLower(name) == levelName {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level %q, must be one of "+
+		"\"debug\", \"info\", \"warn\" or \"error\"", name)
+}
+
+// The log formats.
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+var (
+	mu    sync.RWMutex
+	level = INFO
+
+	// pluginLog writes the messages of plugins, whose level is checked by
+	// the loggers of the plugins, once the logging is set up.
+	pluginLog *log.Logger
+
+	// logfile is the log file opened by the last setup, if any.
+	logfile io.Closer
+)
+
+// Enabled returns whether messages of level l are written at the level of
+// the agent.
+func Enabled(l Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return l >= level
+}
+
+// Print writes the message of a plugin, whose level was checked by the
+// logger of the plugin, whatever the level of the agent. Before the logging
+// is set up, the message is written by the standard logger.
+func Print(s string) {
+	mu.RLock()
+	l := pluginLog
+	mu.RUnlock()
+	if l == nil {
+		log.Print(s)
+		return
+	}
+	l.Print(s)
+}
+
+// Config is the configuration of the logging output.
+type Config struct {
+	// Debug sets the log level to DEBUG.
+	Debug bool
+	// Quiet sets the log level to ERROR.
+	Quiet bool
+	// Logfile directs the logging output to a file. Empty string is
+	// interpreted as stderr. If there is an error opening the file the
+	// logger will fallback to stderr.
+	Logfile string
+	// Format is the format of the messages, "text" or "json". Empty string
+	// is interpreted as "text".
+	Format string
+	// RotationMaxSize is the size, in bytes, at which the log file is
+	// rotated. The log file is not rotated when it is zero.
+	RotationMaxSize int64
+	// RotationMaxArchives is the number of rotated log files kept.
+	RotationMaxArchives int
+}
+
 // newTelegrafWriter returns a logging-wrapped writer.
 func newTelegrafWriter(w io.Writer) io.Writer {
 	return &telegrafLog{
-		writer: wlog.NewWriter(w),
+		writer: w,
+		format: TextFormat,
 	}
 }
 
 type telegrafLog struct {
 	writer io.Writer
+	format string
+	// unfiltered writes the messages whatever their level.
+	unfiltered bool
+}
+
+// jsonMessage is a message written in the JSON format.
+type jsonMessage struct {
+	Time    string `json:"time"`
+	Level   string `json:"level,omitempty"`
+	Plugin  string `json:"plugin,omitempty"`
+	Message string `json:"msg"`
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
-	return t.writer.Write(append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...))
+	l, plugin, msg := parseMessage(b)
+	if l != 0 && !t.unfiltered && !Enabled(l) {
+		return len(b), nil
+	}
+
+	ts := time.Now().UTC().Format(time.RFC3339)
+	if t.format != JSONFormat {
+		return t.writer.Write(append([]byte(ts+" "), b...))
+	}
+
+	line, err := json.Marshal(jsonMessage{
+		Time:    ts,
+		Level:   l.String(),
+		Plugin:  plugin,
+		Message: string(bytes.TrimRight(msg, "\n")),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err = t.writer.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// parseMessage returns the level of the message, the plugin which logged it,
+// when the message is tagged as "[inputs.mysql]", and the message itself
+// without them. Messages without a level are always written.
+func parseMessage(b []byte) (Level, string, []byte) {
+	if len(b) < 3 || b[1] != '!' || b[2] != ' ' {
+		return 0, "", b
+	}
+
+	var l Level
+	switch b[0] {
+	case 'D':
+		l = DEBUG
+	case 'I':
+		l = INFO
+	case 'W':
+		l = WARN
+	case 'E':
+		l = ERROR
+	default:
+		return 0, "", b
+	}
+
+	msg := b[3:]
+	if len(msg) > 0 && msg[0] == '[' {
+		if end := bytes.Index(msg, []byte("] ")); end > 0 {
+			return l, string(msg[1:end]), msg[end+2:]
+		}
+	}
+	return l, "", msg
 }
 
 // SetupLogging configures the logging output.
@@ -31,30 +205,69 @@ func (t *telegrafLog) Write(b []byte) (n int, err error) {
 //           interpreted as stderr. If there is an error opening the file the
 //           logger will fallback to stderr.
 func SetupLogging(debug, quiet bool, logfile string) {
+	Setup(Config{
+		Debug:   debug,
+		Quiet:   quiet,
+		Logfile: logfile,
+	})
+}
+
+// Setup configures the logging output, as SetupLogging, with the format and
+// the rotation of the log file of the given configuration.
+func Setup(config Config) {
 	log.SetFlags(0)
-	if debug {
-		wlog.SetLevel(wlog.DEBUG)
+
+	mu.Lock()
+	level = INFO
+	if config.Debug {
+		level = DEBUG
 	}
-	if quiet {
-		wlog.SetLevel(wlog.ERROR)
+	if config.Quiet {
+		level = ERROR
 	}
+	previous := logfile
+	logfile = nil
+	mu.Unlock()
 
-	var oFile *os.File
-	if logfile != "" {
-		if _, err := os.Stat(logfile); os.IsNotExist(err) {
-			if oFile, err = os.Create(logfile); err != nil {
-				log.Printf("E! Unable to create %s (%s), using stderr", logfile, err)
-				oFile = os.Stderr
-			}
+	var errs []string
+	var w io.Writer = os.Stderr
+	if config.Logfile != "" {
+		f, err := openRotatingFile(config.Logfile,
+			config.RotationMaxSize, config.RotationMaxArchives)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("E! Unable to open %s (%s), using stderr",
+				config.Logfile, err))
 		} else {
-			if oFile, err = os.OpenFile(logfile, os.O_APPEND|os.O_WRONLY, os.ModeAppend); err != nil {
-				log.Printf("E! Unable to append to %s (%s), using stderr", logfile, err)
-				oFile = os.Stderr
-			}
+			w = f
+			mu.Lock()
+			logfile = f
+			mu.Unlock()
 		}
-	} else {
-		oFile = os.Stderr
 	}
 
-	log.SetOutput(newTelegrafWriter(oFile))
+	format := config.Format
+	switch format {
+	case "":
+		format = TextFormat
+	case TextFormat, JSONFormat:
+	default:
+		errs = append(errs, fmt.Sprintf("E! Unknown log format %q, using %q",
+			format, TextFormat))
+		format = TextFormat
+	}
+
+	log.SetOutput(&telegrafLog{writer: w, format: format})
+	mu.Lock()
+	pluginLog = log.New(&telegrafLog{
+		writer:     w,
+		format:     format,
+		unfiltered: true,
+	}, "", 0)
+	mu.Unlock()
+	for _, err := range errs {
+		log.Print(err)
+	}
+	if previous != nil {
+		previous.Close()
+	}
 }
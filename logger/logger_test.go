@@ -2,12 +2,16 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWriteLogToFile(t *testing.T) {
@@ -51,6 +55,113 @@ func TestErrorWriteLogToFile(t *testing.T) {
 	assert.Equal(t, f[19:], []byte("Z E! TEST\n"))
 }
 
+func TestPluginLevelWriteLogToFile(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	SetupLogging(false, false, tmpfile.Name())
+	defer SetupLogging(false, false, "")
+	// the level of the messages of plugins is checked by their loggers.
+	Print("D! [inputs.tail] TEST")
+	log.Printf("D! [inputs.cpu] TEST") // <- should be ignored
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(f)), "\n")
+	require.Len(t, lines, 1)
+	assert.Equal(t, "Z D! [inputs.tail] TEST", lines[0][19:])
+}
+
+func TestJSONWriteLogToFile(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	Setup(Config{Logfile: tmpfile.Name(), Format: JSONFormat})
+	defer SetupLogging(false, false, "")
+	log.Printf("W! [inputs.tail::syslog] TEST \"quoted\"\n")
+	log.Printf("TEST")
+	log.Printf("D! TEST") // <- should be ignored
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(f)), "\n")
+	require.Len(t, lines, 2)
+
+	var msg map[string]string
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &msg))
+	assert.Len(t, msg["time"], 20)
+	delete(msg, "time")
+	assert.Equal(t, map[string]string{
+		"level":  "warn",
+		"plugin": "inputs.tail::syslog",
+		"msg":    `TEST "quoted"`,
+	}, msg)
+
+	msg = nil
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &msg))
+	delete(msg, "time")
+	assert.Equal(t, map[string]string{"msg": "TEST"}, msg)
+}
+
+func TestRotateLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegraf.log")
+
+	f, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	for file, content := range map[string]string{
+		path:        "six\n",
+		path + ".1": "four\nfive\n",
+		path + ".2": "three\n",
+	} {
+		b, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(b), file)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotateLogFileWithoutArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegraf.log")
+
+	f, err := openRotatingFile(path, 5, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("one\n"))
+	require.NoError(t, err)
+	_, err = f.Write([]byte("two\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "two\n", string(b))
+	_, err = os.Stat(path + ".1")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("Debug")
+	assert.NoError(t, err)
+	assert.Equal(t, DEBUG, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
 func BenchmarkTelegrafLogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
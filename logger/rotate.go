@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile is a log file which is rotated once it reaches maxSize bytes.
+// The rotated files are named after the log file, as "telegraf.log.1" for
+// the most recent one, and only maxArchives of them are kept.
+type rotatingFile struct {
+	sync.Mutex
+
+	path        string
+	maxSize     int64
+	maxArchives int
+
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxArchives int) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:        path,
+		maxSize:     maxSize,
+		maxArchives: maxArchives,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write writes b to the log file, rotating it first if b would make it
+// exceed its maximum size. Writes are synchronized, as both the standard
+// logger and the logger of the messages of plugins write to the file.
+func (r *rotatingFile) Write(b []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(b)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the log file as the most recent archive, shifting the
+// older archives and removing the oldest one, and opens a new log file. The
+// log file is reopened even if it could not be archived.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	err := r.archiveFile()
+	if oerr := r.open(); oerr != nil {
+		return oerr
+	}
+	return err
+}
+
+func (r *rotatingFile) archiveFile() error {
+	if r.maxArchives <= 0 {
+		return os.Remove(r.path)
+	}
+	for i := r.maxArchives - 1; i > 0; i-- {
+		err := os.Rename(r.archive(i), r.archive(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(r.path, r.archive(1))
+}
+
+func (r *rotatingFile) archive(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+func (r *rotatingFile) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.file.Close()
+}
@@ -1,6 +1,14 @@
 package telegraf
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/client/v2"
@@ -18,6 +26,18 @@ const (
 	Untyped
 )
 
+// Tag is a tag of a metric.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Field is a field of a metric.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
 type Metric interface {
 	// Name returns the measurement name of the metric
 	Name() string
@@ -25,6 +45,10 @@ type Metric interface {
 	// Name returns the tags associated with the metric
 	Tags() map[string]string
 
+	// TagList returns the tags of the metric, sorted by key. The list must
+	// not be modified.
+	TagList() []Tag
+
 	// Time return the timestamp for the metric
 	Time() time.Time
 
@@ -41,6 +65,10 @@ type Metric interface {
 	// Fields returns the fields for the metric
 	Fields() map[string]interface{}
 
+	// FieldList returns the fields of the metric. The list must not be
+	// modified.
+	FieldList() []Field
+
 	// String returns a line-protocol string of the metric
 	String() string
 
@@ -55,11 +83,45 @@ type Metric interface {
 	SetAggregate(bool)
 	// IsAggregate returns true if the metric is an aggregate
 	IsAggregate() bool
+
+	// SetName sets the measurement name of the metric
+	SetName(name string)
+	// SetTime sets the timestamp of the metric
+	SetTime(t time.Time)
+
+	// HasTag returns true if the metric has the tag
+	HasTag(key string) bool
+	// GetTag returns the value of the tag, and whether the metric has it
+	GetTag(key string) (string, bool)
+	// AddTag adds the tag, replacing its value if the metric already has it.
+	// Tags with an empty key or value are not added.
+	AddTag(key, value string)
+	// RemoveTag removes the tag, if the metric has it
+	RemoveTag(key string)
+
+	// HasField returns true if the metric has the field
+	HasField(key string) bool
+	// GetField returns the value of the field, and whether the metric has it
+	GetField(key string) (interface{}, bool)
+	// AddField adds the field, replacing its value if the metric already has
+	// it. Integers are converted to int64, and float32 to float64. nil values
+	// are not added.
+	AddField(key string, value interface{})
+	// RemoveField removes the field, if the metric has it
+	RemoveField(key string)
+
+	// Copy returns a copy of the metric, which can be modified independently
+	Copy() Metric
 }
 
-// metric is a wrapper of the influxdb client.Point struct
+// metric holds the tags sorted by key, and the fields in the order they were
+// added. Maps are only built when asked for, and line-protocol when
+// serialized.
 type metric struct {
-	pt models.Point
+	name   string
+	tags   []Tag
+	fields []Field
+	t      time.Time
 
 	mType ValueType
 
@@ -67,10 +129,24 @@ type metric struct {
 }
 
 func NewMetricFromPoint(pt models.Point) Metric {
-	return &metric{
-		pt:    pt,
+	m := &metric{
+		name:  pt.Name(),
+		t:     pt.Time(),
 		mType: Untyped,
 	}
+
+	ptTags := pt.Tags()
+	m.tags = make([]Tag, 0, len(ptTags))
+	for _, tag := range ptTags {
+		m.tags = append(m.tags, Tag{Key: string(tag.Key), Value: string(tag.Value)})
+	}
+
+	ptFields := pt.Fields()
+	m.fields = make([]Field, 0, len(ptFields))
+	for k, v := range ptFields {
+		m.fields = append(m.fields, Field{Key: k, Value: v})
+	}
+	return m
 }
 
 // NewMetric returns an untyped metric.
@@ -80,14 +156,7 @@ func NewMetric(
 	fields map[string]interface{},
 	t time.Time,
 ) (Metric, error) {
-	pt, err := models.NewPoint(name, models.NewTags(tags), fields, t)
-	if err != nil {
-		return nil, err
-	}
-	return &metric{
-		pt:    pt,
-		mType: Untyped,
-	}, nil
+	return newMetric(name, tags, fields, t, Untyped)
 }
 
 // NewGaugeMetric returns a gauge metric.
@@ -99,14 +168,7 @@ func NewGaugeMetric(
 	fields map[string]interface{},
 	t time.Time,
 ) (Metric, error) {
-	pt, err := models.NewPoint(name, models.NewTags(tags), fields, t)
-	if err != nil {
-		return nil, err
-	}
-	return &metric{
-		pt:    pt,
-		mType: Gauge,
-	}, nil
+	return newMetric(name, tags, fields, t, Gauge)
 }
 
 // NewCounterMetric returns a Counter metric.
@@ -118,26 +180,118 @@ func NewCounterMetric(
 	fields map[string]interface{},
 	t time.Time,
 ) (Metric, error) {
-	pt, err := models.NewPoint(name, models.NewTags(tags), fields, t)
-	if err != nil {
-		return nil, err
+	return newMetric(name, tags, fields, t, Counter)
+}
+
+func newMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+	mType ValueType,
+) (Metric, error) {
+	m := &metric{
+		name:   name,
+		tags:   make([]Tag, 0, len(tags)),
+		fields: make([]Field, 0, len(fields)),
+		t:      t,
+		mType:  mType,
+	}
+
+	for k, v := range tags {
+		// line protocol has no empty tag keys or values
+		if k == "" || v == "" {
+			continue
+		}
+		m.tags = append(m.tags, Tag{Key: k, Value: v})
+	}
+	sort.Sort(byKey(m.tags))
+
+	for k, v := range fields {
+		if k == "" {
+			return nil, errors.New("all fields must have non-empty names")
+		}
+		v = convertField(v)
+		if v == nil {
+			continue
+		}
+		if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return nil, fmt.Errorf("%v is an unsupported value for field %s", f, k)
+		}
+		m.fields = append(m.fields, Field{Key: k, Value: v})
+	}
+	if len(m.fields) == 0 {
+		return nil, errors.New("Point without fields is unsupported")
+	}
+
+	return m, nil
+}
+
+type byKey []Tag
+
+func (a byKey) Len() int           { return len(a) }
+func (a byKey) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byKey) Less(i, j int) bool { return a[i].Key < a[j].Key }
+
+type byFieldKey []Field
+
+func (a byFieldKey) Len() int           { return len(a) }
+func (a byFieldKey) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byFieldKey) Less(i, j int) bool { return a[i].Key < a[j].Key }
+
+// convertField converts integers to int64, float32 to float64 and []byte to
+// string. nil is returned for nil values, which aren't added.
+func convertField(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		// InfluxDB does not support writing uint64
+		if v <= uint64(math.MaxInt64) {
+			return int64(v)
+		}
+		return int64(math.MaxInt64)
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	default:
+		return v
 	}
-	return &metric{
-		pt:    pt,
-		mType: Counter,
-	}, nil
 }
 
 func (m *metric) Name() string {
-	return m.pt.Name()
+	return m.name
 }
 
 func (m *metric) Tags() map[string]string {
-	return m.pt.Tags().Map()
+	tags := make(map[string]string, len(m.tags))
+	for _, tag := range m.tags {
+		tags[tag.Key] = tag.Value
+	}
+	return tags
+}
+
+func (m *metric) TagList() []Tag {
+	return m.tags
 }
 
 func (m *metric) Time() time.Time {
-	return m.pt.Time()
+	return m.t
 }
 
 func (m *metric) Type() ValueType {
@@ -145,27 +299,98 @@ func (m *metric) Type() ValueType {
 }
 
 func (m *metric) HashID() uint64 {
-	return m.pt.HashID()
+	h := fnv.New64a()
+	h.Write([]byte(m.name))
+	h.Write([]byte("\n"))
+	for _, tag := range m.tags {
+		h.Write([]byte(tag.Key))
+		h.Write([]byte("\n"))
+		h.Write([]byte(tag.Value))
+		h.Write([]byte("\n"))
+	}
+	return h.Sum64()
 }
 
 func (m *metric) UnixNano() int64 {
-	return m.pt.UnixNano()
+	return m.t.UnixNano()
 }
 
 func (m *metric) Fields() map[string]interface{} {
-	return m.pt.Fields()
+	fields := make(map[string]interface{}, len(m.fields))
+	for _, field := range m.fields {
+		fields[field.Key] = field.Value
+	}
+	return fields
+}
+
+func (m *metric) FieldList() []Field {
+	return m.fields
 }
 
 func (m *metric) String() string {
-	return m.pt.String()
+	return m.PrecisionString("n")
 }
 
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	stringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// PrecisionString serializes the metric to line-protocol, with the tags and
+// fields sorted by key.
 func (m *metric) PrecisionString(precison string) string {
-	return m.pt.PrecisionString(precison)
+	var b bytes.Buffer
+	b.WriteString(measurementEscaper.Replace(m.name))
+	for _, tag := range m.tags {
+		b.WriteByte(',')
+		b.WriteString(keyEscaper.Replace(tag.Key))
+		b.WriteByte('=')
+		b.WriteString(keyEscaper.Replace(tag.Value))
+	}
+
+	fields := append([]Field(nil), m.fields...)
+	sort.Sort(byFieldKey(fields))
+	for i, field := range fields {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(keyEscaper.Replace(field.Key))
+		b.WriteByte('=')
+		appendFieldValue(&b, field.Value)
+	}
+
+	if !m.t.IsZero() {
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(
+			m.UnixNano()/models.GetPrecisionMultiplier(precison), 10))
+	}
+	return b.String()
+}
+
+func appendFieldValue(b *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+		b.WriteByte('i')
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case string:
+		b.WriteByte('"')
+		b.WriteString(stringEscaper.Replace(v))
+		b.WriteByte('"')
+	default:
+		fmt.Fprintf(b, "%v", v)
+	}
 }
 
 func (m *metric) Point() *client.Point {
-	return client.NewPointFrom(m.pt)
+	pt, _ := client.NewPoint(m.name, m.Tags(), m.Fields(), m.t)
+	return pt
 }
 
 func (m *metric) IsAggregate() bool {
@@ -175,3 +400,98 @@ func (m *metric) IsAggregate() bool {
 func (m *metric) SetAggregate(b bool) {
 	m.isaggregate = b
 }
+
+func (m *metric) SetName(name string) {
+	m.name = name
+}
+
+func (m *metric) SetTime(t time.Time) {
+	m.t = t
+}
+
+func (m *metric) HasTag(key string) bool {
+	_, ok := m.GetTag(key)
+	return ok
+}
+
+func (m *metric) GetTag(key string) (string, bool) {
+	if i, ok := m.tagIndex(key); ok {
+		return m.tags[i].Value, true
+	}
+	return "", false
+}
+
+func (m *metric) AddTag(key, value string) {
+	if key == "" || value == "" {
+		return
+	}
+
+	i, ok := m.tagIndex(key)
+	if ok {
+		m.tags[i].Value = value
+		return
+	}
+
+	m.tags = append(m.tags, Tag{})
+	copy(m.tags[i+1:], m.tags[i:])
+	m.tags[i] = Tag{Key: key, Value: value}
+}
+
+func (m *metric) RemoveTag(key string) {
+	if i, ok := m.tagIndex(key); ok {
+		m.tags = append(m.tags[:i], m.tags[i+1:]...)
+	}
+}
+
+// tagIndex returns the index of the tag, or the index to insert it at when
+// the metric doesn't have it.
+func (m *metric) tagIndex(key string) (int, bool) {
+	i := sort.Search(len(m.tags), func(i int) bool {
+		return m.tags[i].Key >= key
+	})
+	return i, i < len(m.tags) && m.tags[i].Key == key
+}
+
+func (m *metric) HasField(key string) bool {
+	_, ok := m.GetField(key)
+	return ok
+}
+
+func (m *metric) GetField(key string) (interface{}, bool) {
+	for _, field := range m.fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (m *metric) AddField(key string, value interface{}) {
+	value = convertField(value)
+	if value == nil {
+		return
+	}
+	for i := range m.fields {
+		if m.fields[i].Key == key {
+			m.fields[i].Value = value
+			return
+		}
+	}
+	m.fields = append(m.fields, Field{Key: key, Value: value})
+}
+
+func (m *metric) RemoveField(key string) {
+	for i := range m.fields {
+		if m.fields[i].Key == key {
+			m.fields = append(m.fields[:i], m.fields[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *metric) Copy() Metric {
+	out := *m
+	out.tags = append([]Tag(nil), m.tags...)
+	out.fields = append([]Field(nil), m.fields...)
+	return &out
+}
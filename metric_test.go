@@ -31,6 +31,20 @@ func TestNewMetric(t *testing.T) {
 	assert.Equal(t, now.UnixNano(), m.UnixNano())
 }
 
+func TestNewMetricEmptyTags(t *testing.T) {
+	m, err := NewMetric("cpu",
+		map[string]string{"host": "localhost", "unit": "", "": "value"},
+		map[string]interface{}{"value": float64(1)},
+		time.Unix(0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+
+	m.AddTag("unit", "")
+	m.AddTag("", "value")
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+	assert.Equal(t, "cpu,host=localhost value=1 0", m.String())
+}
+
 func TestNewGaugeMetric(t *testing.T) {
 	now := time.Now()
 
@@ -109,3 +123,141 @@ func TestNewMetricFailNaN(t *testing.T) {
 	_, err := NewMetric("cpu", tags, fields, now)
 	assert.Error(t, err)
 }
+
+func TestMetricTags(t *testing.T) {
+	m, err := NewMetric("cpu",
+		map[string]string{"host": "localhost", "datacenter": "us-east-1"},
+		map[string]interface{}{"usage_idle": float64(99)},
+		time.Now(),
+	)
+	assert.NoError(t, err)
+
+	assert.True(t, m.HasTag("host"))
+	assert.False(t, m.HasTag("cpu"))
+
+	m.AddTag("cpu", "cpu0")
+	m.AddTag("host", "remotehost")
+	value, ok := m.GetTag("host")
+	assert.True(t, ok)
+	assert.Equal(t, "remotehost", value)
+	assert.Equal(t, []Tag{
+		{Key: "cpu", Value: "cpu0"},
+		{Key: "datacenter", Value: "us-east-1"},
+		{Key: "host", Value: "remotehost"},
+	}, m.TagList())
+
+	m.RemoveTag("datacenter")
+	m.RemoveTag("nothing")
+	assert.Equal(t, map[string]string{"cpu": "cpu0", "host": "remotehost"}, m.Tags())
+}
+
+func TestMetricFields(t *testing.T) {
+	m, err := NewMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage_idle": float64(99), "nothing": nil},
+		time.Now(),
+	)
+	assert.NoError(t, err)
+	assert.False(t, m.HasField("nothing"))
+
+	m.AddField("usage_busy", float32(1))
+	m.AddField("count", 5)
+	m.AddField("usage_idle", float64(98))
+	m.AddField("nothing", nil)
+	value, ok := m.GetField("usage_idle")
+	assert.True(t, ok)
+	assert.Equal(t, float64(98), value)
+	assert.Equal(t, map[string]interface{}{
+		"usage_idle": float64(98),
+		"usage_busy": float64(1),
+		"count":      int64(5),
+	}, m.Fields())
+
+	m.RemoveField("count")
+	assert.False(t, m.HasField("count"))
+	assert.Len(t, m.FieldList(), 2)
+}
+
+func TestMetricSetNameAndTime(t *testing.T) {
+	now := time.Now()
+	m, err := NewMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage_idle": float64(99)},
+		now,
+	)
+	assert.NoError(t, err)
+
+	later := now.Add(time.Minute)
+	m.SetName("cpu2")
+	m.SetTime(later)
+	assert.Equal(t, "cpu2", m.Name())
+	assert.Equal(t, later, m.Time())
+	assert.Equal(t, later.UnixNano(), m.UnixNano())
+}
+
+func TestMetricCopy(t *testing.T) {
+	m, err := NewCounterMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage_idle": float64(99)},
+		time.Now(),
+	)
+	assert.NoError(t, err)
+	m.SetAggregate(true)
+
+	c := m.Copy()
+	c.AddTag("cpu", "cpu0")
+	c.RemoveTag("host")
+	c.AddField("usage_idle", float64(1))
+
+	assert.Equal(t, map[string]string{"host": "localhost"}, m.Tags())
+	assert.Equal(t, map[string]interface{}{"usage_idle": float64(99)}, m.Fields())
+	assert.Equal(t, map[string]string{"cpu": "cpu0"}, c.Tags())
+	assert.Equal(t, Counter, c.Type())
+	assert.True(t, c.IsAggregate())
+}
+
+func TestMetricHashID(t *testing.T) {
+	now := time.Now()
+	m1, _ := NewMetric("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": float64(99)},
+		now,
+	)
+	m2, _ := NewMetric("cpu",
+		map[string]string{"cpu": "cpu0", "host": "localhost"},
+		map[string]interface{}{"usage_busy": float64(1)},
+		now.Add(time.Second),
+	)
+	assert.Equal(t, m1.HashID(), m2.HashID())
+
+	m2.AddTag("host", "remotehost")
+	assert.NotEqual(t, m1.HashID(), m2.HashID())
+}
+
+func TestMetricStringEscaping(t *testing.T) {
+	now := time.Now()
+	m, err := NewMetric("cpu load",
+		map[string]string{"host name": "local,host", "a=b": "c"},
+		map[string]interface{}{
+			"value":  int64(1),
+			"string": `say "hi" \o/`,
+			"ok":     true,
+			"float":  float64(1.5),
+		},
+		now,
+	)
+	assert.NoError(t, err)
+
+	lineProto := fmt.Sprintf(`cpu\ load,a\=b=c,host\ name=local\,host `+
+		`float=1.5,ok=true,string="say \"hi\" \\o/",value=1i %d`, now.UnixNano())
+	assert.Equal(t, lineProto, m.String())
+}
+
+func TestNewMetricNoFields(t *testing.T) {
+	_, err := NewMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{},
+		time.Now(),
+	)
+	assert.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package telegraf
+
+// Initializer is an interface that inputs, outputs, processors, aggregators
+// and parsers may implement to be initialized once they are configured.
+type Initializer interface {
+	// Init performs the one time setup of the plugin, and returns an error
+	// if its configuration is invalid. It is called after the configuration
+	// of the plugin has been loaded, before it is started.
+	Init() error
+}
+
+// Logger is the logger of a plugin, which is set on the plugin's exported
+// Log field, of type Logger, when the plugin is loaded. Messages are tagged
+// with the name of the plugin, and their level can be set for each plugin.
+type Logger interface {
+	// Errorf logs an error message, formatted as by fmt.Printf.
+	Errorf(format string, args ...interface{})
+	// Error logs an error message, formatted as by fmt.Print.
+	Error(args ...interface{})
+	// Warnf logs a warning message, formatted as by fmt.Printf.
+	Warnf(format string, args ...interface{})
+	// Warn logs a warning message, formatted as by fmt.Print.
+	Warn(args ...interface{})
+	// Infof logs an information message, formatted as by fmt.Printf.
+	Infof(format string, args ...interface{})
+	// Info logs an information message, formatted as by fmt.Print.
+	Info(args ...interface{})
+	// Debugf logs a debug message, formatted as by fmt.Printf.
+	Debugf(format string, args ...interface{})
+	// Debug logs a debug message, formatted as by fmt.Print.
+	Debug(args ...interface{})
+}
@@ -0,0 +1,338 @@
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// maxLineSize is the maximum size of the lines of the protocol and of the
+// stderr of the plugin.
+const maxLineSize = 16 * 1024 * 1024
+
+// Process is the process of an external plugin, which is restarted when it
+// exits or stops responding to health checks.
+type Process struct {
+	Command     []string
+	Environment []string
+	Kind        string
+
+	// Timeout is how long requests may take, including health checks,
+	// which is 5s by default.
+	Timeout time.Duration
+	// RestartDelay is how long to wait before restarting the plugin.
+	RestartDelay time.Duration
+	// HealthCheckInterval is how often the plugin is pinged. The plugin is
+	// not health checked when it is zero.
+	HealthCheckInterval time.Duration
+
+	Log telegraf.Logger
+
+	// OnMetrics is called with the metrics the plugin sends outside of the
+	// results of requests, such as the metrics of service inputs.
+	OnMetrics func(metrics []telegraf.Metric)
+
+	// requestMu serializes the requests.
+	requestMu sync.Mutex
+
+	sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	results chan Response
+	exited  chan struct{}
+	lastID  uint64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Start starts the plugin, and restarts it whenever it exits until Stop is
+// called.
+func (p *Process) Start() error {
+	if len(p.Command) == 0 {
+		return errors.New("no command")
+	}
+	if p.Timeout == 0 {
+		p.Timeout = 5 * time.Second
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.supervise()
+	if p.HealthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.healthCheck()
+	}
+	return nil
+}
+
+// Stop stops the plugin, closing its stdin and killing it unless it exits
+// within the timeout.
+func (p *Process) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	p.wg.Wait()
+	p.stop = nil
+
+	p.Lock()
+	cmd, stdin, exited := p.cmd, p.stdin, p.exited
+	p.Unlock()
+	stdin.Close()
+	select {
+	case <-exited:
+	case <-time.After(p.Timeout):
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// Request sends the request to the plugin, returning its result. The error
+// of the result, if any, is returned as an error.
+func (p *Process) Request(req Request) (Response, error) {
+	p.requestMu.Lock()
+	defer p.requestMu.Unlock()
+
+	p.Lock()
+	stdin, results, exited := p.stdin, p.results, p.exited
+	p.Unlock()
+	if stdin == nil {
+		return Response{}, errors.New("plugin not started")
+	}
+	return p.request(req, stdin, results, exited)
+}
+
+// request sends the request to the process with the given stdin, results
+// and exited channels, returning its result.
+func (p *Process) request(
+	req Request,
+	stdin io.Writer,
+	results <-chan Response,
+	exited <-chan struct{},
+) (Response, error) {
+	p.Lock()
+	p.lastID++
+	req.ID = p.lastID
+	p.Unlock()
+
+	b, err := Encode(req)
+	if err != nil {
+		return Response{}, err
+	}
+	if _, err := stdin.Write(b); err != nil {
+		return Response{}, fmt.Errorf("writing %s request: %s", req.Type, err)
+	}
+
+	timeout := time.NewTimer(p.Timeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case resp := <-results:
+			// the results of requests which timed out are discarded
+			if resp.ID != req.ID {
+				continue
+			}
+			if resp.Error != "" {
+				return resp, errors.New(resp.Error)
+			}
+			return resp, nil
+		case <-exited:
+			return Response{}, fmt.Errorf("plugin exited during %s request",
+				req.Type)
+		case <-timeout.C:
+			return Response{}, fmt.Errorf("%s request timed out after %s",
+				req.Type, p.Timeout)
+		}
+	}
+}
+
+// start starts the process of the plugin, and checks it supports the
+// protocol and the kind of plugin.
+func (p *Process) start() error {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Env = append(os.Environ(), p.Environment...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %s", p.Command[0], err)
+	}
+
+	results := make(chan Response, 16)
+	exited := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		p.readResponses(stdout, results)
+	}()
+	go func() {
+		defer readers.Done()
+		p.readStderr(stderr)
+	}()
+	go func() {
+		// the pipes must be read entirely before waiting for the process
+		readers.Wait()
+		cmd.Wait()
+		close(exited)
+	}()
+
+	// the process is only used for requests once it answered the hello
+	_, err = p.request(Request{
+		Type:    RequestHello,
+		Version: ProtocolVersion,
+		Kind:    p.Kind,
+	}, stdin, results, exited)
+	if err != nil {
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("starting %s: %s", p.Command[0], err)
+	}
+
+	p.Lock()
+	p.cmd, p.stdin, p.results, p.exited = cmd, stdin, results, exited
+	p.Unlock()
+	return nil
+}
+
+// supervise restarts the plugin once it exits, until it is stopped.
+func (p *Process) supervise() {
+	defer p.wg.Done()
+	for {
+		p.Lock()
+		exited := p.exited
+		p.Unlock()
+		select {
+		case <-p.stop:
+			return
+		case <-exited:
+		}
+
+		p.Log.Errorf("Plugin %s exited, restarting it in %s",
+			p.Command[0], p.RestartDelay)
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(p.RestartDelay):
+			}
+			if err := p.start(); err != nil {
+				p.Log.Errorf("Error restarting plugin: %s", err)
+				continue
+			}
+			break
+		}
+	}
+}
+
+// healthCheck pings the plugin, killing it if it does not respond, for it
+// to be restarted.
+func (p *Process) healthCheck() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// the plugin is being restarted
+		p.Lock()
+		exited := p.exited
+		p.Unlock()
+		select {
+		case <-exited:
+			continue
+		default:
+		}
+
+		if _, err := p.Request(Request{Type: RequestPing}); err != nil {
+			p.Log.Errorf("Plugin %s failed health check, killing it: %s",
+				p.Command[0], err)
+			p.Lock()
+			p.cmd.Process.Kill()
+			p.Unlock()
+		}
+	}
+}
+
+func (p *Process) readResponses(r io.Reader, results chan<- Response) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			p.Log.Errorf("Invalid response from plugin: %s", err)
+			continue
+		}
+
+		switch resp.Type {
+		case ResponseResult:
+			select {
+			case results <- resp:
+			default:
+				p.Log.Errorf("Discarding result of request %d", resp.ID)
+			}
+		case ResponseMetrics:
+			metrics, err := DecodeMetrics(resp.Metrics)
+			if err != nil {
+				p.Log.Errorf("Error in metrics from plugin: %s", err)
+				continue
+			}
+			if p.OnMetrics != nil {
+				p.OnMetrics(metrics)
+			}
+		case ResponseLog:
+			p.logMessage(resp.Level, resp.Message)
+		default:
+			p.Log.Errorf("Unknown response type %q from plugin", resp.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		p.Log.Errorf("Error reading from plugin: %s", err)
+	}
+}
+
+func (p *Process) readStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		p.Log.Errorf("%s", scanner.Text())
+	}
+}
+
+func (p *Process) logMessage(level, msg string) {
+	switch level {
+	case "error":
+		p.Log.Errorf("%s", msg)
+	case "warn":
+		p.Log.Warnf("%s", msg)
+	case "debug":
+		p.Log.Debugf("%s", msg)
+	default:
+		p.Log.Infof("%s", msg)
+	}
+}
@@ -0,0 +1,103 @@
+package external_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/external/sdk"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary runs as the external plugin when this environment
+// variable is set, to the directory of the plugin's marker file.
+const pluginEnv = "TELEGRAF_TEST_EXTERNAL_PLUGIN"
+
+// testInput exits on its first gather, once it created its marker file, for
+// the plugin to be restarted, and gathers a metric afterwards.
+type testInput struct {
+	dir string
+}
+
+func (i *testInput) SampleConfig() string { return "" }
+func (i *testInput) Description() string  { return "" }
+func (i *testInput) Gather(acc telegraf.Accumulator) error {
+	marker := filepath.Join(i.dir, "started")
+	if _, err := os.Stat(marker); os.IsNotExist(err) {
+		ioutil.WriteFile(marker, nil, 0600)
+		os.Exit(1)
+	}
+	acc.AddFields("test", map[string]interface{}{"value": 42}, nil,
+		time.Unix(0, 1))
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(pluginEnv); dir != "" {
+		if err := sdk.Serve(&testInput{dir: dir}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newProcess(t *testing.T, kind string) (*external.Process, func()) {
+	dir, err := ioutil.TempDir("", "external")
+	require.NoError(t, err)
+	return &external.Process{
+		Command:      []string{os.Args[0]},
+		Environment:  []string{pluginEnv + "=" + dir},
+		Kind:         kind,
+		Timeout:      5 * time.Second,
+		RestartDelay: 10 * time.Millisecond,
+		Log:          testutil.Logger{Name: "external"},
+	}, func() { os.RemoveAll(dir) }
+}
+
+func TestProcessRestart(t *testing.T) {
+	p, cleanup := newProcess(t, external.KindInput)
+	defer cleanup()
+	require.NoError(t, p.Start())
+	defer p.Stop()
+
+	_, err := p.Request(external.Request{Type: external.RequestGather})
+	require.Error(t, err)
+
+	// the plugin is restarted once it exited
+	var resp external.Response
+	for i := 0; i < 100; i++ {
+		resp, err = p.Request(external.Request{Type: external.RequestGather})
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test value=42i 1"}, resp.Metrics)
+}
+
+func TestProcessStartKind(t *testing.T) {
+	p, cleanup := newProcess(t, external.KindProcessor)
+	defer cleanup()
+	err := p.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `the plugin does not support the "processor" kind`)
+}
+
+func TestProcessStartCommand(t *testing.T) {
+	p := &external.Process{
+		Command: []string{"/nonexistent/plugin"},
+		Kind:    external.KindInput,
+		Log:     testutil.Logger{Name: "external"},
+	}
+	assert.Error(t, p.Start())
+}
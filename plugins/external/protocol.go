@@ -0,0 +1,132 @@
+// Package external implements the protocol between telegraf and external
+// plugins, which run as separate processes, and the process management of
+// the plugins on the telegraf side.
+//
+// The protocol is line based: telegraf writes requests to the stdin of the
+// plugin, as JSON objects, one per line, and the plugin writes responses to
+// its stdout the same way. Metrics are carried in InfluxDB line protocol.
+// The stderr of the plugin is logged by telegraf.
+//
+// Telegraf first sends a "hello" request with the protocol version and the
+// kind of plugin it expects, then requests depending on the kind: "gather"
+// for inputs, "write" for outputs and "apply" for processors, along with
+// "ping" requests to check the plugin is healthy. Each request gets a
+// "result" response with the same ID. Plugins may also send "metrics"
+// responses without an ID at any time, such as service inputs, and "log"
+// responses, which telegraf logs with the logger of the plugin.
+package external
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// ProtocolVersion is the version of the protocol, sent in the hello request.
+// Plugins refuse versions they do not support.
+const ProtocolVersion = 1
+
+// The kinds of external plugins.
+const (
+	KindInput     = "input"
+	KindOutput    = "output"
+	KindProcessor = "processor"
+)
+
+// The types of requests.
+const (
+	RequestHello  = "hello"
+	RequestPing   = "ping"
+	RequestGather = "gather"
+	RequestWrite  = "write"
+	RequestApply  = "apply"
+)
+
+// The types of responses.
+const (
+	ResponseResult  = "result"
+	ResponseMetrics = "metrics"
+	ResponseLog     = "log"
+)
+
+// Request is a request from telegraf to an external plugin.
+type Request struct {
+	ID   uint64 `json:"id"`
+	Type string `json:"type"`
+
+	// Version and Kind are set on hello requests.
+	Version int    `json:"version,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+
+	// Metrics are the metrics of write and apply requests.
+	Metrics []string `json:"metrics,omitempty"`
+}
+
+// Response is a response of an external plugin to telegraf.
+type Response struct {
+	// ID is the ID of the request of a result.
+	ID   uint64 `json:"id,omitempty"`
+	Type string `json:"type"`
+
+	// Metrics are the metrics of results of gather and apply requests, and
+	// of metrics responses.
+	Metrics []string `json:"metrics,omitempty"`
+	// Error is the error of a result.
+	Error string `json:"error,omitempty"`
+
+	// Level and Message are the level, "error", "warn", "info" or "debug",
+	// and the message of log responses.
+	Level   string `json:"level,omitempty"`
+	Message string `json:"msg,omitempty"`
+}
+
+// Encode returns the message as a line of JSON.
+func Encode(msg interface{}) ([]byte, error) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// EncodeMetrics returns the metrics in line protocol, one line each.
+func EncodeMetrics(metrics []telegraf.Metric) ([]string, error) {
+	serializer, err := serializers.NewInfluxSerializer()
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, m := range metrics {
+		mLines, err := serializer.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, mLines...)
+	}
+	return lines, nil
+}
+
+// DecodeMetrics parses the metrics from lines of line protocol.
+func DecodeMetrics(lines []string) ([]telegraf.Metric, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	parser, err := parsers.NewInfluxParser()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	metrics, err := parser.Parse(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics: %s", err)
+	}
+	return metrics, nil
+}
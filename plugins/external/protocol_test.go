@@ -0,0 +1,47 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	b, err := Encode(Request{ID: 1, Type: RequestHello, Version: 1,
+		Kind: KindInput})
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"id":1,"type":"hello","version":1,"kind":"input"}`+"\n", string(b))
+}
+
+func TestEncodeDecodeMetrics(t *testing.T) {
+	m, err := telegraf.NewMetric("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.5, "count": int64(2)},
+		time.Unix(0, 10))
+	require.NoError(t, err)
+
+	lines, err := EncodeMetrics([]telegraf.Metric{m})
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+
+	metrics, err := DecodeMetrics(lines)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "cpu", metrics[0].Name())
+	assert.Equal(t, m.Tags(), metrics[0].Tags())
+	assert.Equal(t, m.Fields(), metrics[0].Fields())
+	assert.Equal(t, m.Time().UnixNano(), metrics[0].Time().UnixNano())
+}
+
+func TestDecodeMetricsInvalid(t *testing.T) {
+	_, err := DecodeMetrics([]string{"cpu"})
+	assert.Error(t, err)
+
+	metrics, err := DecodeMetrics(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, metrics)
+}
@@ -0,0 +1,144 @@
+package sdk
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// accumulator is the accumulator of inputs. It collects the metrics of
+// Gather, to be sent in its result, or sends the metrics of service inputs
+// to telegraf at once.
+type accumulator struct {
+	// s is the server of a service input.
+	s *server
+
+	sync.Mutex
+	metrics []telegraf.Metric
+	errs    errorList
+}
+
+func (a *accumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addMetric(telegraf.NewMetric(measurement, tags, fields, metricTime(t)))
+}
+
+func (a *accumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addMetric(telegraf.NewGaugeMetric(measurement, tags, fields, metricTime(t)))
+}
+
+func (a *accumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addMetric(telegraf.NewCounterMetric(measurement, tags, fields, metricTime(t)))
+}
+
+// SetPrecision does nothing, as telegraf sets the precision of the metrics
+// of the external input.
+func (a *accumulator) SetPrecision(precision, interval time.Duration) {}
+
+func (a *accumulator) AddError(err error) {
+	if err == nil {
+		return
+	}
+	if a.s != nil {
+		(&logger{s: a.s}).Errorf("%s", err)
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.errs = append(a.errs, err.Error())
+}
+
+// WithTracking returns a tracking accumulator, for which metrics are
+// delivered once they are sent to telegraf.
+func (a *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+func (a *accumulator) addMetric(m telegraf.Metric, err error) {
+	if err != nil {
+		a.AddError(err)
+		return
+	}
+	a.addMetrics([]telegraf.Metric{m})
+}
+
+// addMetrics collects the metrics, or sends them for service inputs,
+// returning whether they were sent.
+func (a *accumulator) addMetrics(metrics []telegraf.Metric) bool {
+	if a.s != nil {
+		if err := a.s.sendMetrics(metrics); err != nil {
+			a.AddError(err)
+			return false
+		}
+		return true
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.metrics = append(a.metrics, metrics...)
+	return true
+}
+
+func (a *accumulator) err() error {
+	a.Lock()
+	defer a.Unlock()
+	if len(a.errs) == 0 {
+		return nil
+	}
+	return a.errs
+}
+
+func metricTime(t []time.Time) time.Time {
+	if len(t) > 0 {
+		return t[0]
+	}
+	return time.Now()
+}
+
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+	lastID    uint64
+}
+
+func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	id := telegraf.TrackingID(atomic.AddUint64(&a.lastID, 1))
+	delivered := a.addMetrics(group)
+	a.delivered <- &deliveryInfo{id: id, delivered: delivered}
+	return id
+}
+
+func (a *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d *deliveryInfo) ID() telegraf.TrackingID {
+	return d.id
+}
+
+func (d *deliveryInfo) Delivered() bool {
+	return d.delivered
+}
@@ -0,0 +1,266 @@
+// Package sdk runs telegraf plugins as external plugins, in their own
+// process, serving the protocol of the external package over stdin and
+// stdout. An external input is a program like:
+//
+//	func main() {
+//		if err := sdk.Serve(&MyInput{}); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+//
+// and is configured in telegraf as:
+//
+//	[[inputs.external]]
+//	  command = ["/usr/local/bin/my-input"]
+//
+// The plugin is configured by the program itself, such as with flags. Its
+// Log field, of type telegraf.Logger, is set as for plugins run by telegraf,
+// the messages being logged by telegraf.
+package sdk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/external"
+)
+
+// maxLineSize is the maximum size of the lines of the protocol.
+const maxLineSize = 16 * 1024 * 1024
+
+// Serve serves the plugin, a telegraf.Input, telegraf.Output or
+// telegraf.Processor, over stdin and stdout until stdin is closed.
+func Serve(plugin interface{}) error {
+	return ServeIO(plugin, os.Stdin, os.Stdout)
+}
+
+// ServeIO serves the plugin, reading requests from r and writing responses
+// to w, until r is closed.
+func ServeIO(plugin interface{}, r io.Reader, w io.Writer) error {
+	s := &server{plugin: plugin, w: w}
+	models.SetLoggerOnPlugin(plugin, &logger{s: s})
+	defer s.stop()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var req external.Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return fmt.Errorf("invalid request: %s", err)
+		}
+
+		resp := external.Response{ID: req.ID, Type: external.ResponseResult}
+		metrics, err := s.handle(req)
+		if err == nil {
+			resp.Metrics, err = external.EncodeMetrics(metrics)
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		if err := s.send(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type server struct {
+	plugin interface{}
+	kind   string
+
+	// started is the service input started, or the output connected.
+	started bool
+
+	sync.Mutex
+	w io.Writer
+}
+
+// handle handles the request, returning the metrics of its result.
+func (s *server) handle(req external.Request) ([]telegraf.Metric, error) {
+	if req.Type != external.RequestHello && s.kind == "" {
+		return nil, fmt.Errorf("%s request before hello", req.Type)
+	}
+
+	switch req.Type {
+	case external.RequestHello:
+		return nil, s.hello(req)
+	case external.RequestPing:
+		return nil, nil
+	case external.RequestGather:
+		if input, ok := s.plugin.(telegraf.Input); ok && s.kind == external.KindInput {
+			acc := &accumulator{}
+			if err := input.Gather(acc); err != nil {
+				acc.AddError(err)
+			}
+			return acc.metrics, acc.err()
+		}
+	case external.RequestWrite:
+		if output, ok := s.plugin.(telegraf.Output); ok && s.kind == external.KindOutput {
+			metrics, err := external.DecodeMetrics(req.Metrics)
+			if err != nil {
+				return nil, err
+			}
+			return nil, output.Write(metrics)
+		}
+	case external.RequestApply:
+		if processor, ok := s.plugin.(telegraf.Processor); ok && s.kind == external.KindProcessor {
+			metrics, err := external.DecodeMetrics(req.Metrics)
+			if err != nil {
+				return nil, err
+			}
+			return processor.Apply(metrics...), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported %s request for %s plugin",
+		req.Type, s.kind)
+}
+
+// hello checks the version of the protocol and the kind of plugin, starting
+// service inputs and connecting outputs.
+func (s *server) hello(req external.Request) error {
+	if req.Version != external.ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d, the plugin "+
+			"supports version %d", req.Version, external.ProtocolVersion)
+	}
+
+	var ok bool
+	switch req.Kind {
+	case external.KindInput:
+		_, ok = s.plugin.(telegraf.Input)
+	case external.KindOutput:
+		_, ok = s.plugin.(telegraf.Output)
+	case external.KindProcessor:
+		_, ok = s.plugin.(telegraf.Processor)
+	}
+	if !ok {
+		return fmt.Errorf("the plugin does not support the %q kind", req.Kind)
+	}
+	if s.kind != "" {
+		return nil
+	}
+	s.kind = req.Kind
+
+	if init, ok := s.plugin.(telegraf.Initializer); ok {
+		if err := init.Init(); err != nil {
+			return err
+		}
+	}
+
+	switch s.kind {
+	case external.KindInput:
+		if input, ok := s.plugin.(telegraf.ServiceInput); ok {
+			if err := input.Start(&accumulator{s: s}); err != nil {
+				return err
+			}
+			s.started = true
+		}
+	case external.KindOutput:
+		if err := s.plugin.(telegraf.Output).Connect(); err != nil {
+			return err
+		}
+		s.started = true
+	}
+	return nil
+}
+
+// stop stops the service input, or closes the output, once stdin is closed.
+func (s *server) stop() {
+	if !s.started {
+		return
+	}
+	switch s.kind {
+	case external.KindInput:
+		s.plugin.(telegraf.ServiceInput).Stop()
+	case external.KindOutput:
+		s.plugin.(telegraf.Output).Close()
+	}
+}
+
+// send writes the response, responses being sent from the goroutines of
+// service inputs as well.
+func (s *server) send(resp external.Response) error {
+	b, err := external.Encode(resp)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	_, err = s.w.Write(b)
+	return err
+}
+
+// sendMetrics sends the metrics added by a service input.
+func (s *server) sendMetrics(metrics []telegraf.Metric) error {
+	lines, err := external.EncodeMetrics(metrics)
+	if err != nil {
+		return err
+	}
+	return s.send(external.Response{
+		Type:    external.ResponseMetrics,
+		Metrics: lines,
+	})
+}
+
+// logger is the telegraf.Logger of the plugin, sending its messages to
+// telegraf.
+type logger struct {
+	s *server
+}
+
+func (l *logger) log(level, msg string) {
+	err := l.s.send(external.Response{
+		Type:    external.ResponseLog,
+		Level:   level,
+		Message: msg,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", level, msg)
+	}
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log("error", fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Error(args ...interface{}) {
+	l.log("error", fmt.Sprint(args...))
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log("warn", fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Warn(args ...interface{}) {
+	l.log("warn", fmt.Sprint(args...))
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log("info", fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Info(args ...interface{}) {
+	l.log("info", fmt.Sprint(args...))
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log("debug", fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Debug(args ...interface{}) {
+	l.log("debug", fmt.Sprint(args...))
+}
+
+// errorList joins the errors added to an accumulator.
+type errorList []string
+
+func (e errorList) Error() string {
+	return strings.Join(e, "; ")
+}
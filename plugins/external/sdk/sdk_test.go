@@ -0,0 +1,181 @@
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testInput struct {
+	Log telegraf.Logger
+
+	initialized bool
+}
+
+func (i *testInput) SampleConfig() string { return "" }
+func (i *testInput) Description() string  { return "" }
+func (i *testInput) Init() error {
+	i.initialized = true
+	return nil
+}
+
+func (i *testInput) Gather(acc telegraf.Accumulator) error {
+	i.Log.Infof("gathering")
+	acc.AddFields("test", map[string]interface{}{"value": int64(42)},
+		map[string]string{"host": "a"}, time.Unix(0, 1))
+	return nil
+}
+
+type testOutput struct {
+	metrics   []telegraf.Metric
+	connected bool
+	closed    bool
+}
+
+func (o *testOutput) SampleConfig() string { return "" }
+func (o *testOutput) Description() string  { return "" }
+func (o *testOutput) Connect() error {
+	o.connected = true
+	return nil
+}
+func (o *testOutput) Close() error {
+	o.closed = true
+	return nil
+}
+func (o *testOutput) Write(metrics []telegraf.Metric) error {
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+type testProcessor struct{}
+
+func (p *testProcessor) SampleConfig() string { return "" }
+func (p *testProcessor) Description() string  { return "" }
+func (p *testProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.AddTag("processed", "true")
+	}
+	return in
+}
+
+// serve serves the plugin the requests, returning its responses.
+func serve(t *testing.T, plugin interface{}, reqs ...external.Request) []external.Response {
+	var in bytes.Buffer
+	for _, req := range reqs {
+		b, err := external.Encode(req)
+		require.NoError(t, err)
+		in.Write(b)
+	}
+	var out bytes.Buffer
+	require.NoError(t, ServeIO(plugin, &in, &out))
+
+	var resps []external.Response
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var resp external.Response
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &resp))
+		resps = append(resps, resp)
+	}
+	return resps
+}
+
+func hello(kind string) external.Request {
+	return external.Request{
+		ID:      1,
+		Type:    external.RequestHello,
+		Version: external.ProtocolVersion,
+		Kind:    kind,
+	}
+}
+
+func TestServeInput(t *testing.T) {
+	input := &testInput{}
+	resps := serve(t, input,
+		hello(external.KindInput),
+		external.Request{ID: 2, Type: external.RequestPing},
+		external.Request{ID: 3, Type: external.RequestGather})
+
+	assert.True(t, input.initialized)
+	assert.Equal(t, []external.Response{
+		{ID: 1, Type: external.ResponseResult},
+		{ID: 2, Type: external.ResponseResult},
+		{Type: external.ResponseLog, Level: "info", Message: "gathering"},
+		{
+			ID:      3,
+			Type:    external.ResponseResult,
+			Metrics: []string{"test,host=a value=42i 1"},
+		},
+	}, resps)
+}
+
+func TestServeOutput(t *testing.T) {
+	output := &testOutput{}
+	resps := serve(t, output,
+		hello(external.KindOutput),
+		external.Request{
+			ID:      2,
+			Type:    external.RequestWrite,
+			Metrics: []string{"test,host=a value=42i 1", "test,host=b value=43i 2"},
+		})
+
+	assert.Equal(t, []external.Response{
+		{ID: 1, Type: external.ResponseResult},
+		{ID: 2, Type: external.ResponseResult},
+	}, resps)
+	assert.True(t, output.connected)
+	assert.True(t, output.closed)
+	require.Len(t, output.metrics, 2)
+	assert.Equal(t, "b", output.metrics[1].Tags()["host"])
+	assert.Equal(t, int64(2), output.metrics[1].Time().UnixNano())
+}
+
+func TestServeProcessor(t *testing.T) {
+	resps := serve(t, &testProcessor{},
+		hello(external.KindProcessor),
+		external.Request{
+			ID:      2,
+			Type:    external.RequestApply,
+			Metrics: []string{"test value=42i 1"},
+		})
+
+	assert.Equal(t, []external.Response{
+		{ID: 1, Type: external.ResponseResult},
+		{
+			ID:      2,
+			Type:    external.ResponseResult,
+			Metrics: []string{"test,processed=true value=42i 1"},
+		},
+	}, resps)
+}
+
+func TestServeErrors(t *testing.T) {
+	resps := serve(t, &testInput{},
+		external.Request{ID: 1, Type: external.RequestGather},
+		external.Request{ID: 2, Type: external.RequestHello, Version: 2,
+			Kind: external.KindInput},
+		hello(external.KindOutput),
+		hello(external.KindInput),
+		external.Request{ID: 5, Type: external.RequestApply})
+
+	require.Len(t, resps, 5)
+	assert.Equal(t, "gather request before hello", resps[0].Error)
+	assert.Equal(t, "unsupported protocol version 2, the plugin supports "+
+		"version 1", resps[1].Error)
+	assert.Equal(t, `the plugin does not support the "output" kind`, resps[2].Error)
+	assert.Empty(t, resps[3].Error)
+	assert.Equal(t, "unsupported apply request for input plugin", resps[4].Error)
+}
+
+func TestServeInvalidRequest(t *testing.T) {
+	var out bytes.Buffer
+	err := ServeIO(&testInput{}, strings.NewReader("not json\n"), &out)
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package external
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Command of the external input, and its arguments. The input is a
+  ## program serving the external plugin protocol, such as one built with
+  ## the Go SDK in plugins/external/sdk.
+  command = ["/usr/local/bin/my-input", "--config", "/etc/my-input.conf"]
+
+  ## Environment variables set for the command, in addition to the ones of
+  ## telegraf, as "NAME=value".
+  # environment = []
+
+  ## Timeout for each request to the plugin to complete.
+  # timeout = "5s"
+
+  ## Delay before restarting the plugin once it exits.
+  # restart_delay = "10s"
+
+  ## Interval at which the plugin is health checked, the plugin being
+  ## restarted if it does not respond. Set to "0s" to disable health checks.
+  # health_check_interval = "30s"
+`
+
+// External is an input running as a separate process.
+type External struct {
+	Command             []string
+	Environment         []string
+	Timeout             internal.Duration
+	RestartDelay        internal.Duration
+	HealthCheckInterval internal.Duration
+
+	Log telegraf.Logger `toml:"-"`
+
+	process *external.Process
+
+	sync.Mutex
+	acc telegraf.Accumulator
+}
+
+func NewExternal() *External {
+	return &External{
+		Timeout:             internal.Duration{Duration: 5 * time.Second},
+		RestartDelay:        internal.Duration{Duration: 10 * time.Second},
+		HealthCheckInterval: internal.Duration{Duration: 30 * time.Second},
+	}
+}
+
+func (e *External) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *External) Description() string {
+	return "Run an external input plugin as a separate process"
+}
+
+func (e *External) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("command is required")
+	}
+	return nil
+}
+
+func (e *External) Start(acc telegraf.Accumulator) error {
+	e.Lock()
+	e.acc = acc
+	e.Unlock()
+
+	e.process = &external.Process{
+		Command:             e.Command,
+		Environment:         e.Environment,
+		Kind:                external.KindInput,
+		Timeout:             e.Timeout.Duration,
+		RestartDelay:        e.RestartDelay.Duration,
+		HealthCheckInterval: e.HealthCheckInterval.Duration,
+		Log:                 e.Log,
+		OnMetrics:           e.addMetrics,
+	}
+	return e.process.Start()
+}
+
+func (e *External) Stop() {
+	e.process.Stop()
+}
+
+func (e *External) Gather(acc telegraf.Accumulator) error {
+	resp, err := e.process.Request(external.Request{Type: external.RequestGather})
+	if err != nil {
+		return err
+	}
+	metrics, err := external.DecodeMetrics(resp.Metrics)
+	if err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	return nil
+}
+
+// addMetrics adds the metrics the plugin sends by itself, as a service
+// input does.
+func (e *External) addMetrics(metrics []telegraf.Metric) {
+	e.Lock()
+	defer e.Unlock()
+	for _, m := range metrics {
+		e.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+}
+
+func init() {
+	inputs.Add("external", func() telegraf.Input {
+		return NewExternal()
+	})
+}
@@ -0,0 +1,73 @@
+package external
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/external/sdk"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary runs as the external input when this environment variable
+// is set.
+const pluginEnv = "TELEGRAF_TEST_EXTERNAL_INPUT"
+
+// testInput is a service input adding a metric once started, and a metric
+// on each gather.
+type testInput struct{}
+
+func (i *testInput) SampleConfig() string { return "" }
+func (i *testInput) Description() string  { return "" }
+func (i *testInput) Start(acc telegraf.Accumulator) error {
+	acc.AddFields("service", map[string]interface{}{"value": 1},
+		map[string]string{"source": "start"})
+	return nil
+}
+func (i *testInput) Stop() {}
+func (i *testInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("gather", map[string]interface{}{"value": 2},
+		map[string]string{"source": "gather"}, time.Unix(0, 1))
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(pluginEnv) != "" {
+		if err := sdk.Serve(&testInput{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestInitNoCommand(t *testing.T) {
+	assert.Error(t, NewExternal().Init())
+}
+
+func TestGather(t *testing.T) {
+	e := NewExternal()
+	e.Command = []string{os.Args[0]}
+	e.Environment = []string{pluginEnv + "=1"}
+	e.Log = testutil.Logger{Name: "inputs.external"}
+	require.NoError(t, e.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	require.NoError(t, e.Gather(acc))
+	acc.Wait(2)
+
+	acc.AssertContainsTaggedFields(t, "service",
+		map[string]interface{}{"value": int64(1)},
+		map[string]string{"source": "start"})
+	acc.AssertContainsTaggedFields(t, "gather",
+		map[string]interface{}{"value": int64(2)},
+		map[string]string{"source": "gather"})
+}
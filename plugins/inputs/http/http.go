@@ -0,0 +1,216 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/errchan"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type HTTP struct {
+	URLs    []string `toml:"urls"`
+	Method  string
+	Body    string
+	Headers map[string]string
+
+	// HTTP Basic Auth Credentials
+	Username string
+	Password string
+
+	// Bearer Token authorization file path
+	BearerToken string `toml:"bearer_token"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	Timeout internal.Duration
+
+	client *http.Client
+	parser parsers.Parser
+}
+
+var sampleConfig = `
+  ## One or more URLs from which to read formatted metrics
+  urls = [
+    "http://localhost/metrics"
+  ]
+
+  ## HTTP method, one of: "GET" or "POST"
+  # method = "GET"
+
+  ## Optional HTTP headers
+  # headers = {"X-Special-Header" = "Special-Value"}
+
+  ## Optional HTTP request body, sent with the request
+  # body = ''
+
+  ## Optional HTTP Basic Auth Credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Optional path to a file containing a bearer token, sent in the
+  ## Authorization header.
+  # bearer_token = "/path/to/bearer/token"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Amount of time allowed to complete the HTTP request
+  # timeout = "5s"
+
+  ## Data format to consume.
+  ## Each data format has it's own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  # data_format = "influx"
+`
+
+func (h *HTTP) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTP) Description() string {
+	return "Read formatted metrics from one or more HTTP endpoints"
+}
+
+func (h *HTTP) SetParser(parser parsers.Parser) {
+	h.parser = parser
+}
+
+// Gather takes in an accumulator and adds the metrics that the Input
+// gathers. This is called every "interval"
+func (h *HTTP) Gather(acc telegraf.Accumulator) error {
+	if h.client == nil {
+		if err := h.init(); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	errChan := errchan.New(len(h.URLs))
+	for _, u := range h.URLs {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			errChan.C <- h.gatherURL(acc, url)
+		}(u)
+	}
+
+	wg.Wait()
+	return errChan.Error()
+}
+
+func (h *HTTP) init() error {
+	h.Method = strings.ToUpper(h.Method)
+	if h.Method == "" {
+		h.Method = http.MethodGet
+	}
+	if h.Method != http.MethodGet && h.Method != http.MethodPost {
+		return fmt.Errorf("invalid method %q, must be GET or POST", h.Method)
+	}
+
+	tlsCfg, err := internal.GetTLSConfig(
+		h.SSLCert, h.SSLKey, h.SSLCA, h.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+
+	h.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: h.Timeout.Duration,
+	}
+	return nil
+}
+
+// gatherURL gathers the metrics parsed from the body of one request to url.
+func (h *HTTP) gatherURL(acc telegraf.Accumulator, url string) error {
+	var body io.Reader
+	if h.Body != "" {
+		body = strings.NewReader(h.Body)
+	}
+	req, err := http.NewRequest(h.Method, url, body)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range h.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+
+	if h.Username != "" || h.Password != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	if h.BearerToken != "" {
+		token, err := ioutil.ReadFile(h.BearerToken)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making HTTP request to %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d (%s) from %s, expected %d (%s)",
+			resp.StatusCode, http.StatusText(resp.StatusCode), url,
+			http.StatusOK, http.StatusText(http.StatusOK))
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading body from %s: %s", url, err)
+	}
+
+	metrics, err := h.parser.Parse(b)
+	if err != nil {
+		return fmt.Errorf("error parsing body from %s: %s", url, err)
+	}
+
+	for _, metric := range metrics {
+		tags := metric.Tags()
+		if _, ok := tags["url"]; !ok {
+			tags["url"] = url
+		}
+		acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("http", func() telegraf.Input {
+		return &HTTP{
+			Method:  http.MethodGet,
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
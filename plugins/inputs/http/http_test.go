@@ -0,0 +1,156 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const influxBody = "cpu,host=server01 usage_idle=90 1422568543702900257\n" +
+	"cpu,host=server02 usage_idle=80 1422568543702900257\n"
+
+func newTestHTTP(urls ...string) *HTTP {
+	h := &HTTP{URLs: urls}
+	p, _ := parsers.NewInfluxParser()
+	h.SetParser(p)
+	return h
+}
+
+func TestHTTPGather(t *testing.T) {
+	var req *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		w.Write([]byte(influxBody))
+	}))
+	defer ts.Close()
+
+	url := ts.URL + "/metrics"
+	h := newTestHTTP(url)
+	h.Headers = map[string]string{"X-Custom": "value", "Host": "example.com"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+
+	require.NotNil(t, req)
+	assert.Equal(t, "GET", req.Method)
+	assert.Equal(t, "/metrics", req.URL.Path)
+	assert.Equal(t, "value", req.Header.Get("X-Custom"))
+	assert.Equal(t, "example.com", req.Host)
+
+	require.Equal(t, 2, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_idle": float64(90)},
+		map[string]string{"host": "server01", "url": url})
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_idle": float64(80)},
+		map[string]string{"host": "server02", "url": url})
+}
+
+func TestHTTPGatherPost(t *testing.T) {
+	var method string
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(influxBody))
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.Method = "post"
+	h.Body = `{"query": "cpu"}`
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	assert.Equal(t, "POST", method)
+	assert.Equal(t, `{"query": "cpu"}`, string(body))
+	assert.Equal(t, 2, len(acc.Metrics))
+}
+
+func TestHTTPGatherAuth(t *testing.T) {
+	var user, pass, auth string
+	var ok bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok = r.BasicAuth()
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(influxBody))
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.Username = "telegraf"
+	h.Password = "secret"
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	assert.True(t, ok)
+	assert.Equal(t, "telegraf", user)
+	assert.Equal(t, "secret", pass)
+
+	token, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(token.Name())
+	token.WriteString("abc123\n")
+	token.Close()
+
+	h = newTestHTTP(ts.URL)
+	h.BearerToken = token.Name()
+	require.NoError(t, h.Gather(&acc))
+	assert.Equal(t, "Bearer abc123", auth)
+}
+
+func TestHTTPGatherTLS(t *testing.T) {
+	pki := testutil.NewPKI()
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(influxBody))
+	}))
+	ts.TLS = pki.ServerTLSConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.SSLCA = pki.CACertPath()
+	h.SSLCert = pki.ClientCertPath()
+	h.SSLKey = pki.ClientKeyPath()
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	assert.Equal(t, 2, len(acc.Metrics))
+}
+
+func TestHTTPGatherErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/notfound":
+			w.WriteHeader(http.StatusNotFound)
+		case "/invalid":
+			w.Write([]byte("not line protocol\n"))
+		default:
+			w.Write([]byte(influxBody))
+		}
+	}))
+	defer ts.Close()
+
+	// an error on one url still gathers the metrics of the others.
+	h := newTestHTTP(ts.URL+"/notfound", ts.URL+"/metrics")
+	var acc testutil.Accumulator
+	assert.Error(t, h.Gather(&acc))
+	assert.Equal(t, 2, len(acc.Metrics))
+
+	h = newTestHTTP(ts.URL + "/invalid")
+	assert.Error(t, h.Gather(&acc))
+}
+
+func TestHTTPInvalidMethod(t *testing.T) {
+	h := newTestHTTP("http://localhost")
+	h.Method = "DELETE"
+	var acc testutil.Accumulator
+	assert.Error(t, h.Gather(&acc))
+}
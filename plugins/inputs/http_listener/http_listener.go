@@ -3,16 +3,21 @@ package http_listener
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/subtle"
+	"crypto/tls"
 	"io"
-	"log"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/prometheus"
+	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 )
 
@@ -35,12 +40,29 @@ type HTTPListener struct {
 	MaxBodySize    int64
 	MaxLineSize    int
 
+	// Paths accepting writes in the configured data format
+	Paths []string
+	// Path accepting metrics in the Prometheus exposition formats, disabled
+	// when empty
+	PrometheusPath string `toml:"prometheus_path"`
+
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	// Credentials of requests, either of which is accepted when both are set
+	BasicUsername string `toml:"basic_username"`
+	BasicPassword string `toml:"basic_password"`
+	Token         string `toml:"token"`
+
+	Log telegraf.Logger `toml:"-"`
+
 	mu sync.Mutex
 	wg sync.WaitGroup
 
 	listener net.Listener
 
-	parser influx.InfluxParser
+	parser parsers.Parser
 	acc    telegraf.Accumulator
 	pool   *pool
 }
@@ -61,6 +83,34 @@ const sampleConfig = `
   ## Maximum line size allowed to be sent in bytes.
   ## 0 means to use the default of 65536 bytes (64 kibibytes)
   max_line_size = 0
+
+  ## Paths to accept writes in the configured data format on.
+  # paths = ["/write"]
+
+  ## Path to accept metrics in the Prometheus text or protobuf exposition
+  ## formats on, with optional grouping labels appended to the path as tags,
+  ## eg: /metrics/job/<job>/instance/<instance>. Disabled by default.
+  # prometheus_path = "/metrics"
+
+  ## TLS Config, serving HTTPS when set.
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## If set, clients must present a certificate signed by one of these CAs.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Optional credentials required of requests, either as HTTP Basic Auth
+  ## or as a token in the Authorization header:
+  ##   Authorization: Token <token>
+  ## When both are set either is accepted.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+  # token = "secret"
+
+  ## Data format to consume on the write paths.
+  ## Each data format has it's own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  # data_format = "influx"
 `
 
 func (h *HTTPListener) SampleConfig() string {
@@ -68,11 +118,15 @@ func (h *HTTPListener) SampleConfig() string {
 }
 
 func (h *HTTPListener) Description() string {
-	return "Influx HTTP write listener"
+	return "HTTP write listener for any input data format"
+}
+
+func (h *HTTPListener) SetParser(parser parsers.Parser) {
+	h.parser = parser
 }
 
 func (h *HTTPListener) Gather(_ telegraf.Accumulator) error {
-	log.Printf("D! The http_listener has created %d buffers", h.pool.ncreated())
+	h.Log.Debugf("Created %d buffers", h.pool.ncreated())
 	return nil
 }
 
@@ -87,14 +141,29 @@ func (h *HTTPListener) Start(acc telegraf.Accumulator) error {
 	if h.MaxLineSize == 0 {
 		h.MaxLineSize = DEFAULT_MAX_LINE_SIZE
 	}
+	if len(h.Paths) == 0 {
+		h.Paths = []string{"/write"}
+	}
+	if h.parser == nil {
+		h.parser = &influx.InfluxParser{}
+	}
+
+	tlsConfig, err := internal.GetServerTLSConfig(
+		h.TLSCert, h.TLSKey, h.TLSAllowedCACerts)
+	if err != nil {
+		return err
+	}
 
 	h.acc = acc
 	h.pool = NewPool(200, h.MaxLineSize)
 
-	var listener, err = net.Listen("tcp", h.ServiceAddress)
+	listener, err := net.Listen("tcp", h.ServiceAddress)
 	if err != nil {
 		return err
 	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
 	h.listener = listener
 
 	h.wg.Add(1)
@@ -103,7 +172,7 @@ func (h *HTTPListener) Start(acc telegraf.Accumulator) error {
 		h.httpListen()
 	}()
 
-	log.Printf("I! Started HTTP listener service on %s\n", h.ServiceAddress)
+	h.Log.Infof("Started HTTP listener service on %s", h.ServiceAddress)
 
 	return nil
 }
@@ -116,7 +185,7 @@ func (h *HTTPListener) Stop() {
 	h.listener.Close()
 	h.wg.Wait()
 
-	log.Println("I! Stopped HTTP listener service on ", h.ServiceAddress)
+	h.Log.Infof("Stopped HTTP listener service on %s", h.ServiceAddress)
 }
 
 // httpListen sets up an http.Server and calls server.Serve.
@@ -141,9 +210,26 @@ func (h *HTTPListener) httpListen() error {
 }
 
 func (h *HTTPListener) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	// pings are answered without credentials, as by InfluxDB.
+	if req.URL.Path != "/ping" && !h.authorized(req) {
+		unauthorized(res, h.BasicUsername != "")
+		return
+	}
+
+	if h.isWritePath(req.URL.Path) {
+		if _, ok := h.parser.(*influx.InfluxParser); ok {
+			h.serveWrite(res, req)
+		} else {
+			h.serveParse(res, req)
+		}
+		return
+	}
+	if labels, ok := h.prometheusLabels(req.URL.Path); ok {
+		h.servePrometheus(res, req, labels)
+		return
+	}
+
 	switch req.URL.Path {
-	case "/write":
-		h.serveWrite(res, req)
 	case "/query":
 		// Deliver a dummy response to the query endpoint, as some InfluxDB
 		// clients test endpoint availability with a query
@@ -160,6 +246,68 @@ func (h *HTTPListener) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (h *HTTPListener) isWritePath(path string) bool {
+	for _, p := range h.Paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// prometheusLabels returns whether path is the Prometheus path, and the
+// grouping labels appended to it as name/value segments.
+func (h *HTTPListener) prometheusLabels(path string) (map[string]string, bool) {
+	if h.PrometheusPath == "" || !strings.HasPrefix(path, h.PrometheusPath) {
+		return nil, false
+	}
+	rest := path[len(h.PrometheusPath):]
+	if rest == "" || rest == "/" {
+		return nil, true
+	}
+	if rest[0] != '/' {
+		return nil, false
+	}
+
+	labels := make(map[string]string)
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	for i := 0; i+1 < len(segments); i += 2 {
+		labels[segments[i]] = segments[i+1]
+	}
+	if len(segments)%2 != 0 {
+		// a label without a value is rejected as a bad request.
+		labels[segments[len(segments)-1]] = ""
+	}
+	return labels, true
+}
+
+// authorized returns whether the request carries the configured credentials.
+func (h *HTTPListener) authorized(req *http.Request) bool {
+	if h.BasicUsername == "" && h.BasicPassword == "" && h.Token == "" {
+		return true
+	}
+
+	if h.BasicUsername != "" || h.BasicPassword != "" {
+		username, password, ok := req.BasicAuth()
+		if ok &&
+			subtle.ConstantTimeCompare([]byte(username), []byte(h.BasicUsername)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(h.BasicPassword)) == 1 {
+			return true
+		}
+	}
+
+	if h.Token != "" {
+		auth := req.Header.Get("Authorization")
+		for _, scheme := range []string{"Token ", "Bearer "} {
+			if strings.HasPrefix(auth, scheme) &&
+				subtle.ConstantTimeCompare([]byte(auth[len(scheme):]), []byte(h.Token)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 	// Check that the content length is not too large for us to handle.
 	if req.ContentLength > h.MaxBodySize {
@@ -175,7 +323,7 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 		body, err = gzip.NewReader(req.Body)
 		defer body.Close()
 		if err != nil {
-			log.Println("E! " + err.Error())
+			h.Log.Error(err.Error())
 			badRequest(res)
 			return
 		}
@@ -190,7 +338,7 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 	for {
 		n, err := io.ReadFull(body, buf[bufStart:])
 		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
-			log.Println("E! " + err.Error())
+			h.Log.Error(err.Error())
 			// problem reading the request body
 			badRequest(res)
 			return
@@ -224,7 +372,7 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 		if err == io.ErrUnexpectedEOF {
 			// finished reading the request body
 			if err := h.parse(buf[:n+bufStart], now); err != nil {
-				log.Println("E! " + err.Error())
+				h.Log.Error(err.Error())
 				return400 = true
 			}
 			if return400 {
@@ -241,7 +389,7 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 		i := bytes.LastIndexByte(buf, '\n')
 		if i == -1 {
 			// drop any line longer than the max buffer size
-			log.Printf("E! http_listener received a single line longer than the maximum of %d bytes",
+			h.Log.Errorf("Received a single line longer than the maximum of %d bytes",
 				len(buf))
 			hangingBytes = true
 			return400 = true
@@ -249,7 +397,7 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 			continue
 		}
 		if err := h.parse(buf[:i], now); err != nil {
-			log.Println("E! " + err.Error())
+			h.Log.Error(err.Error())
 			return400 = true
 		}
 		// rotate the bit remaining after the last newline to the front of the buffer
@@ -261,8 +409,97 @@ func (h *HTTPListener) serveWrite(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// serveParse parses the whole request body with the configured parser, for
+// data formats which can't be parsed a line at a time.
+func (h *HTTPListener) serveParse(res http.ResponseWriter, req *http.Request) {
+	body, ok := h.readBody(res, req)
+	if !ok {
+		return
+	}
+
+	metrics, err := h.parser.Parse(body)
+	if err != nil {
+		h.Log.Error(err.Error())
+		badRequest(res)
+		return
+	}
+	for _, m := range metrics {
+		h.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// servePrometheus accepts metrics in the Prometheus exposition formats,
+// tagged with the grouping labels of the path.
+func (h *HTTPListener) servePrometheus(
+	res http.ResponseWriter,
+	req *http.Request,
+	labels map[string]string,
+) {
+	for name, value := range labels {
+		if value == "" {
+			h.Log.Errorf("Received label %q without a value", name)
+			badRequest(res)
+			return
+		}
+	}
+
+	body, ok := h.readBody(res, req)
+	if !ok {
+		return
+	}
+
+	metrics, err := prometheus.Parse(body, req.Header)
+	if err != nil {
+		h.Log.Error(err.Error())
+		badRequest(res)
+		return
+	}
+	for _, m := range metrics {
+		tags := m.Tags()
+		for k, v := range labels {
+			tags[k] = v
+		}
+		h.acc.AddFields(m.Name(), m.Fields(), tags, m.Time())
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// readBody reads the whole, possibly gzipped, request body. On failure the
+// response is written and false returned.
+func (h *HTTPListener) readBody(res http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	if req.ContentLength > h.MaxBodySize {
+		tooLarge(res)
+		return nil, false
+	}
+
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		r, err := gzip.NewReader(req.Body)
+		if err != nil {
+			h.Log.Error(err.Error())
+			badRequest(res)
+			return nil, false
+		}
+		defer r.Close()
+		body = r
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(body, h.MaxBodySize+1))
+	if err != nil {
+		h.Log.Error(err.Error())
+		badRequest(res)
+		return nil, false
+	}
+	if int64(len(b)) > h.MaxBodySize {
+		tooLarge(res)
+		return nil, false
+	}
+	return b, true
+}
+
 func (h *HTTPListener) parse(b []byte, t time.Time) error {
-	metrics, err := h.parser.ParseWithDefaultTime(b, t)
+	metrics, err := h.parser.(*influx.InfluxParser).ParseWithDefaultTime(b, t)
 
 	for _, m := range metrics {
 		h.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
@@ -278,6 +515,16 @@ func tooLarge(res http.ResponseWriter) {
 	res.Write([]byte(`{"error":"http: request body too large"}`))
 }
 
+func unauthorized(res http.ResponseWriter, basic bool) {
+	if basic {
+		res.Header().Set("WWW-Authenticate", `Basic realm="telegraf"`)
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("X-Influxdb-Version", "1.0")
+	res.WriteHeader(http.StatusUnauthorized)
+	res.Write([]byte(`{"error":"http: unauthorized"}`))
+}
+
 func badRequest(res http.ResponseWriter) {
 	res.Header().Set("Content-Type", "application/json")
 	res.Header().Set("X-Influxdb-Version", "1.0")
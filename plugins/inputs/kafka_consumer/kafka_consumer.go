@@ -1,7 +1,6 @@
 package kafka_consumer
 
 import (
-	"log"
 	"strings"
 	"sync"
 
@@ -10,16 +9,26 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers"
 
 	"github.com/Shopify/sarama"
+	cluster "github.com/bsm/sarama-cluster"
 	"github.com/wvanbergen/kafka/consumergroup"
 )
 
+const defaultMaxUndeliveredMessages = 1000
+
 type Kafka struct {
-	ConsumerGroup   string
-	Topics          []string
+	ConsumerGroup string
+	Topics        []string
+	// Kafka brokers of broker based consumer groups
+	Brokers         []string
 	ZookeeperPeers  []string
 	ZookeeperChroot string
 	Consumer        *consumergroup.ConsumerGroup
 
+	// Commit offsets only once the metrics of a message are delivered
+	CommitAfterDelivery bool `toml:"commit_after_delivery"`
+	// Maximum number of messages whose offsets are not committed yet
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// Legacy metric buffer support
 	MetricBuffer int
 	// TODO remove PointBuffer, legacy support
@@ -28,25 +37,54 @@ type Kafka struct {
 	Offset string
 	parser parsers.Parser
 
+	Log telegraf.Logger `toml:"-"`
+
 	sync.Mutex
 
 	// channel for all incoming kafka messages
 	in <-chan *sarama.ConsumerMessage
 	// channel for all kafka consumer errors
 	errs <-chan *sarama.ConsumerError
-	done chan struct{}
+	// channel for all broker based consumer errors
+	clusterErrs <-chan error
+	done        chan struct{}
+
+	cluster *cluster.Consumer
+	marker  offsetMarker
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc      telegraf.Accumulator
+	tracking telegraf.TrackingAccumulator
 
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
 	doNotCommitMsgs bool
 }
 
+// offsetMarker marks messages as consumed, for their offsets to be committed.
+type offsetMarker interface {
+	MarkOffset(msg *sarama.ConsumerMessage, metadata string)
+}
+
+// zookeeperMarker commits offsets of the zookeeper based consumer group.
+type zookeeperMarker struct {
+	k *Kafka
+}
+
+func (z zookeeperMarker) MarkOffset(msg *sarama.ConsumerMessage, _ string) {
+	// TODO(cam) this locking can be removed if this PR gets merged:
+	// https://github.com/wvanbergen/kafka/pull/84
+	z.k.Lock()
+	z.k.Consumer.CommitUpto(msg)
+	z.k.Unlock()
+}
+
 var sampleConfig = `
   ## topic(s) to consume
   topics = ["telegraf"]
+  ## an array of kafka brokers, to use a broker based consumer group.
+  ## When set, zookeeper_peers is not used.
+  # brokers = ["localhost:9092"]
   ## an array of Zookeeper connection strings
   zookeeper_peers = ["localhost:2181"]
   ## Zookeeper Chroot
@@ -56,6 +94,16 @@ var sampleConfig = `
   ## Offset (must be either "oldest" or "newest")
   offset = "oldest"
 
+  ## Commit the offset of a message only once its metrics have been written
+  ## by all outputs, so that no message is lost if telegraf stops before
+  ## writing them. Messages may then be read again after a restart.
+  # commit_after_delivery = false
+  ## Maximum number of messages read whose offsets have not been committed
+  ## yet, when committing after delivery. Should be no larger than the
+  ## metric_buffer_limit of the outputs, divided by the number of metrics
+  ## in a message.
+  # max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has it's own unique set of configuration options, read
   ## more about them here:
@@ -78,87 +126,273 @@ func (k *Kafka) SetParser(parser parsers.Parser) {
 func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	k.Lock()
 	defer k.Unlock()
-	var consumerErr error
 
 	k.acc = acc
+	if k.CommitAfterDelivery {
+		if k.MaxUndeliveredMessages <= 0 {
+			k.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+		}
+		k.tracking = acc.WithTracking(k.MaxUndeliveredMessages)
+	}
 
-	config := consumergroup.NewConfig()
-	config.Zookeeper.Chroot = k.ZookeeperChroot
+	var offset int64
 	switch strings.ToLower(k.Offset) {
 	case "oldest", "":
-		config.Offsets.Initial = sarama.OffsetOldest
+		offset = sarama.OffsetOldest
 	case "newest":
-		config.Offsets.Initial = sarama.OffsetNewest
+		offset = sarama.OffsetNewest
 	default:
-		log.Printf("I! WARNING: Kafka consumer invalid offset '%s', using 'oldest'\n",
-			k.Offset)
-		config.Offsets.Initial = sarama.OffsetOldest
+		k.Log.Warnf("Invalid offset %q, using 'oldest'", k.Offset)
+		offset = sarama.OffsetOldest
+	}
+
+	var err error
+	if len(k.Brokers) > 0 {
+		err = k.joinCluster(offset)
+	} else {
+		err = k.joinZookeeper(offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	k.done = make(chan struct{})
+
+	// Start the kafka message reader
+	go k.receiver()
+	k.Log.Infof("Started the kafka consumer service, peers: %v, topics: %v",
+		k.peers(), k.Topics)
+	return nil
+}
+
+// joinCluster joins the broker based consumer group.
+func (k *Kafka) joinCluster(offset int64) error {
+	config := cluster.NewConfig()
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = offset
+
+	consumer, err := cluster.NewConsumer(k.Brokers, k.ConsumerGroup, k.Topics, config)
+	if err != nil {
+		return err
 	}
+	k.cluster = consumer
+	k.marker = consumer
+
+	// Setup message and error channels
+	k.in = consumer.Messages()
+	k.clusterErrs = consumer.Errors()
+	return nil
+}
+
+// joinZookeeper joins the zookeeper based consumer group.
+func (k *Kafka) joinZookeeper(offset int64) error {
+	config := consumergroup.NewConfig()
+	config.Zookeeper.Chroot = k.ZookeeperChroot
+	config.Offsets.Initial = offset
 
 	if k.Consumer == nil || k.Consumer.Closed() {
-		k.Consumer, consumerErr = consumergroup.JoinConsumerGroup(
+		consumer, err := consumergroup.JoinConsumerGroup(
 			k.ConsumerGroup,
 			k.Topics,
 			k.ZookeeperPeers,
 			config,
 		)
-		if consumerErr != nil {
-			return consumerErr
+		if err != nil {
+			return err
 		}
+		k.Consumer = consumer
 
 		// Setup message and error channels
 		k.in = k.Consumer.Messages()
 		k.errs = k.Consumer.Errors()
 	}
+	k.marker = zookeeperMarker{k}
+	return nil
+}
 
-	k.done = make(chan struct{})
+func (k *Kafka) peers() []string {
+	if len(k.Brokers) > 0 {
+		return k.Brokers
+	}
+	return k.ZookeeperPeers
+}
 
-	// Start the kafka message reader
-	go k.receiver()
-	log.Printf("I! Started the kafka consumer service, peers: %v, topics: %v\n",
-		k.ZookeeperPeers, k.Topics)
-	return nil
+// topicPartition is a partition of a topic.
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// pendingMessage is a message whose offset is not marked yet.
+type pendingMessage struct {
+	msg       *sarama.ConsumerMessage
+	delivered bool
+}
+
+// offsetTracker tracks the messages whose metrics are being delivered, in
+// the order they were read from each partition. As marking the offset of a
+// message commits the offsets of the messages before it, the offset of a
+// partition is only marked once the metrics of all its messages up to it
+// are delivered, whatever the order they are delivered in.
+type offsetTracker struct {
+	partitions map[topicPartition][]*pendingMessage
+	pending    map[telegraf.TrackingID]*pendingMessage
+	// stopped are the partitions with a message whose metrics were not
+	// delivered, whose offsets are no longer marked so that the message is
+	// read again after a restart.
+	stopped map[topicPartition]bool
+	// count is the number of messages whose offsets are not marked yet.
+	count int
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[topicPartition][]*pendingMessage),
+		pending:    make(map[telegraf.TrackingID]*pendingMessage),
+		stopped:    make(map[topicPartition]bool),
+	}
+}
+
+// add tracks the message whose metrics are tracked with the given id.
+func (t *offsetTracker) add(id telegraf.TrackingID, msg *sarama.ConsumerMessage) {
+	p := &pendingMessage{msg: msg}
+	tp := topicPartition{msg.Topic, msg.Partition}
+	t.partitions[tp] = append(t.partitions[tp], p)
+	t.pending[id] = p
+	t.count++
+}
+
+// deliver records that the metrics tracked with the given id are delivered,
+// returning the message whose offset may now be marked, if any: the last of
+// the messages of its partition which are delivered along with all the ones
+// before them.
+func (t *offsetTracker) deliver(id telegraf.TrackingID) *sarama.ConsumerMessage {
+	p, ok := t.pending[id]
+	if !ok {
+		return nil
+	}
+	tp := topicPartition{p.msg.Topic, p.msg.Partition}
+	mark := t.done(id, p)
+	if t.stopped[tp] {
+		return nil
+	}
+	return mark
+}
+
+// drop records that the metrics tracked with the given id were not
+// delivered, returning their message, if any. The offsets of its partition
+// are no longer marked.
+func (t *offsetTracker) drop(id telegraf.TrackingID) *sarama.ConsumerMessage {
+	p, ok := t.pending[id]
+	if !ok {
+		return nil
+	}
+	t.stopped[topicPartition{p.msg.Topic, p.msg.Partition}] = true
+	t.done(id, p)
+	return p.msg
+}
+
+// done removes the message tracked with the given id, and the messages of
+// its partition done along with all the ones before them, returning the last
+// of those.
+func (t *offsetTracker) done(id telegraf.TrackingID, p *pendingMessage) *sarama.ConsumerMessage {
+	delete(t.pending, id)
+	p.delivered = true
+
+	tp := topicPartition{p.msg.Topic, p.msg.Partition}
+	queue := t.partitions[tp]
+	var mark *sarama.ConsumerMessage
+	for len(queue) > 0 && queue[0].delivered {
+		mark = queue[0].msg
+		queue = queue[1:]
+		t.count--
+	}
+	if len(queue) == 0 {
+		delete(t.partitions, tp)
+	} else {
+		t.partitions[tp] = queue
+	}
+	return mark
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
 // influxdb metric points.
 func (k *Kafka) receiver() {
+	offsets := newOffsetTracker()
+
 	for {
+		in := k.in
+		var delivered <-chan telegraf.DeliveryInfo
+		if k.tracking != nil {
+			delivered = k.tracking.Delivered()
+			if offsets.count >= k.MaxUndeliveredMessages {
+				// stop reading until the offsets of some messages can be
+				// marked.
+				in = nil
+			}
+		}
+
 		select {
 		case <-k.done:
 			return
 		case err := <-k.errs:
 			if err != nil {
-				log.Printf("E! Kafka Consumer Error: %s\n", err)
+				k.Log.Errorf("Consumer error: %s", err)
+			}
+		case err := <-k.clusterErrs:
+			if err != nil {
+				k.Log.Errorf("Consumer error: %s", err)
+			}
+		case info := <-delivered:
+			if !info.Delivered() {
+				if msg := offsets.drop(info.ID()); msg != nil {
+					k.Log.Errorf("Metrics of message at offset %d of partition %d of topic %q were not delivered, "+
+						"no longer committing the offsets of the partition", msg.Offset, msg.Partition, msg.Topic)
+				}
+			} else if msg := offsets.deliver(info.ID()); msg != nil {
+				k.markOffset(msg)
 			}
-		case msg := <-k.in:
+		case msg := <-in:
 			metrics, err := k.parser.Parse(msg.Value)
 			if err != nil {
-				log.Printf("E! Kafka Message Parse Error\nmessage: %s\nerror: %s",
+				k.Log.Errorf("Message parse error\nmessage: %s\nerror: %s",
 					string(msg.Value), err.Error())
 			}
 
-			for _, metric := range metrics {
-				k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
+			if k.tracking != nil {
+				id := k.tracking.AddTrackingMetricGroup(metrics)
+				offsets.add(id, msg)
+				continue
 			}
 
-			if !k.doNotCommitMsgs {
-				// TODO(cam) this locking can be removed if this PR gets merged:
-				// https://github.com/wvanbergen/kafka/pull/84
-				k.Lock()
-				k.Consumer.CommitUpto(msg)
-				k.Unlock()
+			for _, metric := range metrics {
+				k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
 			}
+			k.markOffset(msg)
 		}
 	}
 }
 
+func (k *Kafka) markOffset(msg *sarama.ConsumerMessage) {
+	if !k.doNotCommitMsgs {
+		k.marker.MarkOffset(msg, "")
+	}
+}
+
 func (k *Kafka) Stop() {
 	k.Lock()
 	defer k.Unlock()
 	close(k.done)
-	if err := k.Consumer.Close(); err != nil {
-		log.Printf("E! Error closing kafka consumer: %s\n", err.Error())
+
+	var err error
+	if k.cluster != nil {
+		// marked offsets are committed on close.
+		err = k.cluster.Close()
+	} else {
+		err = k.Consumer.Close()
+	}
+	if err != nil {
+		k.Log.Errorf("Error closing kafka consumer: %s", err.Error())
 	}
 }
 
@@ -168,6 +402,8 @@ func (k *Kafka) Gather(acc telegraf.Accumulator) error {
 
 func init() {
 	inputs.Add("kafka_consumer", func() telegraf.Input {
-		return &Kafka{}
+		return &Kafka{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
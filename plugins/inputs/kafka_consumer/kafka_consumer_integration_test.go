@@ -41,6 +41,7 @@ func TestReadsMetricsFromKafka(t *testing.T) {
 		ZookeeperPeers: zkPeers,
 		PointBuffer:    100000,
 		Offset:         "oldest",
+		Log:            testutil.Logger{Name: "inputs.kafka_consumer"},
 	}
 	p, _ := parsers.NewInfluxParser()
 	k.SetParser(p)
@@ -1,9 +1,11 @@
 package kafka_consumer
 
 import (
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -29,6 +31,7 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 		doNotCommitMsgs: true,
 		errs:            make(chan *sarama.ConsumerError, 1000),
 		done:            make(chan struct{}),
+		Log:             testutil.Logger{Name: "inputs.kafka_consumer"},
 	}
 	return &k, in
 }
@@ -123,6 +126,230 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+type testMarker struct {
+	sync.Mutex
+	offsets []int64
+}
+
+func (m *testMarker) MarkOffset(msg *sarama.ConsumerMessage, _ string) {
+	m.Lock()
+	defer m.Unlock()
+	m.offsets = append(m.offsets, msg.Offset)
+}
+
+func (m *testMarker) marked() []int64 {
+	m.Lock()
+	defer m.Unlock()
+	return append([]int64(nil), m.offsets...)
+}
+
+// Test that offsets are marked once the metrics of messages are read
+func TestRunParserMarkOffsets(t *testing.T) {
+	k, in := newTestKafka()
+	acc := testutil.Accumulator{}
+	marker := &testMarker{}
+	k.acc = &acc
+	k.marker = marker
+	k.doNotCommitMsgs = false
+	defer close(k.done)
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	in <- saramaMsgWithOffset(testMsg, 1)
+	in <- saramaMsgWithOffset(testMsg, 2)
+	time.Sleep(time.Millisecond * 5)
+
+	assert.Equal(t, 2, acc.NFields())
+	assert.Equal(t, []int64{1, 2}, marker.marked())
+}
+
+// Test that offsets are marked once the metrics of messages are delivered
+func TestRunParserCommitAfterDelivery(t *testing.T) {
+	k, in := newTestKafka()
+	acc := testutil.Accumulator{}
+	marker := &testMarker{}
+	k.acc = &acc
+	k.tracking = acc.WithTracking(10)
+	k.MaxUndeliveredMessages = 10
+	k.marker = marker
+	k.doNotCommitMsgs = false
+	defer close(k.done)
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	in <- saramaMsgWithOffset(testMsg, 1)
+	in <- saramaMsgWithOffset(testMsg, 2)
+	time.Sleep(time.Millisecond * 5)
+
+	assert.Equal(t, 2, acc.NFields())
+	assert.Equal(t, []int64{1, 2}, marker.marked())
+}
+
+type manualDelivery struct {
+	id telegraf.TrackingID
+}
+
+func (d manualDelivery) ID() telegraf.TrackingID { return d.id }
+func (d manualDelivery) Delivered() bool         { return true }
+
+// manualTracking is a tracking accumulator whose groups are only delivered
+// when the test delivers them.
+type manualTracking struct {
+	testutil.Accumulator
+	sync.Mutex
+	ids       []telegraf.TrackingID
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *manualTracking) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	a.Lock()
+	defer a.Unlock()
+	id := telegraf.TrackingID(len(a.ids) + 1)
+	a.ids = append(a.ids, id)
+	return id
+}
+
+func (a *manualTracking) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+func (a *manualTracking) tracked() int {
+	a.Lock()
+	defer a.Unlock()
+	return len(a.ids)
+}
+
+// Test that no more messages are read than may be undelivered
+func TestRunParserMaxUndeliveredMessages(t *testing.T) {
+	k, in := newTestKafka()
+	acc := &manualTracking{delivered: make(chan telegraf.DeliveryInfo, 2)}
+	marker := &testMarker{}
+	k.acc = acc
+	k.tracking = acc
+	k.MaxUndeliveredMessages = 2
+	k.marker = marker
+	k.doNotCommitMsgs = false
+	defer close(k.done)
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	in <- saramaMsgWithOffset(testMsg, 1)
+	in <- saramaMsgWithOffset(testMsg, 2)
+	in <- saramaMsgWithOffset(testMsg, 3)
+	time.Sleep(time.Millisecond * 5)
+
+	assert.Equal(t, 2, acc.tracked())
+	assert.Empty(t, marker.marked())
+
+	// the offset of message 1 is not committed yet, so message 2 still
+	// counts as undelivered.
+	acc.delivered <- manualDelivery{id: 2}
+	time.Sleep(time.Millisecond * 5)
+
+	assert.Equal(t, 2, acc.tracked())
+	assert.Empty(t, marker.marked())
+
+	acc.delivered <- manualDelivery{id: 1}
+	time.Sleep(time.Millisecond * 5)
+
+	assert.Equal(t, 3, acc.tracked())
+	assert.Equal(t, []int64{2}, marker.marked())
+}
+
+// Test that the offset of a partition is only marked once the metrics of
+// all its messages up to it are delivered, when they are delivered out of
+// order.
+func TestRunParserCommitOutOfOrder(t *testing.T) {
+	k, in := newTestKafka()
+	acc := &manualTracking{delivered: make(chan telegraf.DeliveryInfo, 10)}
+	marker := &testMarker{}
+	k.acc = acc
+	k.tracking = acc
+	k.MaxUndeliveredMessages = 10
+	k.marker = marker
+	k.doNotCommitMsgs = false
+	defer close(k.done)
+
+	k.parser, _ = parsers.NewInfluxParser()
+	go k.receiver()
+	in <- saramaMsgWithOffset(testMsg, 10)
+	in <- saramaMsgWithOffset(testMsg, 11)
+	in <- saramaMsgWithOffset(testMsg, 12)
+	time.Sleep(time.Millisecond * 5)
+	assert.Equal(t, 3, acc.tracked())
+
+	acc.delivered <- manualDelivery{id: 3}
+	acc.delivered <- manualDelivery{id: 2}
+	time.Sleep(time.Millisecond * 5)
+	assert.Empty(t, marker.marked())
+
+	acc.delivered <- manualDelivery{id: 1}
+	time.Sleep(time.Millisecond * 5)
+	assert.Equal(t, []int64{12}, marker.marked())
+}
+
+func TestOffsetTrackerPartitions(t *testing.T) {
+	offsets := newOffsetTracker()
+	msg := func(partition int32, offset int64) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{
+			Topic:     "telegraf",
+			Partition: partition,
+			Offset:    offset,
+		}
+	}
+	offsets.add(1, msg(0, 5))
+	offsets.add(2, msg(1, 7))
+	offsets.add(3, msg(0, 6))
+	offsets.add(4, msg(1, 8))
+	assert.Equal(t, 4, offsets.count)
+
+	// partitions are committed independently
+	assert.Equal(t, int64(7), offsets.deliver(2).Offset)
+	assert.Nil(t, offsets.deliver(3))
+	assert.Nil(t, offsets.deliver(3))
+	assert.Equal(t, 3, offsets.count)
+
+	assert.Equal(t, int64(6), offsets.deliver(1).Offset)
+	assert.Equal(t, int64(8), offsets.deliver(4).Offset)
+	assert.Equal(t, 0, offsets.count)
+	assert.Empty(t, offsets.partitions)
+	assert.Empty(t, offsets.pending)
+}
+
+func TestOffsetTrackerDrop(t *testing.T) {
+	offsets := newOffsetTracker()
+	msg := func(partition int32, offset int64) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{
+			Topic:     "telegraf",
+			Partition: partition,
+			Offset:    offset,
+		}
+	}
+	offsets.add(1, msg(0, 5))
+	offsets.add(2, msg(0, 6))
+	offsets.add(3, msg(1, 7))
+
+	assert.Equal(t, int64(5), offsets.drop(1).Offset)
+	assert.Nil(t, offsets.drop(1))
+	assert.Equal(t, 2, offsets.count)
+
+	// offsets of the partition are no longer marked
+	assert.Nil(t, offsets.deliver(2))
+	offsets.add(4, msg(0, 8))
+	assert.Nil(t, offsets.deliver(4))
+	assert.Equal(t, 1, offsets.count)
+
+	// other partitions are still marked
+	assert.Equal(t, int64(7), offsets.deliver(3).Offset)
+	assert.Equal(t, 0, offsets.count)
+}
+
+func saramaMsgWithOffset(val string, offset int64) *sarama.ConsumerMessage {
+	msg := saramaMsg(val)
+	msg.Offset = offset
+	return msg
+}
+
 func saramaMsg(val string) *sarama.ConsumerMessage {
 	return &sarama.ConsumerMessage{
 		Key:       nil,
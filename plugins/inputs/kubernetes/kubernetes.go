@@ -6,10 +6,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/errchan"
 	"github.com/influxdata/telegraf/plugins/inputs"
@@ -19,6 +21,18 @@ import (
 type Kubernetes struct {
 	URL string
 
+	// URL of the kubernetes API server, used to look up node labels
+	APIURL string `toml:"api_url"`
+
+	// Pod labels and annotations to add as tags
+	LabelInclude      []string `toml:"label_include"`
+	LabelExclude      []string `toml:"label_exclude"`
+	AnnotationInclude []string `toml:"annotation_include"`
+	AnnotationExclude []string `toml:"annotation_exclude"`
+
+	// Report container usage as a ratio of its resource requests and limits
+	ResourceUsageRatios bool `toml:"resource_usage_ratios"`
+
 	// Bearer Token authorization file path
 	BearerToken string `toml:"bearer_token"`
 
@@ -32,12 +46,30 @@ type Kubernetes struct {
 	InsecureSkipVerify bool
 
 	RoundTripper http.RoundTripper
+
+	labelFilter      *includeExcludeFilter
+	annotationFilter *includeExcludeFilter
 }
 
 var sampleConfig = `
   ## URL for the kubelet
   url = "http://1.1.1.1:10255"
 
+  ## URL for the kubernetes API server. If set, the labels of the node are
+  ## looked up and added as tags to the kubernetes_node measurement.
+  # api_url = "https://kubernetes.default.svc"
+
+  ## Pod labels and annotations to add as tags, prefixed with "label_" and
+  ## "annotation_" respectively. Globs accepted. None are added by default.
+  # label_include = []
+  # label_exclude = []
+  # annotation_include = []
+  # annotation_exclude = []
+
+  ## Report container cpu and memory usage as a ratio of the container's
+  ## resource requests and limits.
+  # resource_usage_ratios = false
+
   ## Use bearer token for authorization
   # bearer_token = /path/to/bearer/token
 
@@ -51,6 +83,8 @@ var sampleConfig = `
 
 const (
 	summaryEndpoint = `%s/stats/summary`
+	podsEndpoint    = `%s/pods`
+	nodeEndpoint    = `%s/api/v1/nodes/%s`
 )
 
 func init() {
@@ -71,6 +105,18 @@ func (k *Kubernetes) Description() string {
 
 //Gather collects kubernetes metrics from a given URL
 func (k *Kubernetes) Gather(acc telegraf.Accumulator) error {
+	if k.labelFilter == nil {
+		var err error
+		k.labelFilter, err = newIncludeExcludeFilter(k.LabelInclude, k.LabelExclude)
+		if err != nil {
+			return err
+		}
+		k.annotationFilter, err = newIncludeExcludeFilter(k.AnnotationInclude, k.AnnotationExclude)
+		if err != nil {
+			return err
+		}
+	}
+
 	var wg sync.WaitGroup
 	errChan := errchan.New(1)
 	wg.Add(1)
@@ -92,8 +138,49 @@ func buildURL(endpoint string, base string) (*url.URL, error) {
 }
 
 func (k *Kubernetes) gatherSummary(baseURL string, acc telegraf.Accumulator) error {
-	url := fmt.Sprintf("%s/stats/summary", baseURL)
+	summaryMetrics := &SummaryMetrics{}
+	err := k.loadJSON(fmt.Sprintf(summaryEndpoint, baseURL), summaryMetrics)
+	if err != nil {
+		return err
+	}
+
+	// Pod metadata and status are supplementary, so a failure to get them
+	// should not prevent the usage metrics from being reported.
+	podInfos := make(map[string]*Pod)
+	pods := &Pods{}
+	err = k.loadJSON(fmt.Sprintf(podsEndpoint, baseURL), pods)
+	if err != nil {
+		acc.AddError(err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		podInfos[pod.Metadata.Namespace+"/"+pod.Metadata.Name] = pod
+	}
+
+	var nodeLabels map[string]string
+	if k.APIURL != "" {
+		node := &Node{}
+		err = k.loadJSON(fmt.Sprintf(nodeEndpoint, k.APIURL,
+			summaryMetrics.Node.NodeName), node)
+		if err != nil {
+			acc.AddError(err)
+		}
+		nodeLabels = node.Metadata.Labels
+	}
+
+	buildSystemContainerMetrics(summaryMetrics, acc)
+	k.buildNodeMetrics(summaryMetrics, nodeLabels, acc)
+	k.buildPodMetrics(summaryMetrics, podInfos, acc)
+	return nil
+}
+
+// loadJSON requests the given url from the kubelet or API server and decodes
+// the JSON response body into v.
+func (k *Kubernetes) loadJSON(url string, v interface{}) error {
 	var req, err = http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
 	var token []byte
 	var resp *http.Response
 
@@ -115,7 +202,7 @@ func (k *Kubernetes) gatherSummary(baseURL string, acc telegraf.Accumulator) err
 		if err != nil {
 			return err
 		}
-		req.Header.Set("Authorization", "Bearer "+string(token))
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
 	}
 
 	resp, err = k.RoundTripper.RoundTrip(req)
@@ -128,14 +215,10 @@ func (k *Kubernetes) gatherSummary(baseURL string, acc telegraf.Accumulator) err
 		return fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
 	}
 
-	summaryMetrics := &SummaryMetrics{}
-	err = json.NewDecoder(resp.Body).Decode(summaryMetrics)
+	err = json.NewDecoder(resp.Body).Decode(v)
 	if err != nil {
 		return fmt.Errorf(`Error parsing response: %s`, err)
 	}
-	buildSystemContainerMetrics(summaryMetrics, acc)
-	buildNodeMetrics(summaryMetrics, acc)
-	buildPodMetrics(summaryMetrics, acc)
 	return nil
 }
 
@@ -161,10 +244,15 @@ func buildSystemContainerMetrics(summaryMetrics *SummaryMetrics, acc telegraf.Ac
 	}
 }
 
-func buildNodeMetrics(summaryMetrics *SummaryMetrics, acc telegraf.Accumulator) {
+func (k *Kubernetes) buildNodeMetrics(
+	summaryMetrics *SummaryMetrics,
+	nodeLabels map[string]string,
+	acc telegraf.Accumulator,
+) {
 	tags := map[string]string{
 		"node_name": summaryMetrics.Node.NodeName,
 	}
+	k.labelFilter.addTags("label_", nodeLabels, tags)
 	fields := make(map[string]interface{})
 	fields["cpu_usage_nanocores"] = summaryMetrics.Node.CPU.UsageNanoCores
 	fields["cpu_usage_core_nanoseconds"] = summaryMetrics.Node.CPU.UsageCoreNanoSeconds
@@ -187,15 +275,28 @@ func buildNodeMetrics(summaryMetrics *SummaryMetrics, acc telegraf.Accumulator)
 	acc.AddFields("kubernetes_node", fields, tags)
 }
 
-func buildPodMetrics(summaryMetrics *SummaryMetrics, acc telegraf.Accumulator) {
+func (k *Kubernetes) buildPodMetrics(
+	summaryMetrics *SummaryMetrics,
+	podInfos map[string]*Pod,
+	acc telegraf.Accumulator,
+) {
 	for _, pod := range summaryMetrics.Pods {
+		podInfo := podInfos[pod.PodRef.Namespace+"/"+pod.PodRef.Name]
+
+		// podTags are shared by all the measurements of the pod
+		podTags := map[string]string{
+			"node_name": summaryMetrics.Node.NodeName,
+			"pod_name":  pod.PodRef.Name,
+			"namespace": pod.PodRef.Namespace,
+		}
+		if podInfo != nil {
+			k.labelFilter.addTags("label_", podInfo.Metadata.Labels, podTags)
+			k.annotationFilter.addTags("annotation_", podInfo.Metadata.Annotations, podTags)
+		}
+
 		for _, container := range pod.Containers {
-			tags := map[string]string{
-				"node_name":      summaryMetrics.Node.NodeName,
-				"namespace":      pod.PodRef.Namespace,
-				"container_name": container.Name,
-				"pod_name":       pod.PodRef.Name,
-			}
+			tags := copyTags(podTags)
+			tags["container_name"] = container.Name
 			fields := make(map[string]interface{})
 			fields["cpu_usage_nanocores"] = container.CPU.UsageNanoCores
 			fields["cpu_usage_core_nanoseconds"] = container.CPU.UsageCoreNanoSeconds
@@ -210,16 +311,16 @@ func buildPodMetrics(summaryMetrics *SummaryMetrics, acc telegraf.Accumulator) {
 			fields["logsfs_avaialble_bytes"] = container.LogsFS.AvailableBytes
 			fields["logsfs_capacity_bytes"] = container.LogsFS.CapacityBytes
 			fields["logsfs_used_bytes"] = container.LogsFS.UsedBytes
+			if podInfo != nil {
+				tags["phase"] = strings.ToLower(podInfo.Status.Phase)
+				k.addContainerStatus(podInfo, &container, fields)
+			}
 			acc.AddFields("kubernetes_pod_container", fields, tags)
 		}
 
 		for _, volume := range pod.Volumes {
-			tags := map[string]string{
-				"node_name":   summaryMetrics.Node.NodeName,
-				"pod_name":    pod.PodRef.Name,
-				"namespace":   pod.PodRef.Namespace,
-				"volume_name": volume.Name,
-			}
+			tags := copyTags(podTags)
+			tags["volume_name"] = volume.Name
 			fields := make(map[string]interface{})
 			fields["available_bytes"] = volume.AvailableBytes
 			fields["capacity_bytes"] = volume.CapacityBytes
@@ -227,16 +328,122 @@ func buildPodMetrics(summaryMetrics *SummaryMetrics, acc telegraf.Accumulator) {
 			acc.AddFields("kubernetes_pod_volume", fields, tags)
 		}
 
-		tags := map[string]string{
-			"node_name": summaryMetrics.Node.NodeName,
-			"pod_name":  pod.PodRef.Name,
-			"namespace": pod.PodRef.Namespace,
-		}
 		fields := make(map[string]interface{})
 		fields["rx_bytes"] = pod.Network.RXBytes
 		fields["rx_errors"] = pod.Network.RXErrors
 		fields["tx_bytes"] = pod.Network.TXBytes
 		fields["tx_errors"] = pod.Network.TXErrors
-		acc.AddFields("kubernetes_pod_network", fields, tags)
+		acc.AddFields("kubernetes_pod_network", fields, podTags)
+	}
+}
+
+// addContainerStatus adds the restart count, readiness, state and resource
+// requests & limits of the given container, as reported by the kubelet's
+// /pods endpoint, to fields.
+func (k *Kubernetes) addContainerStatus(
+	podInfo *Pod,
+	container *ContainerMetrics,
+	fields map[string]interface{},
+) {
+	for _, status := range podInfo.Status.ContainerStatuses {
+		if status.Name != container.Name {
+			continue
+		}
+		fields["restarts_total"] = status.RestartCount
+		if status.Ready {
+			fields["ready"] = int64(1)
+		} else {
+			fields["ready"] = int64(0)
+		}
+		switch {
+		case status.State.Running != nil:
+			fields["state"] = "running"
+		case status.State.Waiting != nil:
+			fields["state"] = "waiting"
+			fields["state_reason"] = status.State.Waiting.Reason
+		case status.State.Terminated != nil:
+			fields["state"] = "terminated"
+			fields["state_reason"] = status.State.Terminated.Reason
+			fields["exit_code"] = status.State.Terminated.ExitCode
+		}
+	}
+
+	for _, spec := range podInfo.Spec.Containers {
+		if spec.Name != container.Name {
+			continue
+		}
+		resources := []struct {
+			name       string
+			quantities map[string]string
+		}{
+			{"request", spec.Resources.Requests},
+			{"limit", spec.Resources.Limits},
+		}
+		for _, r := range resources {
+			if q, ok := r.quantities["cpu"]; ok {
+				if cores, err := parseQuantity(q); err == nil {
+					fields["resource_"+r.name+"s_millicpu_units"] = int64(cores*1000 + 0.5)
+					if k.ResourceUsageRatios && cores > 0 {
+						fields["cpu_usage_"+r.name+"_ratio"] =
+							float64(container.CPU.UsageNanoCores) / (cores * 1e9)
+					}
+				}
+			}
+			if q, ok := r.quantities["memory"]; ok {
+				if bytes, err := parseQuantity(q); err == nil {
+					fields["resource_"+r.name+"s_memory_bytes"] = int64(bytes + 0.5)
+					if k.ResourceUsageRatios && bytes > 0 {
+						fields["memory_usage_"+r.name+"_ratio"] =
+							float64(container.Memory.WorkingSetBytes) / bytes
+					}
+				}
+			}
+		}
+	}
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	out := make(map[string]string, len(tags))
+	for k, v := range tags {
+		out[k] = v
+	}
+	return out
+}
+
+// includeExcludeFilter selects which labels or annotations become tags.
+// Nothing is selected unless it matches an include pattern.
+type includeExcludeFilter struct {
+	include filter.Filter
+	exclude filter.Filter
+}
+
+func newIncludeExcludeFilter(include, exclude []string) (*includeExcludeFilter, error) {
+	var err error
+	f := &includeExcludeFilter{}
+	if f.include, err = filter.Compile(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = filter.Compile(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *includeExcludeFilter) addTags(
+	prefix string,
+	in map[string]string,
+	tags map[string]string,
+) {
+	if f == nil || f.include == nil {
+		return
+	}
+	for k, v := range in {
+		if !f.include.Match(k) {
+			continue
+		}
+		if f.exclude != nil && f.exclude.Match(k) {
+			continue
+		}
+		tags[prefix+k] = v
 	}
 }
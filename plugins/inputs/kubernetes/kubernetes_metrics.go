@@ -1,6 +1,12 @@
 package kubernetes
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // SummaryMetrics represents all the summary data about a paritcular node retrieved from a kubelet
 type SummaryMetrics struct {
@@ -91,3 +97,144 @@ type VolumeMetrics struct {
 	CapacityBytes  int64  `json:"capacityBytes"`
 	UsedBytes      int64  `json:"usedBytes"`
 }
+
+// Pods represents the list of pods running on a node, as returned by the
+// kubelet /pods endpoint
+type Pods struct {
+	Items []Pod `json:"items"`
+}
+
+// Pod contains the metadata, spec and status of a pod
+type Pod struct {
+	Metadata ObjectMetadata `json:"metadata"`
+	Spec     PodSpec        `json:"spec"`
+	Status   PodStatus      `json:"status"`
+}
+
+// Node contains the metadata of a node, as returned by the API server
+type Node struct {
+	Metadata ObjectMetadata `json:"metadata"`
+}
+
+// ObjectMetadata is the identifying information of a pod or node
+type ObjectMetadata struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// PodSpec contains the containers of a pod
+type PodSpec struct {
+	NodeName   string          `json:"nodeName"`
+	Containers []ContainerSpec `json:"containers"`
+}
+
+// ContainerSpec contains the resource requirements of a container
+type ContainerSpec struct {
+	Name      string               `json:"name"`
+	Resources ResourceRequirements `json:"resources"`
+}
+
+// ResourceRequirements are the resource requests & limits of a container,
+// as kubernetes quantities, ie "250m" or "64Mi"
+type ResourceRequirements struct {
+	Requests map[string]string `json:"requests"`
+	Limits   map[string]string `json:"limits"`
+}
+
+// PodStatus contains the phase of a pod and the status of its containers
+type PodStatus struct {
+	Phase             string            `json:"phase"`
+	ContainerStatuses []ContainerStatus `json:"containerStatuses"`
+}
+
+// ContainerStatus represents the restart count, readiness and state of a
+// container
+type ContainerStatus struct {
+	Name         string         `json:"name"`
+	Ready        bool           `json:"ready"`
+	RestartCount int64          `json:"restartCount"`
+	State        ContainerState `json:"state"`
+}
+
+// ContainerState has exactly one of its members set
+type ContainerState struct {
+	Running    *ContainerStateRunning    `json:"running"`
+	Waiting    *ContainerStateWaiting    `json:"waiting"`
+	Terminated *ContainerStateTerminated `json:"terminated"`
+}
+
+// ContainerStateRunning is the state of a running container
+type ContainerStateRunning struct {
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// ContainerStateWaiting is the state of a container that has not started yet
+type ContainerStateWaiting struct {
+	Reason string `json:"reason"`
+}
+
+// ContainerStateTerminated is the state of a container that has exited
+type ContainerStateTerminated struct {
+	ExitCode int64  `json:"exitCode"`
+	Reason   string `json:"reason"`
+}
+
+// decimalSuffixes maps the decimal suffixes of a quantity to their exponent
+var decimalSuffixes = map[string]int{
+	"n": -9,
+	"u": -6,
+	"m": -3,
+	"":  0,
+	"k": 3,
+	"M": 6,
+	"G": 9,
+	"T": 12,
+	"P": 15,
+	"E": 18,
+}
+
+// binarySuffixes maps the binary suffixes of a quantity to their multiplier
+var binarySuffixes = map[string]float64{
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// parseQuantity converts a kubernetes resource quantity, ie "100m", "1.5",
+// "128Mi" or "1e3", into a float.
+func parseQuantity(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '+' && r != '-'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	number, suffix := s[:i], s[i:]
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %s", s, err)
+	}
+
+	if exp, ok := decimalSuffixes[suffix]; ok {
+		// dividing by an exact power of ten keeps "100m" at exactly 0.1
+		if exp < 0 {
+			return value / math.Pow10(-exp), nil
+		}
+		return value * math.Pow10(exp), nil
+	}
+	if multiplier, ok := binarySuffixes[suffix]; ok {
+		return value * multiplier, nil
+	}
+	// decimal exponent, ie "1e3" or "1E3"
+	if len(suffix) > 1 && (suffix[0] == 'e' || suffix[0] == 'E') {
+		return strconv.ParseFloat(s, 64)
+	}
+	return 0, fmt.Errorf("invalid quantity suffix in %q", s)
+}
@@ -120,6 +120,192 @@ func TestKubernetesStats(t *testing.T) {
 
 }
 
+func TestKubernetesPodStatus(t *testing.T) {
+	ts := newKubeletServer()
+	defer ts.Close()
+
+	k := &Kubernetes{
+		URL:               ts.URL,
+		APIURL:            ts.URL,
+		LabelInclude:      []string{"*"},
+		LabelExclude:      []string{"pod-template-hash"},
+		AnnotationInclude: []string{"team"},
+	}
+
+	var acc testutil.Accumulator
+	err := k.Gather(&acc)
+	require.NoError(t, err)
+
+	fields := map[string]interface{}{
+		"cpu_usage_nanocores":              int64(846503),
+		"cpu_usage_core_nanoseconds":       int64(56507553554),
+		"memory_usage_bytes":               int64(30789632),
+		"memory_working_set_bytes":         int64(30789632),
+		"memory_rss_bytes":                 int64(30695424),
+		"memory_page_faults":               int64(10761),
+		"memory_major_page_faults":         int64(0),
+		"rootfs_available_bytes":           int64(84379979776),
+		"rootfs_capacity_bytes":            int64(105553100800),
+		"rootfs_used_bytes":                int64(57344),
+		"logsfs_avaialble_bytes":           int64(84379979776),
+		"logsfs_capacity_bytes":            int64(105553100800),
+		"logsfs_used_bytes":                int64(24576),
+		"restarts_total":                   int64(3),
+		"ready":                            int64(1),
+		"state":                            "running",
+		"resource_requests_millicpu_units": int64(100),
+		"resource_requests_memory_bytes":   int64(67108864),
+		"resource_limits_millicpu_units":   int64(500),
+		"resource_limits_memory_bytes":     int64(134217728),
+	}
+	tags := map[string]string{
+		"node_name":       "node1",
+		"container_name":  "foocontainer",
+		"namespace":       "foons",
+		"pod_name":        "foopod",
+		"phase":           "running",
+		"label_app":       "foo",
+		"annotation_team": "bar",
+	}
+	acc.AssertContainsTaggedFields(t, "kubernetes_pod_container", fields, tags)
+
+	m, ok := acc.Get("kubernetes_pod_network")
+	require.True(t, ok)
+	require.Equal(t, "foo", m.Tags["label_app"])
+	require.NotContains(t, m.Tags, "label_pod-template-hash")
+	require.NotContains(t, m.Tags, "annotation_kubernetes.io/created-by")
+
+	m, ok = acc.Get("kubernetes_node")
+	require.True(t, ok)
+	require.Equal(t, "us-east-1a", m.Tags["label_zone"])
+
+	k.ResourceUsageRatios = true
+	var ratioAcc testutil.Accumulator
+	err = k.Gather(&ratioAcc)
+	require.NoError(t, err)
+
+	m, ok = ratioAcc.Get("kubernetes_pod_container")
+	require.True(t, ok)
+	require.InDelta(t, 0.00846503, m.Fields["cpu_usage_request_ratio"], 1e-9)
+	require.InDelta(t, 0.001693006, m.Fields["cpu_usage_limit_ratio"], 1e-9)
+	require.InDelta(t, 0.458801270, m.Fields["memory_usage_request_ratio"], 1e-9)
+	require.InDelta(t, 0.229400635, m.Fields["memory_usage_limit_ratio"], 1e-9)
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		quantity string
+		expected float64
+	}{
+		{"100m", 0.1},
+		{"2", 2},
+		{"1.5", 1.5},
+		{"64Mi", 67108864},
+		{"1G", 1e9},
+		{"1e3", 1000},
+		{"1Ei", 1 << 60},
+	}
+	for _, tt := range tests {
+		v, err := parseQuantity(tt.quantity)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, v, tt.quantity)
+	}
+
+	_, err := parseQuantity("12xyz")
+	require.Error(t, err)
+}
+
+// newKubeletServer returns a local stand-in for the kubelet and API server,
+// replying with recorded responses.
+func newKubeletServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stats/summary":
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, response)
+		case "/pods":
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, podsResponse)
+		case "/api/v1/nodes/node1":
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, nodeResponse)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+var podsResponse = `
+{
+  "kind": "PodList",
+  "apiVersion": "v1",
+  "metadata": {},
+  "items": [
+    {
+      "metadata": {
+        "name": "foopod",
+        "namespace": "foons",
+        "uid": "6d305b06-8419-11e6-825c-42010af000ae",
+        "labels": {
+          "app": "foo",
+          "pod-template-hash": "3058870187"
+        },
+        "annotations": {
+          "team": "bar",
+          "kubernetes.io/created-by": "{}"
+        }
+      },
+      "spec": {
+        "nodeName": "node1",
+        "containers": [
+          {
+            "name": "foocontainer",
+            "image": "foo:latest",
+            "resources": {
+              "requests": {
+                "cpu": "100m",
+                "memory": "64Mi"
+              },
+              "limits": {
+                "cpu": "500m",
+                "memory": "128Mi"
+              }
+            }
+          }
+        ]
+      },
+      "status": {
+        "phase": "Running",
+        "containerStatuses": [
+          {
+            "name": "foocontainer",
+            "state": {
+              "running": {
+                "startedAt": "2016-09-26T18:46:43Z"
+              }
+            },
+            "ready": true,
+            "restartCount": 3
+          }
+        ]
+      }
+    }
+  ]
+}`
+
+var nodeResponse = `
+{
+  "kind": "Node",
+  "apiVersion": "v1",
+  "metadata": {
+    "name": "node1",
+    "labels": {
+      "kubernetes.io/hostname": "node1",
+      "zone": "us-east-1a"
+    }
+  }
+}`
+
 var response = `
 {
   "node": {
@@ -80,16 +80,18 @@ func (l *LogParserPlugin) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
-func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
+// Init compiles the patterns of the parsers, so that invalid patterns are
+// reported when telegraf starts.
+func (l *LogParserPlugin) Init() error {
 	l.Lock()
 	defer l.Unlock()
+	return l.initParsers()
+}
 
-	l.acc = acc
-	l.lines = make(chan string, 1000)
-	l.done = make(chan struct{})
-
+// initParsers looks for the configured parsers and compiles them.
+func (l *LogParserPlugin) initParsers() error {
 	// Looks for fields which implement LogParser interface
-	l.parsers = []LogParser{}
+	var parsers []LogParser
 	s := reflect.ValueOf(l).Elem()
 	for i := 0; i < s.NumField(); i++ {
 		f := s.Field(i)
@@ -102,17 +104,17 @@ func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
 			if reflect.ValueOf(lpPlugin).IsNil() {
 				continue
 			}
-			l.parsers = append(l.parsers, lpPlugin)
+			parsers = append(parsers, lpPlugin)
 		}
 	}
 
-	if len(l.parsers) == 0 {
+	if len(parsers) == 0 {
 		return fmt.Errorf("ERROR: logparser input plugin: no parser defined.")
 	}
 
 	// compile log parser patterns:
-	errChan := errchan.New(len(l.parsers))
-	for _, parser := range l.parsers {
+	errChan := errchan.New(len(parsers))
+	for _, parser := range parsers {
 		if err := parser.Compile(); err != nil {
 			errChan.C <- err
 		}
@@ -121,6 +123,26 @@ func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	l.parsers = parsers
+	return nil
+}
+
+func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
+	l.Lock()
+	defer l.Unlock()
+
+	l.acc = acc
+	l.lines = make(chan string, 1000)
+	l.done = make(chan struct{})
+
+	// parsers are compiled only once, as compiling them again would add
+	// their custom patterns again.
+	if l.parsers == nil {
+		if err := l.initParsers(); err != nil {
+			return err
+		}
+	}
+
 	var seek tail.SeekInfo
 	if !l.FromBeginning {
 		seek.Whence = 2
@@ -131,6 +153,7 @@ func (l *LogParserPlugin) Start(acc telegraf.Accumulator) error {
 	go l.parser()
 
 	// Create a "tailer" for each file
+	errChan := errchan.New(0)
 	for _, filepath := range l.Files {
 		g, err := globpath.Compile(filepath)
 		if err != nil {
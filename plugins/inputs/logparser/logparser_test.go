@@ -117,3 +117,18 @@ func getCurrentDir() string {
 	_, filename, _, _ := runtime.Caller(1)
 	return strings.Replace(filename, "logparser_test.go", "", 1)
 }
+
+func TestInitInvalidPattern(t *testing.T) {
+	logparser := &LogParserPlugin{
+		Files: []string{"grok/testdata/*.log"},
+		GrokParser: &grok.Parser{
+			Patterns: []string{"%{FOOBAR}"},
+		},
+	}
+	assert.Error(t, logparser.Init())
+
+	logparser = &LogParserPlugin{
+		Files: []string{"grok/testdata/*.log"},
+	}
+	assert.Error(t, logparser.Init())
+}
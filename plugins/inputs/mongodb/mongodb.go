@@ -47,6 +47,12 @@ func (*MongoDB) Description() string {
 
 var localhost = &url.URL{Host: "127.0.0.1:27017"}
 
+// GathersDeltas reports that the operation rates are computed from the
+// server status of the previous gather.
+func (*MongoDB) GathersDeltas() bool {
+	return true
+}
+
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (m *MongoDB) Gather(acc telegraf.Accumulator) error {
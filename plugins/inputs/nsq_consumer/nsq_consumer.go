@@ -1,7 +1,7 @@
 package nsq_consumer
 
 import (
-	"log"
+	"sync"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/inputs"
@@ -17,7 +17,14 @@ type NSQConsumer struct {
 	MaxInFlight int
 	parser      parsers.Parser
 	consumer    *nsq.Consumer
-	acc         telegraf.Accumulator
+	acc         telegraf.TrackingAccumulator
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	// messages whose metrics are not delivered yet, by tracking id
+	messages map[telegraf.TrackingID]*nsq.Message
+	done     chan struct{}
 }
 
 var sampleConfig = `
@@ -55,29 +62,63 @@ func (n *NSQConsumer) Description() string {
 	return "Read NSQ topic for metrics."
 }
 
-// Start pulls data from nsq
+// Start pulls data from nsq. A message is finished once its metrics have
+// been written by the outputs, and requeued when they were dropped, so that
+// it is not lost if telegraf stops before writing them. At most
+// max_in_flight messages are read ahead of the outputs.
 func (n *NSQConsumer) Start(acc telegraf.Accumulator) error {
-	n.acc = acc
+	n.acc = acc.WithTracking(n.MaxInFlight)
+	n.messages = make(map[telegraf.TrackingID]*nsq.Message)
+	n.done = make(chan struct{})
+	go n.onDelivery()
+
 	n.connect()
 	n.consumer.AddConcurrentHandlers(nsq.HandlerFunc(func(message *nsq.Message) error {
 		metrics, err := n.parser.Parse(message.Body)
 		if err != nil {
-			log.Printf("E! NSQConsumer Parse Error\nmessage:%s\nerror:%s", string(message.Body), err.Error())
+			n.Log.Errorf("Parse error\nmessage:%s\nerror:%s", string(message.Body), err.Error())
 			return nil
 		}
-		for _, metric := range metrics {
-			n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-		}
-		message.Finish()
+		message.DisableAutoResponse()
+
+		n.Lock()
+		defer n.Unlock()
+		id := n.acc.AddTrackingMetricGroup(metrics)
+		n.messages[id] = message
 		return nil
 	}), n.MaxInFlight)
 	n.consumer.ConnectToNSQD(n.Server)
 	return nil
 }
 
+// onDelivery finishes or requeues the messages whose metrics are delivered.
+func (n *NSQConsumer) onDelivery() {
+	for {
+		select {
+		case <-n.done:
+			return
+		case info := <-n.acc.Delivered():
+			n.Lock()
+			message, ok := n.messages[info.ID()]
+			delete(n.messages, info.ID())
+			n.Unlock()
+			if !ok {
+				continue
+			}
+
+			if info.Delivered() {
+				message.Finish()
+			} else {
+				message.Requeue(-1)
+			}
+		}
+	}
+}
+
 // Stop processing messages
 func (n *NSQConsumer) Stop() {
 	n.consumer.Stop()
+	close(n.done)
 }
 
 // Gather is a noop
@@ -40,6 +40,7 @@ func TestReadsMetricsFromNSQ(t *testing.T) {
 		Topic:       "telegraf",
 		Channel:     "consume",
 		MaxInFlight: 1,
+		Log:         testutil.Logger{Name: "inputs.nsq_consumer"},
 	}
 
 	p, _ := parsers.NewInfluxParser()
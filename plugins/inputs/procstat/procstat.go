@@ -63,6 +63,12 @@ func (_ *Procstat) Description() string {
 	return "Monitor process cpu and memory usage"
 }
 
+// GathersDeltas reports that the cpu usage is computed from the cpu times of
+// the previous gather.
+func (_ *Procstat) GathersDeltas() bool {
+	return true
+}
+
 func (p *Procstat) Gather(acc telegraf.Accumulator) error {
 	err := p.createProcesses()
 	if err != nil {
@@ -0,0 +1,216 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/globpath"
+	"gopkg.in/yaml.v2"
+)
+
+// target is a url to scrape, along with the tags to add to its metrics.
+type target struct {
+	url  string
+	tags map[string]string
+}
+
+// targetGroup is a group of targets in the Prometheus file_sd format, ie:
+//
+//   [{"targets": ["host1:9100", "host2:9100"], "labels": {"env": "prod"}}]
+//
+// The special labels __scheme__ and __metrics_path__ set the scheme and path
+// of the url of each target. Any other label beginning with "__" is ignored.
+type targetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+}
+
+// sdFile is a service discovery file along with the targets last loaded
+// from it.
+type sdFile struct {
+	modTime time.Time
+	size    int64
+	targets []target
+}
+
+// fileDiscovery keeps track of the targets in the service discovery files
+// matching a set of globs, reloading each file when it changes.
+type fileDiscovery struct {
+	globs []*globpath.GlobPath
+	files map[string]*sdFile
+}
+
+func newFileDiscovery(patterns []string) (*fileDiscovery, error) {
+	d := &fileDiscovery{files: make(map[string]*sdFile)}
+	for _, pattern := range patterns {
+		g, err := globpath.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile glob %s: %s", pattern, err)
+		}
+		d.globs = append(d.globs, g)
+	}
+	return d, nil
+}
+
+// Targets returns the targets of all the matching files. Files that were
+// added or modified since the last call are (re)loaded, and files that were
+// removed are forgotten. If a file fails to load, the targets previously
+// loaded from it are kept and the error is returned.
+func (d *fileDiscovery) Targets() ([]target, error) {
+	var loadErr error
+	seen := make(map[string]bool)
+	for _, g := range d.globs {
+		for path, info := range g.Match() {
+			if info == nil || info.IsDir() {
+				continue
+			}
+			seen[path] = true
+
+			f, ok := d.files[path]
+			if ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+				continue
+			}
+			targets, err := loadTargetFile(path)
+			if err != nil {
+				loadErr = err
+				continue
+			}
+			log.Printf("D! prometheus: loaded %d targets from %s", len(targets), path)
+			d.files[path] = &sdFile{
+				modTime: info.ModTime(),
+				size:    info.Size(),
+				targets: targets,
+			}
+		}
+	}
+
+	var paths []string
+	for path := range d.files {
+		if !seen[path] {
+			delete(d.files, path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var targets []target
+	for _, path := range paths {
+		targets = append(targets, d.files[path].targets...)
+	}
+	return targets, loadErr
+}
+
+// loadTargetFile reads the target groups from a JSON or YAML file.
+func loadTargetFile(path string) ([]target, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []targetGroup
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &groups)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(content, &groups)
+	default:
+		return nil, fmt.Errorf("unknown service discovery file format %s, "+
+			"expected .json, .yml or .yaml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", path, err)
+	}
+
+	var targets []target
+	for _, group := range groups {
+		scheme := "http"
+		metricsPath := "/metrics"
+		tags := make(map[string]string)
+		for k, v := range group.Labels {
+			switch {
+			case k == "__scheme__":
+				scheme = v
+			case k == "__metrics_path__":
+				metricsPath = v
+			case strings.HasPrefix(k, "__"):
+			default:
+				tags[k] = v
+			}
+		}
+
+		for _, address := range group.Targets {
+			u := &url.URL{Scheme: scheme, Host: address, Path: metricsPath}
+			targets = append(targets, target{url: u.String(), tags: tags})
+		}
+	}
+	return targets, nil
+}
+
+// kubeletPods is the subset of the kubelet /pods response needed to discover
+// scrape targets.
+type kubeletPods struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+			PodIP string `json:"podIP"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// podTargets returns a target for each running pod with the
+// prometheus.io/scrape annotation set to "true". The prometheus.io/scheme,
+// prometheus.io/port and prometheus.io/path annotations override the
+// defaults of http, 9102 and /metrics.
+func podTargets(pods *kubeletPods) []target {
+	var targets []target
+	for _, pod := range pods.Items {
+		annotations := pod.Metadata.Annotations
+		if annotations["prometheus.io/scrape"] != "true" {
+			continue
+		}
+		if pod.Status.Phase != "Running" || pod.Status.PodIP == "" {
+			continue
+		}
+
+		scheme := "http"
+		if v, ok := annotations["prometheus.io/scheme"]; ok {
+			scheme = v
+		}
+		port := "9102"
+		if v, ok := annotations["prometheus.io/port"]; ok {
+			port = v
+		}
+		metricsPath := "/metrics"
+		if v, ok := annotations["prometheus.io/path"]; ok {
+			metricsPath = v
+		}
+
+		u := &url.URL{
+			Scheme: scheme,
+			Host:   net.JoinHostPort(pod.Status.PodIP, port),
+			Path:   metricsPath,
+		}
+		targets = append(targets, target{
+			url: u.String(),
+			tags: map[string]string{
+				"pod_name":  pod.Metadata.Name,
+				"namespace": pod.Metadata.Namespace,
+			},
+		})
+	}
+	return targets
+}
@@ -1,16 +1,20 @@
 package prometheus
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/influxdata/telegraf"
-	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/plugins/inputs"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/errchan"
+	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 const acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3`
@@ -18,6 +22,12 @@ const acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client
 type Prometheus struct {
 	Urls []string
 
+	// Service discovery files in the Prometheus file_sd format
+	FileSDFiles []string `toml:"file_sd_files"`
+
+	// Kubelet to discover pods with the prometheus.io/scrape annotation from
+	KubeletURL string `toml:"kubelet_url"`
+
 	// Bearer Token authorization file path
 	BearerToken string `toml:"bearer_token"`
 
@@ -29,12 +39,24 @@ type Prometheus struct {
 	SSLKey string `toml:"ssl_key"`
 	// Use SSL but skip chain & host verification
 	InsecureSkipVerify bool
+
+	fileDiscovery *fileDiscovery
 }
 
 var sampleConfig = `
   ## An array of urls to scrape metrics from.
   urls = ["http://localhost:9100/metrics"]
 
+  ## Files to discover targets from, in the Prometheus file_sd format.
+  ## JSON (.json) and YAML (.yml, .yaml) files are supported, globs accepted.
+  ## Files are reloaded when they change, and target labels become tags.
+  # file_sd_files = ["/etc/telegraf/targets/*.json"]
+
+  ## Kubelet to discover pods annotated with prometheus.io/scrape = "true"
+  ## from. The prometheus.io/scheme, prometheus.io/port & prometheus.io/path
+  ## annotations set the url to scrape.
+  # kubelet_url = "http://1.1.1.1:10255"
+
   ## Use bearer token for authorization
   # bearer_token = /path/to/bearer/token
 
@@ -59,21 +81,78 @@ var ErrProtocolError = errors.New("prometheus protocol error")
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
-	var wg sync.WaitGroup
-
-	var outerr error
+	targets, err := p.targets()
+	if err != nil {
+		// a discovery error still leaves the remaining targets to scrape
+		acc.AddError(err)
+	}
 
-	for _, serv := range p.Urls {
+	var wg sync.WaitGroup
+	errChan := errchan.New(len(targets))
+	for _, t := range targets {
 		wg.Add(1)
-		go func(serv string) {
+		go func(t target) {
 			defer wg.Done()
-			outerr = p.gatherURL(serv, acc)
-		}(serv)
+			errChan.C <- p.gatherURL(t, acc)
+		}(t)
 	}
 
 	wg.Wait()
+	return errChan.Error()
+}
+
+// targets returns the configured urls plus any discovered targets.
+func (p *Prometheus) targets() ([]target, error) {
+	var targets []target
+	for _, u := range p.Urls {
+		targets = append(targets, target{url: u})
+	}
+
+	var errs []string
+	if len(p.FileSDFiles) > 0 {
+		if p.fileDiscovery == nil {
+			d, err := newFileDiscovery(p.FileSDFiles)
+			if err != nil {
+				return targets, err
+			}
+			p.fileDiscovery = d
+		}
+		discovered, err := p.fileDiscovery.Targets()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		targets = append(targets, discovered...)
+	}
 
-	return outerr
+	if p.KubeletURL != "" {
+		discovered, err := p.discoverPods()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		targets = append(targets, discovered...)
+	}
+
+	if len(errs) > 0 {
+		return targets, fmt.Errorf("service discovery failed: %s",
+			strings.Join(errs, ", "))
+	}
+	return targets, nil
+}
+
+// discoverPods returns the targets of the annotated pods on the kubelet.
+func (p *Prometheus) discoverPods() ([]target, error) {
+	url := strings.TrimSuffix(p.KubeletURL, "/") + "/pods"
+	resp, err := p.get(url, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	pods := &kubeletPods{}
+	if err := json.NewDecoder(resp.Body).Decode(pods); err != nil {
+		return nil, fmt.Errorf("error parsing pods from %s: %s", url, err)
+	}
+	return podTargets(pods), nil
 }
 
 var tr = &http.Transport{
@@ -85,17 +164,21 @@ var client = &http.Client{
 	Timeout:   time.Duration(4 * time.Second),
 }
 
-func (p *Prometheus) gatherURL(url string, acc telegraf.Accumulator) error {
-	collectDate := time.Now()
+// get makes a GET request to the given url with the configured SSL and
+// bearer token settings, returning an error for any non-200 response.
+func (p *Prometheus) get(url string, accept string) (*http.Response, error) {
 	var req, err = http.NewRequest("GET", url, nil)
-	req.Header.Add("Accept", acceptHeader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", accept)
 	var token []byte
 	var resp *http.Response
 
 	tlsCfg, err := internal.GetTLSConfig(
 		p.SSLCert, p.SSLKey, p.SSLCA, p.InsecureSkipVerify)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var rt http.RoundTripper = &http.Transport{
@@ -112,19 +195,29 @@ func (p *Prometheus) gatherURL(url string, acc telegraf.Accumulator) error {
 	if p.BearerToken != "" {
 		token, err = ioutil.ReadFile(p.BearerToken)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		req.Header.Set("Authorization", "Bearer "+string(token))
 	}
 
 	resp, err = rt.RoundTrip(req)
 	if err != nil {
-		return fmt.Errorf("error making HTTP request to %s: %s", url, err)
+		return nil, fmt.Errorf("error making HTTP request to %s: %s", url, err)
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+func (p *Prometheus) gatherURL(t target, acc telegraf.Accumulator) error {
+	collectDate := time.Now()
+	resp, err := p.get(t.url, acceptHeader)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -134,12 +227,15 @@ func (p *Prometheus) gatherURL(url string, acc telegraf.Accumulator) error {
 	metrics, err := Parse(body, resp.Header)
 	if err != nil {
 		return fmt.Errorf("error reading metrics for %s: %s",
-			url, err)
+			t.url, err)
 	}
 	// Add (or not) collected metrics
 	for _, metric := range metrics {
 		tags := metric.Tags()
-		tags["url"] = url
+		for k, v := range t.tags {
+			tags[k] = v
+		}
+		tags["url"] = t.url
 		acc.AddFields(metric.Name(), metric.Fields(), tags, collectDate)
 	}
 
@@ -2,9 +2,14 @@ package prometheus
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
@@ -43,3 +48,127 @@ func TestPrometheusGeneratesMetrics(t *testing.T) {
 	assert.True(t, acc.HasFloatField("go_gc_duration_seconds", "count"))
 	assert.True(t, acc.HasFloatField("go_goroutines", "gauge"))
 }
+
+func TestPrometheusFileDiscovery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+	address := strings.TrimPrefix(ts.URL, "http://")
+
+	dir, err := ioutil.TempDir("", "prometheus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sdFile := filepath.Join(dir, "targets.json")
+	err = ioutil.WriteFile(sdFile, []byte(`[{
+		"targets": ["`+address+`"],
+		"labels": {"env": "prod", "__metrics_path__": "/custom"}
+	}]`), 0644)
+	require.NoError(t, err)
+
+	p := &Prometheus{
+		FileSDFiles: []string{filepath.Join(dir, "*.json")},
+	}
+
+	var acc testutil.Accumulator
+	err = p.Gather(&acc)
+	require.NoError(t, err)
+
+	m, ok := acc.Get("go_goroutines")
+	require.True(t, ok)
+	assert.Equal(t, "prod", m.Tags["env"])
+	assert.Equal(t, "http://"+address+"/custom", m.Tags["url"])
+	assert.NotContains(t, m.Tags, "__metrics_path__")
+
+	// changed files are reloaded
+	err = ioutil.WriteFile(sdFile, []byte(`[{
+		"targets": ["`+address+`"],
+		"labels": {"env": "staging"}
+	}]`), 0644)
+	require.NoError(t, err)
+	os.Chtimes(sdFile, time.Now(), time.Now().Add(time.Minute))
+
+	acc.ClearMetrics()
+	err = p.Gather(&acc)
+	require.NoError(t, err)
+
+	m, ok = acc.Get("go_goroutines")
+	require.True(t, ok)
+	assert.Equal(t, "staging", m.Tags["env"])
+	assert.Equal(t, "http://"+address+"/metrics", m.Tags["url"])
+}
+
+func TestLoadTargetFileYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prometheus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sdFile := filepath.Join(dir, "targets.yml")
+	err = ioutil.WriteFile(sdFile, []byte(`
+- targets:
+  - host1:9100
+  - host2:9100
+  labels:
+    env: prod
+    __scheme__: https
+`), 0644)
+	require.NoError(t, err)
+
+	targets, err := loadTargetFile(sdFile)
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+	assert.Equal(t, "https://host1:9100/metrics", targets[0].url)
+	assert.Equal(t, "https://host2:9100/metrics", targets[1].url)
+	assert.Equal(t, map[string]string{"env": "prod"}, targets[0].tags)
+}
+
+func TestPrometheusPodDiscovery(t *testing.T) {
+	kubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/pods", r.URL.Path)
+		fmt.Fprint(w, samplePods)
+	}))
+	defer kubelet.Close()
+
+	p := &Prometheus{
+		KubeletURL: kubelet.URL,
+	}
+	targets, err := p.targets()
+	require.NoError(t, err)
+
+	require.Len(t, targets, 1)
+	assert.Equal(t, "http://10.0.0.5:8080/stats", targets[0].url)
+	assert.Equal(t, map[string]string{
+		"pod_name":  "web-1",
+		"namespace": "default",
+	}, targets[0].tags)
+}
+
+const samplePods = `{
+  "items": [
+    {
+      "metadata": {
+        "name": "web-1",
+        "namespace": "default",
+        "annotations": {
+          "prometheus.io/scrape": "true",
+          "prometheus.io/port": "8080",
+          "prometheus.io/path": "/stats"
+        }
+      },
+      "status": {"phase": "Running", "podIP": "10.0.0.5"}
+    },
+    {
+      "metadata": {
+        "name": "web-2",
+        "namespace": "default",
+        "annotations": {"prometheus.io/scrape": "true"}
+      },
+      "status": {"phase": "Pending"}
+    },
+    {
+      "metadata": {"name": "db-1", "namespace": "default"},
+      "status": {"phase": "Running", "podIP": "10.0.0.6"}
+    }
+  ]
+}`
@@ -0,0 +1,837 @@
+package snmp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// mibs holds the MIBs loaded from the path of all the snmp plugins.
+var mibs = newMibTree()
+
+// LoadMibsFromPath loads the MIB files found in the given directories, and
+// their subdirectories, so that OIDs are translated using them rather than
+// the net-snmp tools. Directories that were already loaded are skipped.
+// The files skipped are logged with the logger of the plugin.
+func LoadMibsFromPath(paths []string, log telegraf.Logger) error {
+	loaded, err := mibs.loadPath(paths, log)
+	if loaded {
+		// lookups that failed before may now succeed.
+		snmpTranslateCachesLock.Lock()
+		snmpTranslateCaches = nil
+		snmpTranslateCachesLock.Unlock()
+	}
+	return err
+}
+
+// mibNode is an object, or any other value with an OID, defined in a MIB.
+type mibNode struct {
+	name   string
+	module string
+	// kind is the macro defining the node, such as OBJECT-TYPE.
+	kind string
+
+	// parent is the name of the node this node's OID is relative to, with
+	// subids following it. An empty parent means subids are absolute.
+	parent string
+	subids []int
+
+	syntax   string
+	access   string
+	index    []string
+	augments string
+
+	oid      []int
+	children map[int]*mibNode
+}
+
+// mibType is a type, usually a textual convention, defined in a MIB.
+type mibType struct {
+	name   string
+	module string
+	syntax string
+}
+
+type mibModule struct {
+	name    string
+	file    string
+	imports map[string]string
+	nodes   map[string]*mibNode
+	types   map[string]*mibType
+}
+
+// mibTree is a set of parsed MIB modules, along with an index of their
+// nodes by OID.
+type mibTree struct {
+	sync.Mutex
+
+	dirs    map[string]bool
+	modules map[string]*mibModule
+
+	root  *mibNode
+	byOID map[string]*mibNode
+}
+
+// smiRoots are the nodes MIBs are ultimately defined relative to. They have
+// no module, as with net-snmp.
+var smiRoots = map[string]int{
+	"ccitt":           0,
+	"iso":             1,
+	"joint-iso-ccitt": 2,
+}
+
+func newMibTree() *mibTree {
+	return &mibTree{
+		dirs:    make(map[string]bool),
+		modules: make(map[string]*mibModule),
+	}
+}
+
+// loaded returns whether any MIBs have been loaded.
+func (m *mibTree) loaded() bool {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.modules) > 0
+}
+
+// loadPath parses all the files in the given directories. Files that aren't
+// valid MIBs are skipped. It returns whether any new modules were loaded.
+func (m *mibTree) loadPath(paths []string, log telegraf.Logger) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	loaded := false
+	for _, dir := range paths {
+		if m.dirs[dir] {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			modules, err := parseMib(string(content))
+			if err != nil {
+				log.Debugf("Skipping MIB file %s: %s", path, err)
+				return nil
+			}
+			for _, mod := range modules {
+				if prev, ok := m.modules[mod.name]; ok {
+					log.Debugf("MIB module %s in %s is already loaded from %s",
+						mod.name, path, prev.file)
+					continue
+				}
+				mod.file = path
+				m.modules[mod.name] = mod
+				loaded = true
+			}
+			return nil
+		})
+		if err != nil {
+			return loaded, fmt.Errorf("loading MIBs from %s: %s", dir, err)
+		}
+		m.dirs[dir] = true
+	}
+
+	if loaded {
+		m.resolve()
+	}
+	return loaded, nil
+}
+
+// resolve works out the OID of every node, and indexes them.
+func (m *mibTree) resolve() {
+	m.root = &mibNode{children: make(map[int]*mibNode)}
+	m.byOID = make(map[string]*mibNode)
+	for name, subid := range smiRoots {
+		node := &mibNode{name: name, oid: []int{subid}}
+		m.add(node)
+	}
+
+	var names []string
+	for name := range m.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, node := range m.modules[name].nodes {
+			node.oid = nil
+			node.children = nil
+		}
+	}
+	var resolved []*mibNode
+	for _, name := range names {
+		for _, node := range m.modules[name].nodes {
+			if oid := m.resolveNode(node, 0); oid != nil {
+				resolved = append(resolved, node)
+			}
+		}
+	}
+
+	// add parents before their children, and in a consistent order so that
+	// the same node wins when several share an OID.
+	sort.Sort(byOIDLength(resolved))
+	for _, node := range resolved {
+		m.add(node)
+	}
+}
+
+type byOIDLength []*mibNode
+
+func (s byOIDLength) Len() int      { return len(s) }
+func (s byOIDLength) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byOIDLength) Less(i, j int) bool {
+	if len(s[i].oid) != len(s[j].oid) {
+		return len(s[i].oid) < len(s[j].oid)
+	}
+	if s[i].module != s[j].module {
+		return s[i].module < s[j].module
+	}
+	return s[i].name < s[j].name
+}
+
+// resolveNode returns the OID of the node, resolving its parent first.
+func (m *mibTree) resolveNode(node *mibNode, depth int) []int {
+	if node.oid != nil || depth > 64 {
+		return node.oid
+	}
+
+	var oid []int
+	if node.parent != "" {
+		parent := m.lookupNode(node.module, node.parent)
+		if parent == nil {
+			return nil
+		}
+		if oid = m.resolveNode(parent, depth+1); oid == nil {
+			return nil
+		}
+	}
+	node.oid = append(append([]int{}, oid...), node.subids...)
+	return node.oid
+}
+
+// add inserts a resolved node into the tree. If another node already has the
+// same OID, the first one added is kept.
+func (m *mibTree) add(node *mibNode) {
+	key := oidString(node.oid)
+	if _, ok := m.byOID[key]; ok {
+		return
+	}
+	m.byOID[key] = node
+
+	parent := m.root
+	if len(node.oid) > 1 {
+		parent = m.byOID[oidString(node.oid[:len(node.oid)-1])]
+	}
+	if parent == nil {
+		// the parent was defined by number only, such as { iso 3 6 1 }.
+		return
+	}
+	if parent.children == nil {
+		parent.children = make(map[int]*mibNode)
+	}
+	parent.children[node.oid[len(node.oid)-1]] = node
+}
+
+// lookupNode finds the node with the given name as seen from the given
+// module: its own definitions, then its imports, then any loaded module.
+func (m *mibTree) lookupNode(module string, name string) *mibNode {
+	if mod, ok := m.modules[module]; ok {
+		if node, ok := mod.nodes[name]; ok {
+			return node
+		}
+		if from, ok := mod.imports[name]; ok {
+			if mod, ok := m.modules[from]; ok {
+				if node, ok := mod.nodes[name]; ok {
+					return node
+				}
+			}
+		}
+	}
+	if subid, ok := smiRoots[name]; ok {
+		return m.byOID[strconv.Itoa(subid)]
+	}
+
+	var names []string
+	for name := range m.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, mod := range names {
+		if node, ok := m.modules[mod].nodes[name]; ok {
+			return node
+		}
+	}
+	return nil
+}
+
+// lookupType finds a type as lookupNode finds a node.
+func (m *mibTree) lookupType(module string, name string) *mibType {
+	mod, ok := m.modules[module]
+	if !ok {
+		return nil
+	}
+	if t, ok := mod.types[name]; ok {
+		return t
+	}
+	if from, ok := mod.imports[name]; ok {
+		if mod, ok := m.modules[from]; ok {
+			return mod.types[name]
+		}
+	}
+	return nil
+}
+
+// find resolves an OID, either numeric (.1.3.6.1.2.1.2.2.1.2.1) or textual
+// (IF-MIB::ifDescr.1, ifDescr.1, .iso.3.6), into the node it is an instance
+// of and the remaining subids of the instance index.
+func (m *mibTree) find(oid string) (*mibNode, []string, error) {
+	if m.root == nil {
+		return nil, nil, fmt.Errorf("no MIBs loaded")
+	}
+
+	module := ""
+	if i := strings.Index(oid, "::"); i != -1 {
+		module = oid[:i]
+		oid = oid[i+2:]
+		if _, ok := m.modules[module]; !ok {
+			return nil, nil, fmt.Errorf("MIB module %s not found", module)
+		}
+	}
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+
+	var full []int
+	if _, err := strconv.Atoi(parts[0]); err != nil {
+		node := m.lookupNode(module, parts[0])
+		if node == nil || node.oid == nil {
+			return nil, nil, fmt.Errorf("%s not found in MIBs", oid)
+		}
+		full = append(full, node.oid...)
+		parts = parts[1:]
+	}
+	for _, part := range parts {
+		if subid, err := strconv.Atoi(part); err == nil {
+			full = append(full, subid)
+			continue
+		}
+		// a name following the first, such as .iso.org.dod
+		parent := m.byOID[oidString(full)]
+		if parent == nil {
+			return nil, nil, fmt.Errorf("%s not found in MIBs", oid)
+		}
+		child := parent.childNamed(part)
+		if child == nil {
+			return nil, nil, fmt.Errorf("%s not found in MIBs", oid)
+		}
+		full = child.oid
+	}
+
+	// the object is the longest prefix of the OID defined in a module, the
+	// rest is the instance index.
+	for i := len(full); i > 0; i-- {
+		node := m.byOID[oidString(full[:i])]
+		if node == nil || node.module == "" {
+			continue
+		}
+		var index []string
+		for _, subid := range full[i:] {
+			index = append(index, strconv.Itoa(subid))
+		}
+		return node, index, nil
+	}
+	return nil, nil, fmt.Errorf("%s not found in MIBs", oid)
+}
+
+// childNamed returns the child with the given subid or name.
+func (n *mibNode) childNamed(part string) *mibNode {
+	if subid, err := strconv.Atoi(part); err == nil {
+		return n.children[subid]
+	}
+	for _, child := range n.children {
+		if child.name == part {
+			return child
+		}
+	}
+	return nil
+}
+
+// translate resolves an OID like snmpTranslate does with snmptranslate.
+func (m *mibTree) translate(oid string) (mibName string, oidNum string, oidText string, conversion string, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	node, index, err := m.find(oid)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
+	oidNum = "." + oidString(node.oid)
+	oidText = node.name
+	if len(index) > 0 {
+		oidNum += "." + strings.Join(index, ".")
+		oidText += "." + strings.Join(index, ".")
+	}
+	return node.module, oidNum, oidText, m.conversion(node), nil
+}
+
+// conversion returns the conversion for the textual convention of the
+// node's syntax, or of any textual convention it is derived from.
+func (m *mibTree) conversion(node *mibNode) string {
+	module, syntax := node.module, node.syntax
+	for i := 0; i < 8 && syntax != ""; i++ {
+		switch syntax {
+		case "MacAddress", "PhysAddress":
+			return "hwaddr"
+		case "InetAddressIPv4", "InetAddressIPv6", "InetAddress":
+			return "ipaddr"
+		}
+		t := m.lookupType(module, syntax)
+		if t == nil {
+			break
+		}
+		module, syntax = t.module, t.syntax
+	}
+	return ""
+}
+
+// table returns the columns of a table, like snmpTable does with snmptable.
+// Index columns are tags, and columns which are not accessible are skipped.
+func (m *mibTree) table(oid string) (mibName string, oidNum string, oidText string, fields []Field, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	node, index, err := m.find(oid)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+	if len(index) > 0 || !strings.HasPrefix(node.syntax, "SEQUENCE OF ") {
+		return "", "", "", nil, fmt.Errorf("%s is not a table", oid)
+	}
+
+	entryType := strings.TrimPrefix(node.syntax, "SEQUENCE OF ")
+	entry := node.children[1]
+	for _, child := range node.children {
+		if child.syntax == entryType {
+			entry = child
+		}
+	}
+	if entry == nil {
+		return "", "", "", nil, fmt.Errorf("no entry found for table %s", oid)
+	}
+
+	tags := map[string]bool{}
+	indexes := entry.index
+	if entry.augments != "" {
+		if augmented := m.lookupNode(entry.module, entry.augments); augmented != nil {
+			indexes = augmented.index
+		}
+	}
+	for _, name := range indexes {
+		tags[name] = true
+	}
+
+	var subids []int
+	for subid := range entry.children {
+		subids = append(subids, subid)
+	}
+	sort.Ints(subids)
+
+	mibPrefix := node.module + "::"
+	for _, subid := range subids {
+		col := entry.children[subid]
+		if col.access == "not-accessible" {
+			continue
+		}
+		fields = append(fields, Field{Name: col.name, Oid: mibPrefix + col.name, IsTag: tags[col.name]})
+	}
+	return node.module, "." + oidString(node.oid), node.name, fields, nil
+}
+
+func oidString(oid []int) string {
+	s := make([]string, len(oid))
+	for i, subid := range oid {
+		s[i] = strconv.Itoa(subid)
+	}
+	return strings.Join(s, ".")
+}
+
+// mibParser parses the subset of SMIv1 & SMIv2 needed to translate OIDs:
+// the OID of each definition, the syntax, access and index of objects, and
+// textual conventions. Anything else is skipped over.
+type mibParser struct {
+	tokens []string
+	pos    int
+}
+
+// parseMib parses the modules defined in a MIB file.
+func parseMib(content string) ([]*mibModule, error) {
+	tokens, err := tokenizeMib(content)
+	if err != nil {
+		return nil, err
+	}
+	p := &mibParser{tokens: tokens}
+
+	var modules []*mibModule
+	for !p.done() {
+		mod, err := p.parseModule()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, mod)
+	}
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("no MIB modules found")
+	}
+	return modules, nil
+}
+
+func (p *mibParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *mibParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *mibParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *mibParser) expect(expected string) error {
+	if t := p.next(); t != expected {
+		return fmt.Errorf("expected %q, got %q", expected, t)
+	}
+	return nil
+}
+
+// skipBalanced skips a bracketed group, starting at its opening bracket.
+func (p *mibParser) skipBalanced() []string {
+	start := p.pos
+	depth := 0
+	for !p.done() {
+		switch p.next() {
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	return p.tokens[start:p.pos]
+}
+
+func (p *mibParser) parseModule() (*mibModule, error) {
+	mod := &mibModule{
+		name:    p.next(),
+		imports: make(map[string]string),
+		nodes:   make(map[string]*mibNode),
+		types:   make(map[string]*mibType),
+	}
+	if err := p.expect("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	for !p.done() && p.peek() != "::=" {
+		p.next()
+	}
+	p.next()
+	if err := p.expect("BEGIN"); err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.done() {
+			return nil, fmt.Errorf("module %s: unexpected end of file", mod.name)
+		}
+		switch t := p.next(); t {
+		case "END":
+			return mod, nil
+		case "IMPORTS":
+			p.parseImports(mod)
+		case "EXPORTS":
+			for !p.done() && p.next() != ";" {
+			}
+		default:
+			if err := p.parseAssignment(mod, t); err != nil {
+				return nil, fmt.Errorf("module %s: %s: %s", mod.name, t, err)
+			}
+		}
+	}
+}
+
+func (p *mibParser) parseImports(mod *mibModule) {
+	var symbols []string
+	for !p.done() {
+		t := p.next()
+		switch t {
+		case ";":
+			return
+		case ",":
+		case "FROM":
+			from := p.next()
+			if p.peek() == "{" {
+				p.skipBalanced()
+			}
+			for _, symbol := range symbols {
+				mod.imports[symbol] = from
+			}
+			symbols = symbols[:0]
+		default:
+			symbols = append(symbols, t)
+		}
+	}
+}
+
+func (p *mibParser) parseAssignment(mod *mibModule, name string) error {
+	if p.peek() == "MACRO" {
+		for !p.done() && p.next() != "END" {
+		}
+		return nil
+	}
+
+	// the clauses of the macro, up to the assignment.
+	start := p.pos
+	for !p.done() && p.peek() != "::=" {
+		switch p.peek() {
+		case "{", "(", "[":
+			p.skipBalanced()
+		default:
+			p.next()
+		}
+	}
+	clauses := p.tokens[start:p.pos]
+	if err := p.expect("::="); err != nil {
+		return err
+	}
+
+	if first := name[0]; first >= 'A' && first <= 'Z' {
+		return p.parseTypeAssignment(mod, name)
+	}
+
+	node := &mibNode{name: name, module: mod.name}
+	if len(clauses) > 0 {
+		node.kind = clauses[0]
+	}
+	if node.kind == "OBJECT-TYPE" {
+		parseObjectClauses(node, clauses)
+	}
+
+	switch {
+	case p.peek() == "{":
+		if err := parseOIDValue(node, p.skipBalanced()); err != nil {
+			return err
+		}
+	case node.kind == "TRAP-TYPE":
+		// SNMPv1 traps are numbered under their enterprise, as in RFC 3584.
+		specific, err := strconv.Atoi(p.next())
+		if err != nil {
+			return fmt.Errorf("invalid trap number")
+		}
+		for i, clause := range clauses {
+			if clause == "ENTERPRISE" && i+1 < len(clauses) {
+				node.parent = clauses[i+1]
+			}
+		}
+		node.subids = []int{0, specific}
+	default:
+		// a value which isn't an OID.
+		p.next()
+		return nil
+	}
+	mod.nodes[name] = node
+	return nil
+}
+
+func (p *mibParser) parseTypeAssignment(mod *mibModule, name string) error {
+	if p.peek() == "TEXTUAL-CONVENTION" {
+		for !p.done() && p.peek() != "SYNTAX" {
+			p.next()
+		}
+		p.next()
+	}
+	syntax := p.parseType()
+	if syntax == "" {
+		return fmt.Errorf("invalid type")
+	}
+	mod.types[name] = &mibType{name: name, module: mod.name, syntax: syntax}
+	return nil
+}
+
+// parseType parses a type, returning its name without any constraints, ie
+// "OCTET STRING (SIZE (6))" is "OCTET STRING".
+func (p *mibParser) parseType() string {
+	if p.peek() == "[" {
+		p.skipBalanced()
+	}
+	if p.peek() == "IMPLICIT" || p.peek() == "EXPLICIT" {
+		p.next()
+	}
+
+	var syntax string
+	switch t := p.next(); t {
+	case "OCTET", "OBJECT":
+		syntax = t + " " + p.next()
+	case "SEQUENCE":
+		if p.peek() == "OF" {
+			p.next()
+			syntax = "SEQUENCE OF " + p.next()
+		} else {
+			syntax = t
+		}
+	default:
+		syntax = t
+	}
+
+	for p.peek() == "{" || p.peek() == "(" {
+		p.skipBalanced()
+	}
+	return syntax
+}
+
+// parseObjectClauses sets the syntax, access and index of an object from the
+// clauses of its OBJECT-TYPE macro.
+func parseObjectClauses(node *mibNode, clauses []string) {
+	p := &mibParser{tokens: clauses}
+	for !p.done() {
+		switch p.next() {
+		case "SYNTAX":
+			node.syntax = p.parseType()
+		case "MAX-ACCESS", "ACCESS":
+			node.access = p.next()
+		case "INDEX":
+			for _, t := range p.skipBalanced() {
+				switch t {
+				case "{", "}", ",", "IMPLIED":
+				default:
+					node.index = append(node.index, t)
+				}
+			}
+		case "AUGMENTS":
+			for _, t := range p.skipBalanced() {
+				if t != "{" && t != "}" {
+					node.augments = t
+				}
+			}
+		}
+	}
+}
+
+// parseOIDValue parses an OID value such as { ifEntry 2 }, { iso(1) 3 6 } or
+// { 1 0 0 } into the node's parent and subids.
+func parseOIDValue(node *mibNode, tokens []string) error {
+	tokens = tokens[1 : len(tokens)-1]
+	if len(tokens) == 0 {
+		return fmt.Errorf("empty OID")
+	}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if subid, err := strconv.Atoi(t); err == nil {
+			node.subids = append(node.subids, subid)
+			continue
+		}
+		// name(number)
+		if i+1 < len(tokens) && tokens[i+1] == "(" {
+			if i+3 >= len(tokens) || tokens[i+3] != ")" {
+				return fmt.Errorf("invalid OID component %s", t)
+			}
+			subid, err := strconv.Atoi(tokens[i+2])
+			if err != nil {
+				return fmt.Errorf("invalid OID component %s", t)
+			}
+			node.subids = append(node.subids, subid)
+			i += 3
+			continue
+		}
+		if i != 0 {
+			return fmt.Errorf("invalid OID component %s", t)
+		}
+		node.parent = t
+	}
+	return nil
+}
+
+// tokenizeMib splits a MIB into tokens, dropping comments. Quoted strings
+// are kept as a single token.
+func tokenizeMib(content string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(content[i:], "--"):
+			// comments end at the end of the line, or at the next "--".
+			end := i + 2
+			for end < len(content) && content[end] != '\n' && !strings.HasPrefix(content[end:], "--") {
+				end++
+			}
+			if strings.HasPrefix(content[end:], "--") {
+				end += 2
+			}
+			i = end
+		case c == '"':
+			end := strings.IndexByte(content[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, content[i:i+end+2])
+			i += end + 2
+		case c == '\'':
+			// binary or hex string, ie '00'H
+			end := strings.IndexByte(content[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			end += i + 2
+			if end < len(content) && (content[end] == 'H' || content[end] == 'h' ||
+				content[end] == 'B' || content[end] == 'b') {
+				end++
+			}
+			tokens = append(tokens, content[i:end])
+			i = end
+		case strings.HasPrefix(content[i:], "::="):
+			tokens = append(tokens, "::=")
+			i += 3
+		case strings.HasPrefix(content[i:], ".."):
+			tokens = append(tokens, "..")
+			i += 2
+		case isMibIdentChar(c):
+			end := i + 1
+			for end < len(content) && isMibIdentChar(content[end]) &&
+				!strings.HasPrefix(content[end:], "--") {
+				end++
+			}
+			tokens = append(tokens, content[i:end])
+			i = end
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isMibIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_'
+}
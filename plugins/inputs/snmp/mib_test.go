@@ -0,0 +1,192 @@
+package snmp
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestMibs(t *testing.T) *mibTree {
+	m := newMibTree()
+	loaded, err := m.loadPath([]string{"testdata"}, testutil.Logger{Name: "inputs.snmp"})
+	require.NoError(t, err)
+	require.True(t, loaded)
+	return m
+}
+
+func TestMibTranslate(t *testing.T) {
+	m := loadTestMibs(t)
+
+	translations := []struct {
+		oid        string
+		mibName    string
+		oidNum     string
+		oidText    string
+		conversion string
+	}{
+		{"TEST::server", "TEST", ".1.0.0.0.1.1", "server", ""},
+		{"TEST::server.0", "TEST", ".1.0.0.0.1.1.0", "server.0", ""},
+		{".1.0.0.0.1.1.0", "TEST", ".1.0.0.0.1.1.0", "server.0", ""},
+		{"1.0.0.1.1", "TEST", ".1.0.0.1.1", "hostname", ""},
+		{".1.0.0.1.2", "TEST", ".1.0.0.1.2", "testOID.1.2", ""},
+		{"ifDescr", "IF-MIB", ".1.3.6.1.2.1.2.2.1.2", "ifDescr", ""},
+		{".1.3.6.1.2.1.2.2.1.2.3", "IF-MIB", ".1.3.6.1.2.1.2.2.1.2.3", "ifDescr.3", ""},
+		{"IF-MIB::ifEntry.8", "IF-MIB", ".1.3.6.1.2.1.2.2.1.8", "ifOperStatus", ""},
+		{".iso.org.dod.internet.mgmt.mib-2.2.2.1.1", "IF-MIB", ".1.3.6.1.2.1.2.2.1.1", "ifIndex", ""},
+		{"IF-MIB::ifPhysAddress.1", "IF-MIB", ".1.3.6.1.2.1.2.2.1.6.1", "ifPhysAddress.1", "hwaddr"},
+		{"IF-MIB::ifName", "IF-MIB", ".1.3.6.1.2.1.31.1.1.1.1", "ifName", ""},
+		{".1.3.6.1.6.3.1.1.5.3", "IF-MIB", ".1.3.6.1.6.3.1.1.5.3", "linkDown", ""},
+		{"SNMPv2-SMI::enterprises", "SNMPv2-SMI", ".1.3.6.1.4.1", "enterprises", ""},
+		{"SNMPv2-SMI::zeroDotZero", "SNMPv2-SMI", ".0.0", "zeroDotZero", ""},
+		{".1.3.6.1.4.1.99999.0.2", "TEST-TRAP-MIB", ".1.3.6.1.4.1.99999.0.2", "testStationLost", ""},
+		{"TEST-TRAP-MIB::testStationAddress", "TEST-TRAP-MIB", ".1.3.6.1.4.1.99999.1.1.1", "testStationAddress", "hwaddr"},
+	}
+	for _, txl := range translations {
+		mibName, oidNum, oidText, conversion, err := m.translate(txl.oid)
+		if !assert.NoError(t, err, txl.oid) {
+			continue
+		}
+		assert.Equal(t, txl.mibName, mibName, txl.oid)
+		assert.Equal(t, txl.oidNum, oidNum, txl.oid)
+		assert.Equal(t, txl.oidText, oidText, txl.oid)
+		assert.Equal(t, txl.conversion, conversion, txl.oid)
+	}
+
+	for _, oid := range []string{".1.2.3", ".999", "FOO-MIB::foo", "TEST::foo", "IF-MIB::ifDescr.foo"} {
+		_, _, _, _, err := m.translate(oid)
+		assert.Error(t, err, oid)
+	}
+}
+
+func TestMibTable(t *testing.T) {
+	m := loadTestMibs(t)
+
+	mibName, oidNum, oidText, fields, err := m.table("TEST::testTable")
+	require.NoError(t, err)
+	assert.Equal(t, "TEST", mibName)
+	assert.Equal(t, ".1.0.0.0", oidNum)
+	assert.Equal(t, "testTable", oidText)
+	assert.Equal(t, []Field{
+		{Name: "server", Oid: "TEST::server", IsTag: true},
+		{Name: "connections", Oid: "TEST::connections"},
+		{Name: "latency", Oid: "TEST::latency"},
+	}, fields)
+
+	_, _, oidText, fields, err = m.table(".1.3.6.1.2.1.2.2")
+	require.NoError(t, err)
+	assert.Equal(t, "ifTable", oidText)
+	require.Len(t, fields, 8)
+	assert.Equal(t, Field{Name: "ifIndex", Oid: "IF-MIB::ifIndex", IsTag: true}, fields[0])
+	assert.Equal(t, Field{Name: "ifOperStatus", Oid: "IF-MIB::ifOperStatus"}, fields[7])
+
+	// the index of an augmenting table is that of the table it augments,
+	// and index columns which aren't accessible aren't fields.
+	_, _, _, fields, err = m.table("IF-MIB::ifXTable")
+	require.NoError(t, err)
+	assert.Equal(t, []Field{
+		{Name: "ifName", Oid: "IF-MIB::ifName"},
+		{Name: "ifHCInOctets", Oid: "IF-MIB::ifHCInOctets"},
+		{Name: "ifAlias", Oid: "IF-MIB::ifAlias"},
+	}, fields)
+
+	_, _, _, fields, err = m.table("TEST-TRAP-MIB::testStationTable")
+	require.NoError(t, err)
+	assert.Equal(t, []Field{
+		{Name: "testStationIP", Oid: "TEST-TRAP-MIB::testStationIP"},
+		{Name: "testStationPackets", Oid: "TEST-TRAP-MIB::testStationPackets"},
+	}, fields)
+
+	_, _, _, _, err = m.table("IF-MIB::ifDescr")
+	assert.Error(t, err)
+}
+
+func TestMibLoadPath(t *testing.T) {
+	m := loadTestMibs(t)
+
+	// snmpd.conf isn't a MIB, and is skipped.
+	assert.Len(t, m.modules, 5)
+
+	loaded, err := m.loadPath([]string{"testdata"}, testutil.Logger{Name: "inputs.snmp"})
+	require.NoError(t, err)
+	assert.False(t, loaded)
+
+	_, err = m.loadPath([]string{"testdata/nonexistent"}, testutil.Logger{Name: "inputs.snmp"})
+	assert.Error(t, err)
+}
+
+func TestParseMib(t *testing.T) {
+	modules, err := parseMib(`
+FIRST-MIB DEFINITIONS ::= BEGIN
+IMPORTS enterprises FROM SNMPv2-SMI;
+first OBJECT IDENTIFIER ::= { enterprises 1 } -- ends at the next -- inline ::= { first 9 }
+-- or at the end of the line ::= { first 10 }
+END
+
+SECOND-MIB DEFINITIONS IMPLICIT TAGS ::= BEGIN
+second OBJECT IDENTIFIER ::= { first 2 }
+Hex ::= OCTET STRING (SIZE (0..8))
+hex OBJECT-TYPE
+    SYNTAX Hex
+    MAX-ACCESS read-write
+    STATUS current
+    DESCRIPTION "A ""hex"" -- value."
+    DEFVAL { 'ff'H }
+    ::= { second 1 }
+END
+`)
+	require.NoError(t, err)
+	require.Len(t, modules, 2)
+
+	assert.Equal(t, "FIRST-MIB", modules[0].name)
+	assert.Equal(t, map[string]string{"enterprises": "SNMPv2-SMI"}, modules[0].imports)
+	assert.Len(t, modules[0].nodes, 2)
+	assert.Equal(t, "enterprises", modules[0].nodes["first"].parent)
+	assert.Equal(t, []int{1}, modules[0].nodes["first"].subids)
+	assert.Equal(t, "first", modules[0].nodes["inline"].parent)
+	assert.Equal(t, []int{9}, modules[0].nodes["inline"].subids)
+
+	assert.Equal(t, "SECOND-MIB", modules[1].name)
+	assert.Equal(t, "OCTET STRING", modules[1].types["Hex"].syntax)
+	hex := modules[1].nodes["hex"]
+	assert.Equal(t, "OBJECT-TYPE", hex.kind)
+	assert.Equal(t, "Hex", hex.syntax)
+	assert.Equal(t, "read-write", hex.access)
+	assert.Equal(t, "second", hex.parent)
+
+	_, err = parseMib("rocommunity public")
+	assert.Error(t, err)
+}
+
+func TestSnmpTranslateFallback(t *testing.T) {
+	defer func(m *mibTree) { mibs = m }(mibs)
+	mibs = loadTestMibs(t)
+
+	// found in the loaded MIBs
+	mibName, oidNum, oidText, conversion, err := snmpTranslate("IF-MIB::ifPhysAddress.2")
+	require.NoError(t, err)
+	assert.Equal(t, "IF-MIB", mibName)
+	assert.Equal(t, ".1.3.6.1.2.1.2.2.1.6.2", oidNum)
+	assert.Equal(t, "ifPhysAddress.2", oidText)
+	assert.Equal(t, "hwaddr", conversion)
+
+	// not found, so snmptranslate is used
+	mibName, oidNum, oidText, conversion, err = snmpTranslate("BRIDGE-MIB::dot1dTpFdbAddress.1")
+	require.NoError(t, err)
+	assert.Equal(t, "BRIDGE-MIB", mibName)
+	assert.Equal(t, ".1.3.6.1.2.1.17.4.3.1.1.1", oidNum)
+	assert.Equal(t, "dot1dTpFdbAddress.1", oidText)
+	assert.Equal(t, "hwaddr", conversion)
+
+	_, oidNum, oidText, _, err = snmpTranslate(".999")
+	require.NoError(t, err)
+	assert.Equal(t, ".999", oidNum)
+	assert.Equal(t, ".999", oidText)
+
+	_, _, oidText, fields, err := snmpTable("TEST::testTable")
+	require.NoError(t, err)
+	assert.Equal(t, "testTable", oidText)
+	assert.Len(t, fields, 3)
+}
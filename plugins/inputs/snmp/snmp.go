@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -33,6 +34,11 @@ const sampleConfig = `
   ## The GETBULK max-repetitions parameter
   max_repetitions = 10
 
+  ## Paths to directories of MIB files, which are loaded to translate OIDs.
+  ## OIDs not found in them, or all OIDs if unset, are translated using the
+  ## net-snmp tools (snmptranslate & snmptable) instead.
+  # path = ["/usr/share/snmp/mibs"]
+
   ## SNMPv3 auth parameters
   #sec_name = "myuser"
   #auth_protocol = "md5"      # Values: "MD5", "SHA", ""
@@ -126,6 +132,9 @@ type Snmp struct {
 	EngineBoots  uint32
 	EngineTime   uint32
 
+	// Path to directories of MIB files used to translate OIDs.
+	Path []string
+
 	Tables []Table `toml:"table"`
 
 	// Name & Fields are the elements of a Table.
@@ -134,15 +143,29 @@ type Snmp struct {
 	Name   string
 	Fields []Field `toml:"field"`
 
+	Log telegraf.Logger `toml:"-"`
+
 	connectionCache map[string]snmpConnection
 	initialized     bool
 }
 
+// Init loads the MIBs and translates the OIDs of the fields and tables, so
+// that configuration errors are reported when telegraf starts.
+func (s *Snmp) Init() error {
+	return s.init()
+}
+
 func (s *Snmp) init() error {
 	if s.initialized {
 		return nil
 	}
 
+	if len(s.Path) > 0 {
+		if err := LoadMibsFromPath(s.Path, s.Log); err != nil {
+			return err
+		}
+	}
+
 	for i := range s.Tables {
 		if err := s.Tables[i].init(); err != nil {
 			return err
@@ -205,59 +228,14 @@ func (t *Table) initBuild() error {
 		return nil
 	}
 
-	mibName, _, oidText, _, err := snmpTranslate(t.Oid)
+	_, _, oidText, fields, err := snmpTable(t.Oid)
 	if err != nil {
-		return Errorf(err, "translating %s", t.Oid)
+		return err
 	}
 	if t.Name == "" {
 		t.Name = oidText
 	}
-	mibPrefix := mibName + "::"
-	oidFullName := mibPrefix + oidText
-
-	// first attempt to get the table's tags
-	tagOids := map[string]struct{}{}
-	// We have to guess that the "entry" oid is `t.Oid+".1"`. snmptable and snmptranslate don't seem to have a way to provide the info.
-	if out, err := execCmd("snmptranslate", "-Td", oidFullName+".1"); err == nil {
-		lines := bytes.Split(out, []byte{'\n'})
-		for _, line := range lines {
-			if !bytes.HasPrefix(line, []byte("  INDEX")) {
-				continue
-			}
-
-			i := bytes.Index(line, []byte("{ "))
-			if i == -1 { // parse error
-				continue
-			}
-			line = line[i+2:]
-			i = bytes.Index(line, []byte(" }"))
-			if i == -1 { // parse error
-				continue
-			}
-			line = line[:i]
-			for _, col := range bytes.Split(line, []byte(", ")) {
-				tagOids[mibPrefix+string(col)] = struct{}{}
-			}
-		}
-	}
-
-	// this won't actually try to run a query. The `-Ch` will just cause it to dump headers.
-	out, err := execCmd("snmptable", "-Ch", "-Cl", "-c", "public", "127.0.0.1", oidFullName)
-	if err != nil {
-		return Errorf(err, "getting table columns for %s", t.Oid)
-	}
-	cols := bytes.SplitN(out, []byte{'\n'}, 2)[0]
-	if len(cols) == 0 {
-		return fmt.Errorf("unable to get columns for table %s", t.Oid)
-	}
-	for _, col := range bytes.Split(cols, []byte{' '}) {
-		if len(col) == 0 {
-			continue
-		}
-		col := string(col)
-		_, isTag := tagOids[mibPrefix+col]
-		t.Fields = append(t.Fields, Field{Name: col, Oid: mibPrefix + col, IsTag: isTag})
-	}
+	t.Fields = append(t.Fields, fields...)
 
 	return nil
 }
@@ -292,7 +270,7 @@ func (f *Field) init() error {
 		return nil
 	}
 
-	_, oidNum, oidText, conversion, err := snmpTranslate(f.Oid)
+	_, oidNum, oidText, conversion, err := SnmpTranslate(f.Oid)
 	if err != nil {
 		return Errorf(err, "translating %s", f.Oid)
 	}
@@ -841,8 +819,134 @@ func fieldConvert(conv string, v interface{}) (interface{}, error) {
 	return nil, fmt.Errorf("invalid conversion type '%s'", conv)
 }
 
-// snmpTranslate resolves the given OID.
+type snmpTranslateCache struct {
+	mibName    string
+	oidNum     string
+	oidText    string
+	conversion string
+	err        error
+}
+
+var snmpTranslateCachesLock sync.Mutex
+var snmpTranslateCaches map[string]snmpTranslateCache
+
+// SnmpTranslate resolves the given OID, either a name (IF-MIB::ifDescr) or a
+// number (.1.3.6.1.2.1.2.2.1.2), into its MIB name, numeric OID, textual name
+// and suggested conversion.
+// Results (including failures) are cached, so that each OID is only looked up
+// once for the lifetime of the process.
+func SnmpTranslate(oid string) (mibName string, oidNum string, oidText string, conversion string, err error) {
+	snmpTranslateCachesLock.Lock()
+	if snmpTranslateCaches == nil {
+		snmpTranslateCaches = map[string]snmpTranslateCache{}
+	}
+
+	var stc snmpTranslateCache
+	var ok bool
+	if stc, ok = snmpTranslateCaches[oid]; !ok {
+		// Only one lookup runs at a time, which also keeps a burst of new OIDs
+		// from spawning many snmptranslate processes at once.
+		stc.mibName, stc.oidNum, stc.oidText, stc.conversion, stc.err = snmpTranslate(oid)
+		snmpTranslateCaches[oid] = stc
+	}
+
+	snmpTranslateCachesLock.Unlock()
+
+	return stc.mibName, stc.oidNum, stc.oidText, stc.conversion, stc.err
+}
+
+// snmpTable resolves the given table OID, and returns its columns as fields,
+// using the loaded MIBs or falling back to snmptable.
+func snmpTable(oid string) (mibName string, oidNum string, oidText string, fields []Field, err error) {
+	if mibs.loaded() {
+		mibName, oidNum, oidText, fields, err = mibs.table(oid)
+		if err == nil {
+			return mibName, oidNum, oidText, fields, nil
+		}
+	}
+	return snmpTableCall(oid)
+}
+
+// snmpTableCall resolves the given table OID with snmptranslate & snmptable.
+func snmpTableCall(oid string) (mibName string, oidNum string, oidText string, fields []Field, err error) {
+	mibName, oidNum, oidText, _, err = SnmpTranslate(oid)
+	if err != nil {
+		return "", "", "", nil, Errorf(err, "translating %s", oid)
+	}
+	mibPrefix := mibName + "::"
+	oidFullName := mibPrefix + oidText
+
+	// first attempt to get the table's tags
+	tagOids := map[string]struct{}{}
+	// We have to guess that the "entry" oid is `t.Oid+".1"`. snmptable and snmptranslate don't seem to have a way to provide the info.
+	if out, err := execCmd("snmptranslate", "-Td", oidFullName+".1"); err == nil {
+		lines := bytes.Split(out, []byte{'\n'})
+		for _, line := range lines {
+			if !bytes.HasPrefix(line, []byte("  INDEX")) {
+				continue
+			}
+
+			i := bytes.Index(line, []byte("{ "))
+			if i == -1 { // parse error
+				continue
+			}
+			line = line[i+2:]
+			i = bytes.Index(line, []byte(" }"))
+			if i == -1 { // parse error
+				continue
+			}
+			line = line[:i]
+			for _, col := range bytes.Split(line, []byte(", ")) {
+				tagOids[mibPrefix+string(col)] = struct{}{}
+			}
+		}
+	}
+
+	// this won't actually try to run a query. The `-Ch` will just cause it to dump headers.
+	out, err := execCmd("snmptable", "-Ch", "-Cl", "-c", "public", "127.0.0.1", oidFullName)
+	if err != nil {
+		return "", "", "", nil, Errorf(err, "getting table columns for %s", oid)
+	}
+	cols := bytes.SplitN(out, []byte{'\n'}, 2)[0]
+	if len(cols) == 0 {
+		return "", "", "", nil, fmt.Errorf("unable to get columns for table %s", oid)
+	}
+	for _, col := range bytes.Split(cols, []byte{' '}) {
+		if len(col) == 0 {
+			continue
+		}
+		col := string(col)
+		_, isTag := tagOids[mibPrefix+col]
+		fields = append(fields, Field{Name: col, Oid: mibPrefix + col, IsTag: isTag})
+	}
+
+	return mibName, oidNum, oidText, fields, nil
+}
+
+// snmpTranslate resolves the given OID using the loaded MIBs, falling back to
+// snmptranslate for OIDs that aren't found in them.
 func snmpTranslate(oid string) (mibName string, oidNum string, oidText string, conversion string, err error) {
+	if !mibs.loaded() {
+		return snmpTranslateCall(oid)
+	}
+
+	mibName, oidNum, oidText, conversion, err = mibs.translate(oid)
+	if err == nil {
+		return mibName, oidNum, oidText, conversion, nil
+	}
+	mibName, oidNum, oidText, conversion, execErr := snmpTranslateCall(oid)
+	if execErr == nil {
+		return mibName, oidNum, oidText, conversion, nil
+	}
+	if strings.Trim(oid, ".0123456789") == "" {
+		// numeric OIDs don't need translating.
+		return "", oid, oid, "", nil
+	}
+	return "", "", "", "", err
+}
+
+// snmpTranslateCall resolves the given OID with snmptranslate.
+func snmpTranslateCall(oid string) (mibName string, oidNum string, oidText string, conversion string, err error) {
 	var out []byte
 	if strings.ContainsAny(oid, ":abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
 		out, err = execCmd("snmptranslate", "-Td", "-Ob", oid)
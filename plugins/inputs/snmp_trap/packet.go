@@ -0,0 +1,393 @@
+package snmp_trap
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ASN.1 BER tags used by SNMP.
+const (
+	tagInteger        = 0x02
+	tagOctetString    = 0x04
+	tagNull           = 0x05
+	tagOID            = 0x06
+	tagSequence       = 0x30
+	tagIPAddress      = 0x40
+	tagCounter32      = 0x41
+	tagGauge32        = 0x42
+	tagTimeTicks      = 0x43
+	tagOpaque         = 0x44
+	tagCounter64      = 0x46
+	tagNoSuchObject   = 0x80
+	tagNoSuchInstance = 0x81
+	tagEndOfMibView   = 0x82
+
+	pduResponse = 0xa2
+	pduTrapV1   = 0xa4
+	pduInform   = 0xa6
+	pduTrapV2   = 0xa7
+	pduReport   = 0xa8
+)
+
+// SNMP message versions.
+const (
+	version1  = 0
+	version2c = 1
+	version3  = 3
+)
+
+var errTruncated = errors.New("truncated packet")
+
+// varbind is a decoded variable binding.
+type varbind struct {
+	oid   string
+	tag   byte
+	value interface{}
+}
+
+// packet is a decoded SNMP trap or inform.
+type packet struct {
+	version   int64
+	community string
+	pduType   byte
+	requestID int64
+	varbinds  []varbind
+
+	// rawVarbinds is the encoded varbind list, echoed back in the response to
+	// an inform.
+	rawVarbinds []byte
+
+	// SNMPv1 trap fields
+	enterprise   string
+	agentAddress string
+	genericTrap  int64
+	specificTrap int64
+	timestamp    uint64
+
+	// SNMPv3 fields
+	msgID           int64
+	msgFlags        byte
+	usm             usmParams
+	contextEngineID []byte
+	contextName     []byte
+}
+
+// trapOID returns the OID identifying the trap. For SNMPv1 traps this is
+// derived from the generic and specific trap numbers as described in RFC 3584.
+func (p *packet) trapOID() string {
+	if p.pduType == pduTrapV1 {
+		if p.genericTrap >= 0 && p.genericTrap < 6 {
+			return ".1.3.6.1.6.3.1.1.5." + strconv.FormatInt(p.genericTrap+1, 10)
+		}
+		return p.enterprise + ".0." + strconv.FormatInt(p.specificTrap, 10)
+	}
+	for _, vb := range p.varbinds {
+		if vb.oid == oidSnmpTrapOID {
+			if s, ok := vb.value.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+const (
+	oidSysUpTime   = ".1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = ".1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// readTLV reads a single BER encoded element from b, returning its tag, its
+// value, and the remainder of b.
+func readTLV(b []byte) (tag byte, value []byte, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	tag = b[0]
+	length := int(b[1])
+	offset := 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(b) < 2+n {
+			return 0, nil, nil, fmt.Errorf("invalid length encoding")
+		}
+		length = 0
+		for _, c := range b[2 : 2+n] {
+			length = length<<8 | int(c)
+		}
+		offset += n
+	}
+	if length < 0 || len(b) < offset+length {
+		return 0, nil, nil, errTruncated
+	}
+	return tag, b[offset : offset+length], b[offset+length:], nil
+}
+
+// readExpected reads an element from b, checking that it has the given tag.
+func readExpected(b []byte, expected byte, what string) ([]byte, []byte, error) {
+	tag, value, rest, err := readTLV(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %s", what, err)
+	}
+	if tag != expected {
+		return nil, nil, fmt.Errorf("reading %s: unexpected tag 0x%02x", what, tag)
+	}
+	return value, rest, nil
+}
+
+func readInt(b []byte, what string) (int64, []byte, error) {
+	value, rest, err := readExpected(b, tagInteger, what)
+	if err != nil {
+		return 0, nil, err
+	}
+	return parseInt(value), rest, nil
+}
+
+// parseInt decodes a two's complement integer.
+func parseInt(b []byte) int64 {
+	var v int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// parseUint decodes an unsigned integer.
+func parseUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// parseOID decodes an object identifier into dotted notation with a leading
+// dot, ie ".1.3.6.1".
+func parseOID(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", fmt.Errorf("empty OID")
+	}
+	var parts []string
+	var v uint64
+	for i, c := range b {
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return "", errTruncated
+			}
+			continue
+		}
+		if len(parts) == 0 {
+			// the first byte encodes the first two components
+			first := v / 40
+			if first > 2 {
+				first = 2
+			}
+			parts = append(parts, strconv.FormatUint(first, 10),
+				strconv.FormatUint(v-first*40, 10))
+		} else {
+			parts = append(parts, strconv.FormatUint(v, 10))
+		}
+		v = 0
+	}
+	return "." + strings.Join(parts, "."), nil
+}
+
+// decodeValue converts the BER encoded value of a varbind into a Go value.
+func decodeValue(tag byte, b []byte) (interface{}, error) {
+	switch tag {
+	case tagInteger:
+		return parseInt(b), nil
+	case tagOctetString, tagOpaque:
+		return b, nil
+	case tagOID:
+		return parseOID(b)
+	case tagIPAddress:
+		return net.IP(b).String(), nil
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		return parseUint(b), nil
+	case tagNull, tagNoSuchObject, tagNoSuchInstance, tagEndOfMibView:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type 0x%02x", tag)
+	}
+}
+
+// parseVarbinds decodes a varbind list.
+func parseVarbinds(b []byte) ([]varbind, error) {
+	var varbinds []varbind
+	for len(b) > 0 {
+		vb, rest, err := readExpected(b, tagSequence, "varbind")
+		if err != nil {
+			return nil, err
+		}
+		b = rest
+
+		oidBytes, vb, err := readExpected(vb, tagOID, "varbind oid")
+		if err != nil {
+			return nil, err
+		}
+		oid, err := parseOID(oidBytes)
+		if err != nil {
+			return nil, err
+		}
+		tag, value, _, err := readTLV(vb)
+		if err != nil {
+			return nil, fmt.Errorf("reading value of %s: %s", oid, err)
+		}
+		v, err := decodeValue(tag, value)
+		if err != nil {
+			return nil, fmt.Errorf("decoding value of %s: %s", oid, err)
+		}
+		varbinds = append(varbinds, varbind{oid: oid, tag: tag, value: v})
+	}
+	return varbinds, nil
+}
+
+// parsePDU decodes the PDU of a message into p.
+func parsePDU(b []byte, p *packet) error {
+	tag, pdu, _, err := readTLV(b)
+	if err != nil {
+		return fmt.Errorf("reading pdu: %s", err)
+	}
+	p.pduType = tag
+
+	var raw []byte
+	switch tag {
+	case pduTrapV1:
+		var enterprise, addr, ts []byte
+		if enterprise, pdu, err = readExpected(pdu, tagOID, "enterprise"); err != nil {
+			return err
+		}
+		if p.enterprise, err = parseOID(enterprise); err != nil {
+			return err
+		}
+		if addr, pdu, err = readExpected(pdu, tagIPAddress, "agent address"); err != nil {
+			return err
+		}
+		p.agentAddress = net.IP(addr).String()
+		if p.genericTrap, pdu, err = readInt(pdu, "generic trap"); err != nil {
+			return err
+		}
+		if p.specificTrap, pdu, err = readInt(pdu, "specific trap"); err != nil {
+			return err
+		}
+		if ts, pdu, err = readExpected(pdu, tagTimeTicks, "timestamp"); err != nil {
+			return err
+		}
+		p.timestamp = parseUint(ts)
+	case pduTrapV2, pduInform, pduResponse, pduReport:
+		if p.requestID, pdu, err = readInt(pdu, "request id"); err != nil {
+			return err
+		}
+		if _, pdu, err = readInt(pdu, "error status"); err != nil {
+			return err
+		}
+		if _, pdu, err = readInt(pdu, "error index"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported pdu type 0x%02x", tag)
+	}
+
+	if raw, _, err = readExpected(pdu, tagSequence, "varbind list"); err != nil {
+		return err
+	}
+	p.rawVarbinds = raw
+	p.varbinds, err = parseVarbinds(raw)
+	return err
+}
+
+// encodeTLV encodes a single BER element.
+func encodeTLV(tag byte, value []byte) []byte {
+	var out []byte
+	n := len(value)
+	switch {
+	case n < 0x80:
+		out = make([]byte, 0, 2+n)
+		out = append(out, tag, byte(n))
+	case n <= 0xff:
+		out = make([]byte, 0, 3+n)
+		out = append(out, tag, 0x81, byte(n))
+	case n <= 0xffff:
+		out = make([]byte, 0, 4+n)
+		out = append(out, tag, 0x82, byte(n>>8), byte(n))
+	default:
+		out = make([]byte, 0, 6+n)
+		out = append(out, tag, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, value...)
+}
+
+// encodeInt encodes a two's complement integer with the given tag.
+func encodeInt(tag byte, v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 0x7f || v < -0x80 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return encodeTLV(tag, b)
+}
+
+// encodeUint encodes an unsigned integer, such as a Counter32, with the
+// given tag.
+func encodeUint(tag byte, v uint64) []byte {
+	b := []byte{byte(v)}
+	for v > 0x7f {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return encodeTLV(tag, b)
+}
+
+// encodeOID encodes an object identifier in dotted notation.
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %s", oid)
+	}
+	nums := make([]uint64, len(parts))
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %s", oid)
+		}
+		nums[i] = n
+	}
+
+	var b []byte
+	nums = append([]uint64{nums[0]*40 + nums[1]}, nums[2:]...)
+	for _, n := range nums {
+		enc := []byte{byte(n & 0x7f)}
+		for n >>= 7; n > 0; n >>= 7 {
+			enc = append([]byte{byte(n&0x7f) | 0x80}, enc...)
+		}
+		b = append(b, enc...)
+	}
+	return encodeTLV(tagOID, b), nil
+}
+
+// encodeSequence concatenates the given encoded elements into a sequence or
+// PDU with the given tag.
+func encodeSequence(tag byte, elements ...[]byte) []byte {
+	var b []byte
+	for _, e := range elements {
+		b = append(b, e...)
+	}
+	return encodeTLV(tag, b)
+}
+
+// responsePDU builds the response to an inform, echoing its varbinds.
+func responsePDU(p *packet) []byte {
+	return encodeSequence(pduResponse,
+		encodeInt(tagInteger, p.requestID),
+		encodeInt(tagInteger, 0),
+		encodeInt(tagInteger, 0),
+		encodeTLV(tagSequence, p.rawVarbinds),
+	)
+}
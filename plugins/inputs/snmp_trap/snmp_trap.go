@@ -0,0 +1,349 @@
+package snmp_trap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/snmp"
+)
+
+// maxPacketSize is the largest UDP payload.
+const maxPacketSize = 64 * 1024
+
+// lookup translates OIDs, it is a variable so tests can mock it out.
+var lookup = snmp.SnmpTranslate
+
+const sampleConfig = `
+  ## Address and port to listen for traps and informs on.
+  # service_address = ":162"
+
+  ## Paths to directories of MIB files used to translate OIDs, as in the
+  ## snmp input. If unset, the net-snmp tools are used.
+  # path = ["/usr/share/snmp/mibs"]
+
+  ## SNMPv1 & SNMPv2c traps are accepted from any community. To accept
+  ## SNMPv3 traps and informs, configure the user they are sent by.
+  #sec_name = "myuser"
+  #auth_protocol = "md5"      # Values: "MD5", "SHA", ""
+  #auth_password = "pass"
+  #sec_level = "authNoPriv"   # Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+  #priv_protocol = ""         # Values: "DES", "AES", ""
+  #priv_password = ""
+
+  ## Engine ID, in hex, that SNMPv3 informs are sent to. SNMPv3 traps use the
+  ## engine ID of the sender. If unset, a random engine ID is generated at
+  ## startup, which senders discover automatically.
+  #engine_id = ""
+`
+
+// SnmpTrap receives SNMP traps and informs.
+type SnmpTrap struct {
+	ServiceAddress string
+	// Path to directories of MIB files used to translate OIDs.
+	Path []string
+
+	// Parameters for Version 3
+	// Values: "noAuthNoPriv", "authNoPriv", "authPriv"
+	SecLevel string
+	SecName  string
+	// Values: "MD5", "SHA", "". Default: ""
+	AuthProtocol string
+	AuthPassword string
+	// Values: "DES", "AES", "". Default: ""
+	PrivProtocol string
+	PrivPassword string
+	EngineID     string `toml:"engine_id"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	wg sync.WaitGroup
+
+	acc  telegraf.Accumulator
+	conn *net.UDPConn
+	usm  *usm
+	done chan struct{}
+	// malformed tracks the number of malformed packets
+	malformed int
+}
+
+func (s *SnmpTrap) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SnmpTrap) Description() string {
+	return "Receive SNMP traps and informs"
+}
+
+// All the work is done in the Start() function, so this is just a dummy
+// function.
+func (s *SnmpTrap) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (s *SnmpTrap) Start(acc telegraf.Accumulator) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.Path) > 0 {
+		if err := snmp.LoadMibsFromPath(s.Path, s.Log); err != nil {
+			return err
+		}
+	}
+
+	var engineID []byte
+	if s.EngineID != "" {
+		var err error
+		engineID, err = hex.DecodeString(strings.TrimPrefix(s.EngineID, "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid engine_id %s: %s", s.EngineID, err)
+		}
+	}
+	u, err := newUSM(s.SecName, s.SecLevel, s.AuthProtocol, s.AuthPassword,
+		s.PrivProtocol, s.PrivPassword, engineID)
+	if err != nil {
+		return err
+	}
+	s.usm = u
+
+	address, err := net.ResolveUDPAddr("udp", s.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	s.conn, err = net.ListenUDP("udp", address)
+	if err != nil {
+		return err
+	}
+
+	s.acc = acc
+	s.done = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.listen()
+
+	s.Log.Infof("Started SNMP trap receiver on %s", s.conn.LocalAddr())
+	return nil
+}
+
+func (s *SnmpTrap) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	close(s.done)
+	s.conn.Close()
+	s.wg.Wait()
+	s.Log.Infof("Stopped SNMP trap receiver on %s", s.ServiceAddress)
+}
+
+func (s *SnmpTrap) listen() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			if err, ok := err.(net.Error); ok && err.Temporary() {
+				continue
+			}
+			s.acc.AddError(fmt.Errorf("reading SNMP trap: %s", err))
+			return
+		}
+
+		msg := make([]byte, n)
+		copy(msg, buf[:n])
+		s.handle(msg, addr)
+	}
+}
+
+// handle decodes a single message, replying to it if it is an inform, and
+// adds a metric for the trap it contains.
+func (s *SnmpTrap) handle(msg []byte, addr *net.UDPAddr) {
+	p, err := s.decode(msg)
+	if err == errUnknownEngineID {
+		if p.msgFlags&flagReportable != 0 {
+			msg, err := s.usm.report(p)
+			s.reply(addr, msg, err)
+		}
+		return
+	}
+	if err != nil {
+		s.malformed++
+		if s.malformed == 1 || s.malformed%1000 == 0 {
+			s.Log.Errorf("Received %d malformed packets thus far, "+
+				"most recently from %s: %s", s.malformed, addr.IP, err)
+		}
+		return
+	}
+
+	switch p.pduType {
+	case pduResponse, pduReport:
+		return
+	case pduInform:
+		var response []byte
+		if p.version == version3 {
+			flags := p.msgFlags & (flagAuth | flagPriv)
+			response, err = s.usm.encode(p, responsePDU(p), flags, p.usm.userName)
+		} else {
+			response = encodeSequence(tagSequence,
+				encodeInt(tagInteger, p.version),
+				encodeTLV(tagOctetString, []byte(p.community)),
+				responsePDU(p),
+			)
+		}
+		s.reply(addr, response, err)
+	}
+
+	s.addTrap(p, addr)
+}
+
+func (s *SnmpTrap) reply(addr *net.UDPAddr, msg []byte, err error) {
+	if err == nil {
+		_, err = s.conn.WriteToUDP(msg, addr)
+	}
+	if err != nil {
+		s.acc.AddError(fmt.Errorf("replying to SNMP inform from %s: %s", addr.IP, err))
+	}
+}
+
+// decode decodes an SNMP message of any version.
+func (s *SnmpTrap) decode(msg []byte) (*packet, error) {
+	body, _, err := readExpected(msg, tagSequence, "message")
+	if err != nil {
+		return nil, err
+	}
+	p := &packet{}
+	if p.version, body, err = readInt(body, "version"); err != nil {
+		return nil, err
+	}
+
+	switch p.version {
+	case version1, version2c:
+		community, body, err := readExpected(body, tagOctetString, "community")
+		if err != nil {
+			return nil, err
+		}
+		p.community = string(community)
+		err = parsePDU(body, p)
+		if err == nil && (p.pduType == pduTrapV1) != (p.version == version1) {
+			err = fmt.Errorf("unexpected pdu type 0x%02x for version %s",
+				p.pduType, versionName(p.version))
+		}
+		return p, err
+	case version3:
+		return p, s.usm.decode(msg, body, p)
+	default:
+		return nil, fmt.Errorf("unsupported version %d", p.version)
+	}
+}
+
+func versionName(version int64) string {
+	switch version {
+	case version1:
+		return "1"
+	case version2c:
+		return "2c"
+	case version3:
+		return "3"
+	}
+	return fmt.Sprint(version)
+}
+
+// addTrap adds a metric with a field for each varbind of the trap, named
+// after the object it is an instance of.
+func (s *SnmpTrap) addTrap(p *packet, addr *net.UDPAddr) {
+	trapOID := p.trapOID()
+	mibName, trapName, _ := translate(trapOID)
+
+	tags := map[string]string{
+		"source":  addr.IP.String(),
+		"version": versionName(p.version),
+		"oid":     trapOID,
+		"name":    trapName,
+	}
+	if mibName != "" {
+		tags["mib"] = mibName
+	}
+	fields := map[string]interface{}{}
+
+	if p.pduType == pduTrapV1 {
+		tags["agent_address"] = p.agentAddress
+		_, name, _ := translate(oidSysUpTime)
+		fields[name] = p.timestamp
+	}
+
+	for _, vb := range p.varbinds {
+		if vb.oid == oidSnmpTrapOID || vb.value == nil {
+			continue
+		}
+		_, name, conversion := translate(vb.oid)
+		fields[name] = convert(conversion, vb.value)
+	}
+
+	s.acc.AddFields("snmp_trap", fields, tags, time.Now())
+}
+
+// translate resolves an OID to its MIB and object name, stripping any
+// instance index from the name. If the OID can't be resolved, the numeric OID
+// is used as the name.
+func translate(oid string) (mibName string, name string, conversion string) {
+	mibName, _, oidText, conversion, err := lookup(oid)
+	if err != nil || oidText == "" {
+		return "", oid, ""
+	}
+	if i := strings.Index(oidText, "."); i > 0 {
+		oidText = oidText[:i]
+	}
+	return mibName, oidText, conversion
+}
+
+// convert converts octet strings into text, as suggested by the textual
+// convention of the object, or as printable text if possible and hex
+// otherwise.
+func convert(conversion string, v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+
+	switch conversion {
+	case "hwaddr":
+		return net.HardwareAddr(b).String()
+	case "ipaddr":
+		if len(b) == net.IPv4len || len(b) == net.IPv6len {
+			return net.IP(b).String()
+		}
+	}
+
+	if utf8.Valid(b) {
+		printable := true
+		for _, r := range string(b) {
+			if r < ' ' && r != '\t' && r != '\n' && r != '\r' {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return string(b)
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+func init() {
+	inputs.Add("snmp_trap", func() telegraf.Input {
+		return &SnmpTrap{
+			ServiceAddress: ":162",
+		}
+	})
+}
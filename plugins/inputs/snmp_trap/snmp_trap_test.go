@@ -0,0 +1,437 @@
+package snmp_trap
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mibs = map[string][]string{
+	".1.3.6.1.6.3.1.1.5.3":       {"IF-MIB", "linkDown", ""},
+	".1.3.6.1.2.1.1.3.0":         {"DISMAN-EVENT-MIB", "sysUpTimeInstance", ""},
+	".1.3.6.1.2.1.2.2.1.1.2":     {"IF-MIB", "ifIndex.2", ""},
+	".1.3.6.1.2.1.2.2.1.2.2":     {"IF-MIB", "ifDescr.2", ""},
+	".1.3.6.1.2.1.2.2.1.6.2":     {"IF-MIB", "ifPhysAddress.2", "hwaddr"},
+	".1.3.6.1.2.1.2.2.1.8.2":     {"IF-MIB", "ifOperStatus.2", ""},
+	".1.3.6.1.6.3.1.1.4.3.0":     {"SNMPv2-MIB", "snmpTrapEnterprise.0", ""},
+	".1.3.6.1.4.1.8072.2.3.0.1":  {"NET-SNMP-EXAMPLES-MIB", "netSnmpExampleHeartbeatNotification", ""},
+	".1.3.6.1.4.1.8072.2.3.2.1":  {"NET-SNMP-EXAMPLES-MIB", "netSnmpExampleHeartbeatRate", ""},
+	".1.3.6.1.4.1.8072.2.3.2.99": nil,
+}
+
+func init() {
+	lookup = func(oid string) (string, string, string, string, error) {
+		t, ok := mibs[oid]
+		if !ok || t == nil {
+			return "", "", "", "", fmt.Errorf("unknown OID %s", oid)
+		}
+		return t[0], oid, t[1], t[2], nil
+	}
+}
+
+func mustOID(t *testing.T, oid string) []byte {
+	b, err := encodeOID(oid)
+	require.NoError(t, err)
+	return b
+}
+
+// linkDownVarbinds are the varbinds of an IF-MIB::linkDown notification.
+func linkDownVarbinds(t *testing.T) []byte {
+	return encodeSequence(tagSequence,
+		encodeSequence(tagSequence, mustOID(t, oidSysUpTime), encodeUint(tagTimeTicks, 123456)),
+		encodeSequence(tagSequence, mustOID(t, oidSnmpTrapOID), mustOID(t, ".1.3.6.1.6.3.1.1.5.3")),
+		linkDownObjects(t),
+	)
+}
+
+func linkDownObjects(t *testing.T) []byte {
+	return concat(
+		encodeSequence(tagSequence, mustOID(t, ".1.3.6.1.2.1.2.2.1.1.2"), encodeInt(tagInteger, 2)),
+		encodeSequence(tagSequence, mustOID(t, ".1.3.6.1.2.1.2.2.1.2.2"), encodeTLV(tagOctetString, []byte("eth0"))),
+		encodeSequence(tagSequence, mustOID(t, ".1.3.6.1.2.1.2.2.1.6.2"),
+			encodeTLV(tagOctetString, []byte{0x00, 0x1b, 0x21, 0x3c, 0x9d, 0xf8})),
+		encodeSequence(tagSequence, mustOID(t, ".1.3.6.1.2.1.2.2.1.8.2"), encodeInt(tagInteger, 2)),
+	)
+}
+
+var linkDownFields = map[string]interface{}{
+	"sysUpTimeInstance": uint64(123456),
+	"ifIndex":           int64(2),
+	"ifDescr":           "eth0",
+	"ifPhysAddress":     "00:1b:21:3c:9d:f8",
+	"ifOperStatus":      int64(2),
+}
+
+func v2cMessage(pdu []byte) []byte {
+	return encodeSequence(tagSequence,
+		encodeInt(tagInteger, version2c),
+		encodeTLV(tagOctetString, []byte("public")),
+		pdu,
+	)
+}
+
+func startTrap(t *testing.T, s *SnmpTrap) (*testutil.Accumulator, *net.UDPConn) {
+	s.ServiceAddress = "127.0.0.1:0"
+	s.Log = testutil.Logger{Name: "inputs.snmp_trap"}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+
+	conn, err := net.DialUDP("udp", nil, s.conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	return acc, conn
+}
+
+// waitMetrics waits for the accumulator to receive n metrics.
+func waitMetrics(t *testing.T, acc *testutil.Accumulator, n int) {
+	for i := 0; i < 100 && acc.NMetrics() < uint64(n); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, uint64(n), acc.NMetrics())
+}
+
+func readResponse(t *testing.T, conn *net.UDPConn) []byte {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, maxPacketSize)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
+
+func TestReceiveTrapV1(t *testing.T) {
+	s := &SnmpTrap{}
+	acc, conn := startTrap(t, s)
+	defer s.Stop()
+	defer conn.Close()
+
+	msg := encodeSequence(tagSequence,
+		encodeInt(tagInteger, version1),
+		encodeTLV(tagOctetString, []byte("public")),
+		encodeSequence(pduTrapV1,
+			mustOID(t, ".1.3.6.1.4.1.8072.2.3"),
+			encodeTLV(tagIPAddress, []byte{192, 168, 1, 10}),
+			encodeInt(tagInteger, 6),
+			encodeInt(tagInteger, 1),
+			encodeUint(tagTimeTicks, 4200),
+			encodeSequence(tagSequence,
+				encodeSequence(tagSequence, mustOID(t, ".1.3.6.1.4.1.8072.2.3.2.1"), encodeInt(tagInteger, 30)),
+				encodeSequence(tagSequence, mustOID(t, ".1.3.6.1.4.1.8072.2.3.2.99"), encodeInt(tagInteger, -1)),
+			),
+		),
+	)
+	_, err := conn.Write(msg)
+	require.NoError(t, err)
+	waitMetrics(t, acc, 1)
+
+	acc.AssertContainsTaggedFields(t, "snmp_trap",
+		map[string]interface{}{
+			"sysUpTimeInstance":           uint64(4200),
+			"netSnmpExampleHeartbeatRate": int64(30),
+			".1.3.6.1.4.1.8072.2.3.2.99":  int64(-1),
+		},
+		map[string]string{
+			"source":        "127.0.0.1",
+			"version":       "1",
+			"oid":           ".1.3.6.1.4.1.8072.2.3.0.1",
+			"name":          "netSnmpExampleHeartbeatNotification",
+			"mib":           "NET-SNMP-EXAMPLES-MIB",
+			"agent_address": "192.168.1.10",
+		},
+	)
+}
+
+func TestReceiveTrapV2c(t *testing.T) {
+	s := &SnmpTrap{}
+	acc, conn := startTrap(t, s)
+	defer s.Stop()
+	defer conn.Close()
+
+	msg := v2cMessage(encodeSequence(pduTrapV2,
+		encodeInt(tagInteger, 1234),
+		encodeInt(tagInteger, 0),
+		encodeInt(tagInteger, 0),
+		linkDownVarbinds(t),
+	))
+	_, err := conn.Write(msg)
+	require.NoError(t, err)
+	waitMetrics(t, acc, 1)
+
+	acc.AssertContainsTaggedFields(t, "snmp_trap", linkDownFields,
+		map[string]string{
+			"source":  "127.0.0.1",
+			"version": "2c",
+			"oid":     ".1.3.6.1.6.3.1.1.5.3",
+			"name":    "linkDown",
+			"mib":     "IF-MIB",
+		},
+	)
+}
+
+func TestReceiveInformV2c(t *testing.T) {
+	s := &SnmpTrap{}
+	acc, conn := startTrap(t, s)
+	defer s.Stop()
+	defer conn.Close()
+
+	msg := v2cMessage(encodeSequence(pduInform,
+		encodeInt(tagInteger, 1234),
+		encodeInt(tagInteger, 0),
+		encodeInt(tagInteger, 0),
+		linkDownVarbinds(t),
+	))
+	_, err := conn.Write(msg)
+	require.NoError(t, err)
+
+	response, err := s.decode(readResponse(t, conn))
+	require.NoError(t, err)
+	assert.Equal(t, byte(pduResponse), response.pduType)
+	assert.Equal(t, int64(1234), response.requestID)
+	assert.Equal(t, "public", response.community)
+	assert.Len(t, response.varbinds, 6)
+
+	waitMetrics(t, acc, 1)
+	acc.AssertContainsFields(t, "snmp_trap", linkDownFields)
+}
+
+func TestReceiveMalformed(t *testing.T) {
+	s := &SnmpTrap{}
+	acc, conn := startTrap(t, s)
+	defer s.Stop()
+	defer conn.Close()
+
+	for _, msg := range [][]byte{
+		[]byte("not snmp"),
+		// v1 PDU in a v2c message
+		v2cMessage(encodeSequence(pduTrapV1)),
+		// truncated varbind list
+		v2cMessage(encodeSequence(pduTrapV2,
+			encodeInt(tagInteger, 1),
+			encodeInt(tagInteger, 0),
+			encodeInt(tagInteger, 0),
+			linkDownVarbinds(t)[:20],
+		)),
+	} {
+		_, err := conn.Write(msg)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 100 && s.malformed < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 3, s.malformed)
+	assert.Equal(t, uint64(0), acc.NMetrics())
+}
+
+func TestReceiveTrapV3(t *testing.T) {
+	senderEngineID := []byte{0x80, 0x00, 0x1f, 0x88, 0x80, 0x01, 0x02, 0x03, 0x04}
+
+	for _, level := range []struct {
+		secLevel, authProtocol, privProtocol string
+	}{
+		{"noAuthNoPriv", "", ""},
+		{"authNoPriv", "MD5", ""},
+		{"authNoPriv", "SHA", ""},
+		{"authPriv", "MD5", "DES"},
+		{"authPriv", "SHA", "AES"},
+	} {
+		s := &SnmpTrap{
+			SecName:      "myuser",
+			SecLevel:     level.secLevel,
+			AuthProtocol: level.authProtocol,
+			AuthPassword: "authpassword",
+			PrivProtocol: level.privProtocol,
+			PrivPassword: "privpassword",
+		}
+		acc, conn := startTrap(t, s)
+
+		sender, err := newUSM(s.SecName, s.SecLevel, s.AuthProtocol, s.AuthPassword,
+			s.PrivProtocol, s.PrivPassword, senderEngineID)
+		require.NoError(t, err)
+		pdu := encodeSequence(pduTrapV2,
+			encodeInt(tagInteger, 1234),
+			encodeInt(tagInteger, 0),
+			encodeInt(tagInteger, 0),
+			linkDownVarbinds(t),
+		)
+		msg, err := sender.encode(&packet{msgID: 1}, pdu, sender.msgFlags, []byte("myuser"))
+		require.NoError(t, err)
+
+		// a message from an unknown user, or with a different password, is
+		// rejected.
+		other, err := newUSM("otheruser", s.SecLevel, s.AuthProtocol, "otherpassword",
+			s.PrivProtocol, "otherpassword", senderEngineID)
+		require.NoError(t, err)
+		unknown, err := other.encode(&packet{msgID: 2}, pdu, other.msgFlags, []byte("otheruser"))
+		require.NoError(t, err)
+
+		for _, m := range [][]byte{unknown, msg} {
+			_, err = conn.Write(m)
+			require.NoError(t, err)
+		}
+		waitMetrics(t, acc, 1)
+		assert.Equal(t, 1, s.malformed, level.secLevel)
+
+		acc.AssertContainsTaggedFields(t, "snmp_trap", linkDownFields,
+			map[string]string{
+				"source":  "127.0.0.1",
+				"version": "3",
+				"oid":     ".1.3.6.1.6.3.1.1.5.3",
+				"name":    "linkDown",
+				"mib":     "IF-MIB",
+			},
+		)
+
+		conn.Close()
+		s.Stop()
+	}
+}
+
+func TestReceiveInformV3(t *testing.T) {
+	s := &SnmpTrap{
+		SecName:      "myuser",
+		SecLevel:     "authPriv",
+		AuthProtocol: "SHA",
+		AuthPassword: "authpassword",
+		PrivProtocol: "AES",
+		PrivPassword: "privpassword",
+		EngineID:     "0x8000000001020304",
+	}
+	acc, conn := startTrap(t, s)
+	defer s.Stop()
+	defer conn.Close()
+
+	// discover the engine ID of the receiver
+	discovery := encodeSequence(tagSequence,
+		encodeInt(tagInteger, version3),
+		encodeSequence(tagSequence,
+			encodeInt(tagInteger, 1),
+			encodeInt(tagInteger, 65507),
+			encodeTLV(tagOctetString, []byte{flagReportable}),
+			encodeInt(tagInteger, userSecurityModel),
+		),
+		encodeTLV(tagOctetString, encodeSequence(tagSequence,
+			encodeTLV(tagOctetString, nil),
+			encodeInt(tagInteger, 0),
+			encodeInt(tagInteger, 0),
+			encodeTLV(tagOctetString, nil),
+			encodeTLV(tagOctetString, nil),
+			encodeTLV(tagOctetString, nil),
+		)),
+		encodeSequence(tagSequence,
+			encodeTLV(tagOctetString, nil),
+			encodeTLV(tagOctetString, nil),
+			encodeSequence(0xa0,
+				encodeInt(tagInteger, 42),
+				encodeInt(tagInteger, 0),
+				encodeInt(tagInteger, 0),
+				encodeTLV(tagSequence, nil),
+			),
+		),
+	)
+	_, err := conn.Write(discovery)
+	require.NoError(t, err)
+
+	noAuth, err := newUSM("", "", "", "", "", "", nil)
+	require.NoError(t, err)
+	report := &packet{}
+	body, _, err := readExpected(readResponse(t, conn), tagSequence, "message")
+	require.NoError(t, err)
+	report.version, body, err = readInt(body, "version")
+	require.NoError(t, err)
+	require.NoError(t, noAuth.decode(nil, body, report))
+	assert.Equal(t, byte(pduReport), report.pduType)
+	assert.Equal(t, int64(42), report.requestID)
+	assert.Equal(t, []byte{0x80, 0, 0, 0, 1, 2, 3, 4}, report.usm.engineID)
+	require.Len(t, report.varbinds, 1)
+	assert.Equal(t, oidUnknownEngineIDs, report.varbinds[0].oid)
+
+	// send an inform to the discovered engine ID
+	sender, err := newUSM(s.SecName, s.SecLevel, s.AuthProtocol, s.AuthPassword,
+		s.PrivProtocol, s.PrivPassword, report.usm.engineID)
+	require.NoError(t, err)
+	pdu := encodeSequence(pduInform,
+		encodeInt(tagInteger, 1234),
+		encodeInt(tagInteger, 0),
+		encodeInt(tagInteger, 0),
+		linkDownVarbinds(t),
+	)
+	msg, err := sender.encode(&packet{msgID: 2}, pdu, sender.msgFlags|flagReportable, []byte("myuser"))
+	require.NoError(t, err)
+	_, err = conn.Write(msg)
+	require.NoError(t, err)
+
+	response := &packet{}
+	respMsg := readResponse(t, conn)
+	body, _, err = readExpected(respMsg, tagSequence, "message")
+	require.NoError(t, err)
+	response.version, body, err = readInt(body, "version")
+	require.NoError(t, err)
+	require.NoError(t, sender.decode(respMsg, body, response))
+	assert.Equal(t, byte(pduResponse), response.pduType)
+	assert.Equal(t, int64(2), response.msgID)
+	assert.Equal(t, int64(1234), response.requestID)
+
+	waitMetrics(t, acc, 1)
+	acc.AssertContainsFields(t, "snmp_trap", linkDownFields)
+}
+
+func TestCheckTime(t *testing.T) {
+	u, err := newUSM("myuser", "authNoPriv", "SHA", "authpassword", "", "", nil)
+	require.NoError(t, err)
+
+	// informs are timely in the window of our engine time
+	assert.NoError(t, u.checkTime(&usmParams{engineID: u.engineID, engineBoots: 1, engineTime: 10}))
+	assert.Error(t, u.checkTime(&usmParams{engineID: u.engineID, engineBoots: 1, engineTime: 1000}))
+	assert.Error(t, u.checkTime(&usmParams{engineID: u.engineID, engineBoots: 2, engineTime: 0}))
+
+	// traps are timely in the window of the time last received from their
+	// engine
+	engineID := []byte{0x80, 0, 0, 0, 1}
+	trap := func(boots, engineTime int64) error {
+		return u.checkTime(&usmParams{engineID: engineID, engineBoots: boots, engineTime: engineTime})
+	}
+	assert.NoError(t, trap(5, 1000))
+	assert.NoError(t, trap(5, 900))
+	assert.Error(t, trap(5, 800))
+	assert.Error(t, trap(4, 2000))
+	assert.NoError(t, trap(6, 0))
+	assert.Error(t, trap(5, 1000))
+
+	// the times of a bounded number of engines are kept
+	for i := 0; i < maxEngines+10; i++ {
+		id := []byte(fmt.Sprint(i))
+		require.NoError(t, u.checkTime(&usmParams{engineID: id, engineBoots: 1, engineTime: 0}))
+	}
+	assert.Len(t, u.engines, maxEngines)
+}
+
+func TestConvert(t *testing.T) {
+	assert.Equal(t, "eth0", convert("", []byte("eth0")))
+	assert.Equal(t, "0001ff", convert("", []byte{0, 1, 255}))
+	assert.Equal(t, "00:01:ff", convert("hwaddr", []byte{0, 1, 255}))
+	assert.Equal(t, "10.0.0.1", convert("ipaddr", []byte{10, 0, 0, 1}))
+	assert.Equal(t, int64(5), convert("", int64(5)))
+}
+
+func TestOIDEncoding(t *testing.T) {
+	for _, oid := range []string{".1.3.6.1.2.1.1.3.0", ".1.3.6.1.4.1.2636.4294967295", ".2.999.1"} {
+		b, err := encodeOID(oid)
+		require.NoError(t, err)
+		value, _, err := readExpected(b, tagOID, "oid")
+		require.NoError(t, err)
+		decoded, err := parseOID(value)
+		require.NoError(t, err)
+		assert.Equal(t, oid, decoded)
+	}
+}
+
+func TestIntEncoding(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 2147483647, -2147483648} {
+		value, _, err := readExpected(encodeInt(tagInteger, v), tagInteger, "int")
+		require.NoError(t, err)
+		assert.Equal(t, v, parseInt(value))
+	}
+}
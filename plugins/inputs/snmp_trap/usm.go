@@ -0,0 +1,580 @@
+package snmp_trap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// SNMPv3 message flags.
+const (
+	flagAuth       = 0x01
+	flagPriv       = 0x02
+	flagReportable = 0x04
+)
+
+// userSecurityModel is the only SNMPv3 security model supported.
+const userSecurityModel = 3
+
+// oidUnknownEngineIDs is usmStatsUnknownEngineIDs.0, reported to senders of
+// informs that don't know our engine ID yet.
+const oidUnknownEngineIDs = ".1.3.6.1.6.3.15.1.1.4.0"
+
+// timeWindow is the number of seconds the engine time of an authenticated
+// message may lag behind the one of its engine, per RFC 3414 section 3.2.
+const timeWindow = 150
+
+// maxEngineBoots is the engine boots value of engines that must be
+// reconfigured, whose messages are never in the time window.
+const maxEngineBoots = 2147483647
+
+// maxEngines is the number of engines whose time is kept.
+const maxEngines = 1000
+
+// errUnknownEngineID is returned when decoding an engine ID discovery
+// request, which should be answered with a report.
+var errUnknownEngineID = errors.New("unknown engine id")
+
+// errNotInTimeWindow is returned when decoding an authenticated message
+// which is not timely, such as a replayed one.
+var errNotInTimeWindow = errors.New("message not in time window")
+
+// usmParams are the User-based Security Model parameters of a message.
+type usmParams struct {
+	engineID    []byte
+	engineBoots int64
+	engineTime  int64
+	userName    []byte
+	authParams  []byte
+	privParams  []byte
+}
+
+// usm holds the SNMPv3 user that traps are accepted from, and the engine
+// identity used to receive informs.
+type usm struct {
+	userName     string
+	authProtocol string
+	authPassword string
+	privProtocol string
+	privPassword string
+	msgFlags     byte
+
+	engineID    []byte
+	engineBoots int64
+	startTime   time.Time
+
+	// password digests, computed once as they are expensive
+	authDigest []byte
+	privDigest []byte
+
+	// time of the engines that sent authenticated traps, by engine ID
+	engines map[string]*engineClock
+
+	unknownEngineIDs uint64
+}
+
+type localizedKeys struct {
+	auth []byte
+	priv []byte
+}
+
+// engineClock is our notion of the boots and time of an authoritative
+// engine, as described in RFC 3414 section 2.3.
+type engineClock struct {
+	boots int64
+	time  int64
+	// latestTime is the highest engine time received for boots.
+	latestTime int64
+	// synced is when time was received.
+	synced time.Time
+}
+
+func newUSM(
+	userName, secLevel, authProtocol, authPassword, privProtocol, privPassword string,
+	engineID []byte,
+) (*usm, error) {
+	u := &usm{
+		userName:     userName,
+		authProtocol: strings.ToLower(authProtocol),
+		authPassword: authPassword,
+		privProtocol: strings.ToLower(privProtocol),
+		privPassword: privPassword,
+		engineID:     engineID,
+		engineBoots:  1,
+		startTime:    time.Now(),
+		engines:      make(map[string]*engineClock),
+	}
+
+	switch strings.ToLower(secLevel) {
+	case "noauthnopriv", "":
+		u.msgFlags = 0
+	case "authnopriv":
+		u.msgFlags = flagAuth
+	case "authpriv":
+		u.msgFlags = flagAuth | flagPriv
+	default:
+		return nil, fmt.Errorf("invalid sec_level %s", secLevel)
+	}
+
+	switch u.authProtocol {
+	case "md5", "sha":
+	case "":
+		if u.msgFlags&flagAuth != 0 {
+			return nil, fmt.Errorf("auth_protocol is required for sec_level %s", secLevel)
+		}
+	default:
+		return nil, fmt.Errorf("invalid auth_protocol %s", authProtocol)
+	}
+
+	switch u.privProtocol {
+	case "des", "aes":
+	case "":
+		if u.msgFlags&flagPriv != 0 {
+			return nil, fmt.Errorf("priv_protocol is required for sec_level %s", secLevel)
+		}
+	default:
+		return nil, fmt.Errorf("invalid priv_protocol %s", privProtocol)
+	}
+
+	if u.engineID == nil {
+		// RFC 3411 engine ID in the format of net-snmp's enterprise number,
+		// followed by random octets.
+		u.engineID = []byte{0x80, 0x00, 0x1f, 0x88, 0x80}
+		random := make([]byte, 8)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		u.engineID = append(u.engineID, random...)
+	}
+	return u, nil
+}
+
+func (u *usm) hash() func() hash.Hash {
+	if u.authProtocol == "sha" {
+		return sha1.New
+	}
+	return md5.New
+}
+
+// localize returns the auth & priv keys localized to the given engine ID, as
+// described in RFC 3414 section 2.6.
+func (u *usm) localize(engineID []byte) *localizedKeys {
+	if u.authDigest == nil && u.authPassword != "" {
+		u.authDigest = passwordDigest(u.hash(), u.authPassword)
+	}
+	if u.privDigest == nil && u.privPassword != "" {
+		u.privDigest = passwordDigest(u.hash(), u.privPassword)
+	}
+
+	keys := &localizedKeys{}
+	if u.authDigest != nil {
+		keys.auth = localizeKey(u.hash(), u.authDigest, engineID)
+	}
+	if u.privDigest != nil {
+		keys.priv = localizeKey(u.hash(), u.privDigest, engineID)
+	}
+	return keys
+}
+
+// passwordDigest hashes a megabyte of the repeated password.
+func passwordDigest(newHash func() hash.Hash, password string) []byte {
+	h := newHash()
+	buf := make([]byte, 64)
+	for count := 0; count < 1048576; count += 64 {
+		for i := range buf {
+			buf[i] = password[(count+i)%len(password)]
+		}
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+func localizeKey(newHash func() hash.Hash, digest []byte, engineID []byte) []byte {
+	h := newHash()
+	h.Write(digest)
+	h.Write(engineID)
+	h.Write(digest)
+	return h.Sum(nil)
+}
+
+// engineTime is the number of seconds since the receiver started.
+func (u *usm) engineTime() int64 {
+	return int64(time.Since(u.startTime) / time.Second)
+}
+
+// checkTime checks that the authenticated message with the given parameters
+// is in the time window, as described in RFC 3414 section 3.2 step 7. Our
+// engine is authoritative for informs sent to it, and the sender is for
+// traps, whose time is synchronized from their messages.
+func (u *usm) checkTime(params *usmParams) error {
+	if string(params.engineID) == string(u.engineID) {
+		if u.engineBoots == maxEngineBoots ||
+			params.engineBoots != u.engineBoots ||
+			abs(params.engineTime-u.engineTime()) > timeWindow {
+			return errNotInTimeWindow
+		}
+		return nil
+	}
+
+	clock, ok := u.engines[string(params.engineID)]
+	if !ok {
+		if len(u.engines) >= maxEngines {
+			// forget any engine, whose time is synchronized again from its
+			// next message.
+			for id := range u.engines {
+				delete(u.engines, id)
+				break
+			}
+		}
+		clock = &engineClock{boots: params.engineBoots}
+		u.engines[string(params.engineID)] = clock
+		clock.sync(params)
+		return nil
+	}
+	if params.engineBoots > clock.boots ||
+		(params.engineBoots == clock.boots && params.engineTime > clock.latestTime) {
+		clock.boots = params.engineBoots
+		clock.sync(params)
+	}
+
+	now := clock.time + int64(time.Since(clock.synced)/time.Second)
+	if clock.boots == maxEngineBoots ||
+		params.engineBoots < clock.boots ||
+		(params.engineBoots == clock.boots && params.engineTime < now-timeWindow) {
+		return errNotInTimeWindow
+	}
+	return nil
+}
+
+func (c *engineClock) sync(params *usmParams) {
+	c.time = params.engineTime
+	c.latestTime = params.engineTime
+	c.synced = time.Now()
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// decode decodes the SNMPv3 message msg, whose version has already been read
+// leaving body, into p, authenticating and decrypting it.
+func (u *usm) decode(msg []byte, body []byte, p *packet) error {
+	global, body, err := readExpected(body, tagSequence, "global data")
+	if err != nil {
+		return err
+	}
+	if p.msgID, global, err = readInt(global, "message id"); err != nil {
+		return err
+	}
+	if _, global, err = readInt(global, "max size"); err != nil {
+		return err
+	}
+	flags, global, err := readExpected(global, tagOctetString, "flags")
+	if err != nil {
+		return err
+	}
+	if len(flags) != 1 {
+		return fmt.Errorf("invalid flags")
+	}
+	p.msgFlags = flags[0]
+	model, _, err := readInt(global, "security model")
+	if err != nil {
+		return err
+	}
+	if model != userSecurityModel {
+		return fmt.Errorf("unsupported security model %d", model)
+	}
+
+	secParams, body, err := readExpected(body, tagOctetString, "security parameters")
+	if err != nil {
+		return err
+	}
+	params, _, err := readExpected(secParams, tagSequence, "usm parameters")
+	if err != nil {
+		return err
+	}
+	if p.usm.engineID, params, err = readExpected(params, tagOctetString, "engine id"); err != nil {
+		return err
+	}
+	if p.usm.engineBoots, params, err = readInt(params, "engine boots"); err != nil {
+		return err
+	}
+	if p.usm.engineTime, params, err = readInt(params, "engine time"); err != nil {
+		return err
+	}
+	if p.usm.userName, params, err = readExpected(params, tagOctetString, "user name"); err != nil {
+		return err
+	}
+	if p.usm.authParams, params, err = readExpected(params, tagOctetString, "auth parameters"); err != nil {
+		return err
+	}
+	if p.usm.privParams, _, err = readExpected(params, tagOctetString, "priv parameters"); err != nil {
+		return err
+	}
+
+	if len(p.usm.engineID) == 0 {
+		// engine ID discovery, the scoped PDU is in plain text.
+		if seq, _, err := readExpected(body, tagSequence, "scoped pdu"); err == nil {
+			p.requestID = discoveryRequestID(seq)
+		}
+		return errUnknownEngineID
+	}
+
+	if string(p.usm.userName) != u.userName {
+		return fmt.Errorf("unknown user %q", p.usm.userName)
+	}
+	if p.msgFlags&(flagAuth|flagPriv) != u.msgFlags {
+		return fmt.Errorf("unsupported security level for user %q", p.usm.userName)
+	}
+
+	keys := u.localize(p.usm.engineID)
+	if p.msgFlags&flagAuth != 0 {
+		if err := u.authenticate(msg, p.usm.authParams, keys.auth); err != nil {
+			return err
+		}
+		if err := u.checkTime(&p.usm); err != nil {
+			return err
+		}
+	}
+
+	scoped := body
+	if p.msgFlags&flagPriv != 0 {
+		encrypted, _, err := readExpected(body, tagOctetString, "encrypted pdu")
+		if err != nil {
+			return err
+		}
+		if scoped, err = u.decrypt(encrypted, &p.usm, keys.priv); err != nil {
+			return err
+		}
+	}
+
+	seq, _, err := readExpected(scoped, tagSequence, "scoped pdu")
+	if err != nil {
+		return err
+	}
+	if p.contextEngineID, seq, err = readExpected(seq, tagOctetString, "context engine id"); err != nil {
+		return err
+	}
+	if p.contextName, seq, err = readExpected(seq, tagOctetString, "context name"); err != nil {
+		return err
+	}
+	return parsePDU(seq, p)
+}
+
+// discoveryRequestID returns the request id of the PDU of a discovery
+// request, which is usually a get request rather than a trap or inform.
+func discoveryRequestID(seq []byte) int64 {
+	var err error
+	if _, seq, err = readExpected(seq, tagOctetString, "context engine id"); err != nil {
+		return 0
+	}
+	if _, seq, err = readExpected(seq, tagOctetString, "context name"); err != nil {
+		return 0
+	}
+	_, pdu, _, err := readTLV(seq)
+	if err != nil {
+		return 0
+	}
+	id, _, _ := readInt(pdu, "request id")
+	return id
+}
+
+// authenticate checks the HMAC-96 of msg, which is calculated with the auth
+// parameters zeroed out. authParams must be a slice of msg.
+func (u *usm) authenticate(msg []byte, authParams []byte, key []byte) error {
+	if len(authParams) != 12 {
+		return fmt.Errorf("invalid auth parameters length %d", len(authParams))
+	}
+	offset := cap(msg) - cap(authParams)
+	zeroed := make([]byte, len(msg))
+	copy(zeroed, msg)
+	for i := offset; i < offset+len(authParams); i++ {
+		zeroed[i] = 0
+	}
+
+	mac := hmac.New(u.hash(), key)
+	mac.Write(zeroed)
+	if !hmac.Equal(mac.Sum(nil)[:12], authParams) {
+		return fmt.Errorf("authentication failed for user %q", u.userName)
+	}
+	return nil
+}
+
+func (u *usm) decrypt(encrypted []byte, params *usmParams, key []byte) ([]byte, error) {
+	if len(params.privParams) != 8 {
+		return nil, fmt.Errorf("invalid priv parameters length %d", len(params.privParams))
+	}
+	plain := make([]byte, len(encrypted))
+	switch u.privProtocol {
+	case "des":
+		if len(encrypted)%des.BlockSize != 0 {
+			return nil, fmt.Errorf("invalid encrypted pdu length %d", len(encrypted))
+		}
+		block, err := des.NewCipher(key[:8])
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCBCDecrypter(block, desIV(key, params.privParams)).CryptBlocks(plain, encrypted)
+	case "aes":
+		block, err := aes.NewCipher(key[:16])
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCFBDecrypter(block, aesIV(params)).XORKeyStream(plain, encrypted)
+	}
+	return plain, nil
+}
+
+func (u *usm) encrypt(plain []byte, params *usmParams, key []byte) ([]byte, error) {
+	switch u.privProtocol {
+	case "des":
+		if n := len(plain) % des.BlockSize; n != 0 {
+			plain = append(plain, make([]byte, des.BlockSize-n)...)
+		}
+		block, err := des.NewCipher(key[:8])
+		if err != nil {
+			return nil, err
+		}
+		encrypted := make([]byte, len(plain))
+		cipher.NewCBCEncrypter(block, desIV(key, params.privParams)).CryptBlocks(encrypted, plain)
+		return encrypted, nil
+	case "aes":
+		block, err := aes.NewCipher(key[:16])
+		if err != nil {
+			return nil, err
+		}
+		encrypted := make([]byte, len(plain))
+		cipher.NewCFBEncrypter(block, aesIV(params)).XORKeyStream(encrypted, plain)
+		return encrypted, nil
+	}
+	return nil, fmt.Errorf("invalid priv_protocol %s", u.privProtocol)
+}
+
+// desIV is the pre-IV from the second half of the key, xored with the salt.
+func desIV(key []byte, salt []byte) []byte {
+	iv := make([]byte, des.BlockSize)
+	for i := range iv {
+		iv[i] = key[8+i] ^ salt[i]
+	}
+	return iv
+}
+
+// aesIV is the engine boots and time followed by the salt.
+func aesIV(params *usmParams) []byte {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint32(iv[0:], uint32(params.engineBoots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(params.engineTime))
+	copy(iv[8:], params.privParams)
+	return iv
+}
+
+// encode builds an SNMPv3 message from our engine containing the given PDU,
+// authenticated and encrypted as p, which it is a reply to, was.
+func (u *usm) encode(p *packet, pdu []byte, flags byte, userName []byte) ([]byte, error) {
+	params := usmParams{
+		engineID:    u.engineID,
+		engineBoots: u.engineBoots,
+		engineTime:  u.engineTime(),
+		userName:    userName,
+	}
+	keys := u.localize(u.engineID)
+
+	scoped := encodeSequence(tagSequence,
+		encodeTLV(tagOctetString, u.engineID),
+		encodeTLV(tagOctetString, p.contextName),
+		pdu,
+	)
+	msgData := scoped
+	if flags&flagPriv != 0 {
+		params.privParams = make([]byte, 8)
+		if _, err := rand.Read(params.privParams); err != nil {
+			return nil, err
+		}
+		encrypted, err := u.encrypt(scoped, &params, keys.priv)
+		if err != nil {
+			return nil, err
+		}
+		msgData = encodeTLV(tagOctetString, encrypted)
+	}
+	if flags&flagAuth != 0 {
+		params.authParams = make([]byte, 12)
+	}
+
+	// encode the message, keeping track of where the auth parameters are so
+	// they can be filled in once the whole message is known.
+	usmHead := concat(
+		encodeTLV(tagOctetString, params.engineID),
+		encodeInt(tagInteger, params.engineBoots),
+		encodeInt(tagInteger, params.engineTime),
+		encodeTLV(tagOctetString, params.userName),
+	)
+	usmContent := concat(usmHead,
+		encodeTLV(tagOctetString, params.authParams),
+		encodeTLV(tagOctetString, params.privParams),
+	)
+	usmSeq := encodeTLV(tagSequence, usmContent)
+	secParams := encodeTLV(tagOctetString, usmSeq)
+	msgHead := concat(
+		encodeInt(tagInteger, version3),
+		encodeSequence(tagSequence,
+			encodeInt(tagInteger, p.msgID),
+			encodeInt(tagInteger, 65507),
+			encodeTLV(tagOctetString, []byte{flags}),
+			encodeInt(tagInteger, userSecurityModel),
+		),
+	)
+	msgContent := concat(msgHead, secParams, msgData)
+	msg := encodeTLV(tagSequence, msgContent)
+
+	if flags&flagAuth != 0 {
+		offset := len(msg) - len(msgContent) +
+			len(msgHead) +
+			len(secParams) - len(usmSeq) +
+			len(usmSeq) - len(usmContent) +
+			len(usmHead) + 2
+
+		mac := hmac.New(u.hash(), keys.auth)
+		mac.Write(msg)
+		copy(msg[offset:offset+12], mac.Sum(nil)[:12])
+	}
+	return msg, nil
+}
+
+// report builds the report sent in reply to an engine ID discovery request.
+func (u *usm) report(p *packet) ([]byte, error) {
+	u.unknownEngineIDs++
+	oid, err := encodeOID(oidUnknownEngineIDs)
+	if err != nil {
+		return nil, err
+	}
+	pdu := encodeSequence(pduReport,
+		encodeInt(tagInteger, p.requestID),
+		encodeInt(tagInteger, 0),
+		encodeInt(tagInteger, 0),
+		encodeSequence(tagSequence,
+			encodeSequence(tagSequence, oid, encodeUint(tagCounter32, u.unknownEngineIDs)),
+		),
+	)
+	return u.encode(p, pdu, 0, nil)
+}
+
+func concat(elements ...[]byte) []byte {
+	var b []byte
+	for _, e := range elements {
+		b = append(b, e...)
+	}
+	return b
+}
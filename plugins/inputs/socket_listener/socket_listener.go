@@ -0,0 +1,503 @@
+package socket_listener
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// defaultMaxMessageSize is the largest line or length-prefixed message
+// accepted on stream sockets, unless max_message_size is set.
+const defaultMaxMessageSize = 1024 * 1024
+
+// defaultRefusalMessage is the message sent to the clients refused once the
+// maximum number of connections is reached.
+const defaultRefusalMessage = "Telegraf maximum concurrent connections (%d)" +
+	" reached, closing.\nYou may want to increase max_connections" +
+	" in the Telegraf socket listener configuration.\n"
+
+// udpMaxPacketSize is the largest datagram read, see
+// https://en.wikipedia.org/wiki/User_Datagram_Protocol#Packet_structure
+const udpMaxPacketSize = 64 * 1024
+
+type setReadBufferer interface {
+	SetReadBuffer(bytes int) error
+}
+
+type streamSocketListener struct {
+	net.Listener
+	*SocketListener
+
+	sockType  string
+	tlsConfig *tls.Config
+
+	// Lock for preventing a data race during resource cleanup
+	cleanup sync.Mutex
+	wg      sync.WaitGroup
+	// accept channel tracks how many active connections there are, if there
+	// is an available bool in accept, then we are below the maximum and can
+	// accept the connection
+	accept chan bool
+	// track current connections so we can close them in Stop()
+	conns map[string]net.Conn
+}
+
+func (ssl *streamSocketListener) listen() {
+	defer ssl.wg.Done()
+
+	for {
+		c, err := ssl.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				ssl.AddError(err)
+			}
+			return
+		}
+
+		if ssl.accept != nil {
+			select {
+			case <-ssl.accept:
+			default:
+				// We are over the connection limit, refuse & close. Tell the
+				// client why we are closing, unless it expects TLS.
+				if ssl.tlsConfig == nil {
+					msg := ssl.RefusalMessage
+					if msg == "" {
+						msg = defaultRefusalMessage
+					}
+					fmt.Fprintf(c, msg, ssl.MaxConnections)
+				}
+				c.Close()
+				ssl.Log.Infof("Refused %s connection from %s, maximum connections "+
+					"(%d) reached", ssl.sockType, c.RemoteAddr(), ssl.MaxConnections)
+				continue
+			}
+		}
+
+		if err := ssl.setupConn(c); err != nil {
+			ssl.AddError(err)
+			c.Close()
+			if ssl.accept != nil {
+				ssl.accept <- true
+			}
+			continue
+		}
+
+		if ssl.tlsConfig != nil {
+			c = tls.Server(c, ssl.tlsConfig)
+		}
+
+		ssl.wg.Add(1)
+		// generate a random id for this connection
+		id := internal.RandomString(6)
+		ssl.remember(id, c)
+		go ssl.read(c, id)
+	}
+}
+
+// setupConn applies the read buffer size and keep alive settings to a new
+// connection.
+func (ssl *streamSocketListener) setupConn(c net.Conn) error {
+	if ssl.ReadBufferSize > 0 {
+		if srb, ok := c.(setReadBufferer); ok {
+			if err := srb.SetReadBuffer(ssl.ReadBufferSize); err != nil {
+				return fmt.Errorf("setting read buffer size: %s", err)
+			}
+		} else {
+			ssl.Log.Infof("Unable to set read buffer on a %s socket", ssl.sockType)
+		}
+	}
+
+	if ssl.KeepAlivePeriod == nil {
+		return nil
+	}
+	tcpc, ok := c.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if ssl.KeepAlivePeriod.Duration == 0 {
+		return tcpc.SetKeepAlive(false)
+	}
+	if err := tcpc.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return tcpc.SetKeepAlivePeriod(ssl.KeepAlivePeriod.Duration)
+}
+
+// read reads the messages of a single connection.
+func (ssl *streamSocketListener) read(c net.Conn, id string) {
+	defer func() {
+		ssl.wg.Done()
+		c.Close()
+		ssl.forget(id)
+		if ssl.accept != nil {
+			// Add one connection potential back to channel when this one closes
+			ssl.accept <- true
+		}
+	}()
+
+	maxSize := ssl.maxMessageSize()
+	var next func() ([]byte, error)
+	if ssl.Framing == "length-prefixed" {
+		r := bufio.NewReader(c)
+		var length [4]byte
+		var msg bytes.Buffer
+		next = func() ([]byte, error) {
+			if _, err := io.ReadFull(r, length[:]); err != nil {
+				return nil, err
+			}
+			n := int64(binary.BigEndian.Uint32(length[:]))
+			if n > maxSize {
+				return nil, fmt.Errorf("message length %d exceeds the maximum of %d", n, maxSize)
+			}
+			// the message is read as it arrives rather than allocated at
+			// once, as the length is sent by the client.
+			msg.Reset()
+			if _, err := io.CopyN(&msg, r, n); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			return msg.Bytes(), nil
+		}
+	} else {
+		scnr := bufio.NewScanner(c)
+		scnr.Buffer(make([]byte, 64*1024), int(maxSize))
+		next = func() ([]byte, error) {
+			if !scnr.Scan() {
+				if err := scnr.Err(); err != nil {
+					return nil, err
+				}
+				return nil, io.EOF
+			}
+			return scnr.Bytes(), nil
+		}
+	}
+
+	for {
+		if ssl.ReadTimeout != nil && ssl.ReadTimeout.Duration > 0 {
+			c.SetReadDeadline(time.Now().Add(ssl.ReadTimeout.Duration))
+		}
+
+		msg, err := next()
+		if err != nil {
+			if err != io.EOF && !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				ssl.AddError(fmt.Errorf("reading from %s: %s", c.RemoteAddr(), err))
+			}
+			return
+		}
+		ssl.parse(msg)
+	}
+}
+
+func (ssl *streamSocketListener) Close() error {
+	err := ssl.Listener.Close()
+
+	var conns []net.Conn
+	ssl.cleanup.Lock()
+	for _, c := range ssl.conns {
+		conns = append(conns, c)
+	}
+	ssl.cleanup.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+
+	ssl.wg.Wait()
+	return err
+}
+
+// forget a connection
+func (ssl *streamSocketListener) forget(id string) {
+	ssl.cleanup.Lock()
+	defer ssl.cleanup.Unlock()
+	delete(ssl.conns, id)
+}
+
+// remember a connection
+func (ssl *streamSocketListener) remember(id string, c net.Conn) {
+	ssl.cleanup.Lock()
+	defer ssl.cleanup.Unlock()
+	ssl.conns[id] = c
+}
+
+type packetSocketListener struct {
+	net.PacketConn
+	*SocketListener
+
+	path string
+	wg   sync.WaitGroup
+}
+
+func (psl *packetSocketListener) listen() {
+	defer psl.wg.Done()
+
+	buf := make([]byte, udpMaxPacketSize)
+	for {
+		n, _, err := psl.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				psl.AddError(err)
+			}
+			return
+		}
+		psl.parse(buf[:n])
+	}
+}
+
+func (psl *packetSocketListener) Close() error {
+	err := psl.PacketConn.Close()
+	psl.wg.Wait()
+	if psl.path != "" {
+		// unlike stream sockets, datagram sockets aren't removed on close.
+		os.Remove(psl.path)
+	}
+	return err
+}
+
+type SocketListener struct {
+	ServiceAddress string
+
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	MaxConnections  int
+	ReadBufferSize  int
+	ReadTimeout     *internal.Duration
+	KeepAlivePeriod *internal.Duration
+	Framing         string
+	MaxMessageSize  internal.Size
+
+	// RefusalMessage is the message sent to the clients refused once there
+	// are MaxConnections connections, formatted with MaxConnections, for the
+	// plugins implemented with the socket listener.
+	RefusalMessage string `toml:"-"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	parsers.Parser
+	telegraf.Accumulator
+	io.Closer
+}
+
+func (sl *SocketListener) maxMessageSize() int64 {
+	if sl.MaxMessageSize.Size > 0 {
+		return sl.MaxMessageSize.Size
+	}
+	return defaultMaxMessageSize
+}
+
+func (sl *SocketListener) Description() string {
+	return "Generic socket listener capable of handling multiple socket types."
+}
+
+func (sl *SocketListener) SampleConfig() string {
+	return `
+  ## URL to listen on
+  # service_address = "tcp://:8094"
+  # service_address = "tcp://127.0.0.1:http"
+  # service_address = "tcp4://:8094"
+  # service_address = "tcp6://:8094"
+  # service_address = "tcp6://[2001:db8::1]:8094"
+  # service_address = "udp://:8094"
+  # service_address = "udp4://:8094"
+  # service_address = "udp6://:8094"
+  # service_address = "unix:///tmp/telegraf.sock"
+  # service_address = "unixgram:///tmp/telegraf.sock"
+
+  ## TLS Config, for stream sockets only.
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## If set, clients must present a certificate signed by one of these CAs.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Maximum number of concurrent connections, for stream sockets only.
+  ## 0 means unlimited.
+  # max_connections = 1024
+
+  ## Read timeout, for stream sockets only.
+  ## 0 means no timeout.
+  # read_timeout = "30s"
+
+  ## Maximum socket buffer size in bytes.
+  ## For stream sockets, once the buffer fills up, the sender will start
+  ## backing up. For datagram sockets, once the buffer fills up, metrics will
+  ## start dropping. Defaults to the OS default.
+  # read_buffer_size = 65535
+
+  ## Period between keep alive probes, for TCP sockets only.
+  ## 0 disables keep alive probes. Defaults to the OS configuration.
+  # keep_alive_period = "5m"
+
+  ## Framing of messages on stream sockets:
+  ##   "newline": each line is a message.
+  ##   "length-prefixed": each message is preceded by its length in bytes,
+  ##   as a 4 byte big-endian unsigned integer.
+  ## Each datagram of a datagram socket is always a message.
+  # framing = "newline"
+
+  ## Maximum size of a message on stream sockets, as a number of bytes or
+  ## with a unit, ie, "1MiB". Connections sending larger ones are closed.
+  # max_message_size = "1MiB"
+
+  ## Data format to consume.
+  ## Each data format has it's own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  # data_format = "influx"
+`
+}
+
+// All the work is done in the Start() function, so this is just a dummy
+// function.
+func (sl *SocketListener) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (sl *SocketListener) SetParser(parser parsers.Parser) {
+	sl.Parser = parser
+}
+
+func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
+	sl.Accumulator = acc
+
+	spl := strings.SplitN(sl.ServiceAddress, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid service address: %s", sl.ServiceAddress)
+	}
+	protocol, address := spl[0], spl[1]
+
+	switch sl.Framing {
+	case "", "newline", "length-prefixed":
+	default:
+		return fmt.Errorf("unknown framing %q", sl.Framing)
+	}
+
+	tlsConfig, err := internal.GetServerTLSConfig(
+		sl.TLSCert, sl.TLSKey, sl.TLSAllowedCACerts)
+	if err != nil {
+		return err
+	}
+
+	switch protocol {
+	case "tcp", "tcp4", "tcp6", "unix":
+		if protocol == "unix" {
+			// remove a socket left behind by an unclean shutdown.
+			os.Remove(address)
+		}
+		l, err := net.Listen(protocol, address)
+		if err != nil {
+			return err
+		}
+		sl.Log.Infof("Listening on %s://%s", protocol, l.Addr())
+
+		ssl := &streamSocketListener{
+			Listener:       l,
+			SocketListener: sl,
+			sockType:       protocol,
+			tlsConfig:      tlsConfig,
+			conns:          make(map[string]net.Conn),
+		}
+		if sl.MaxConnections > 0 {
+			ssl.accept = make(chan bool, sl.MaxConnections)
+			for i := 0; i < sl.MaxConnections; i++ {
+				ssl.accept <- true
+			}
+		}
+
+		sl.Closer = ssl
+		ssl.wg.Add(1)
+		go ssl.listen()
+	case "udp", "udp4", "udp6", "unixgram":
+		if tlsConfig != nil {
+			return fmt.Errorf("TLS is not supported on %s sockets", protocol)
+		}
+		psl := &packetSocketListener{
+			SocketListener: sl,
+		}
+		if protocol == "unixgram" {
+			os.Remove(address)
+			psl.path = address
+		}
+		pc, err := net.ListenPacket(protocol, address)
+		if err != nil {
+			return err
+		}
+		psl.PacketConn = pc
+		sl.Log.Infof("Listening on %s://%s", protocol, pc.LocalAddr())
+
+		if sl.ReadBufferSize > 0 {
+			if srb, ok := pc.(setReadBufferer); ok {
+				if err := srb.SetReadBuffer(sl.ReadBufferSize); err != nil {
+					pc.Close()
+					return fmt.Errorf("setting read buffer size: %s", err)
+				}
+			} else {
+				sl.Log.Infof("Unable to set read buffer on a %s socket", protocol)
+			}
+		}
+
+		sl.Closer = psl
+		psl.wg.Add(1)
+		go psl.listen()
+	default:
+		return fmt.Errorf("unknown protocol '%s' in '%s'", protocol, sl.ServiceAddress)
+	}
+
+	return nil
+}
+
+// parse parses a message and adds its metrics to the accumulator.
+func (sl *SocketListener) parse(msg []byte) {
+	if len(msg) == 0 {
+		return
+	}
+	metrics, err := sl.Parse(msg)
+	if err != nil {
+		sl.AddError(fmt.Errorf("unable to parse incoming message: %s", err))
+		return
+	}
+	for _, m := range metrics {
+		sl.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+}
+
+func (sl *SocketListener) Stop() {
+	if sl.Closer != nil {
+		sl.Close()
+		sl.Closer = nil
+	}
+}
+
+func newSocketListener() *SocketListener {
+	parser, _ := parsers.NewInfluxParser()
+
+	return &SocketListener{
+		Parser: parser,
+	}
+}
+
+func init() {
+	inputs.Add("socket_listener", func() telegraf.Input { return newSocketListener() })
+}
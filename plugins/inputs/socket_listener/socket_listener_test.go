@@ -0,0 +1,292 @@
+package socket_listener
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketListener_tcp(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.ReadBufferSize = 1024
+	sl.KeepAlivePeriod = &internal.Duration{Duration: time.Minute}
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+
+	testSocketListener(t, sl, client)
+}
+
+func TestSocketListener_tls(t *testing.T) {
+	pki := testutil.NewPKI()
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.TLSCert = pki.ServerCertPath()
+	sl.TLSKey = pki.ServerKeyPath()
+	sl.TLSAllowedCACerts = []string{pki.CACertPath()}
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	addr := sl.Closer.(net.Listener).Addr().String()
+	client, err := tls.Dial("tcp", addr, pki.ClientTLSConfig())
+	require.NoError(t, err)
+
+	testSocketListener(t, sl, client)
+
+	// clients without a certificate are refused.
+	config := pki.ClientTLSConfig()
+	config.Certificates = nil
+	client, err = tls.Dial("tcp", addr, config)
+	if err == nil {
+		defer client.Close()
+		_, err = client.Write([]byte("test,foo=bar v=1i 123456789\n"))
+		if err == nil {
+			_, err = client.Read(make([]byte, 1))
+		}
+	}
+	assert.Error(t, err)
+}
+
+func TestSocketListener_udp(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "udp://127.0.0.1:0"
+	sl.ReadBufferSize = 1024
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("udp", sl.Closer.(net.PacketConn).LocalAddr().String())
+	require.NoError(t, err)
+
+	testSocketListener(t, sl, client)
+}
+
+func TestSocketListener_unix(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	sock := filepath.Join(tmpdir, "sl.TestSocketListener_unix.sock")
+
+	// a socket left behind is replaced.
+	f, err := os.Create(sock)
+	require.NoError(t, err)
+	f.Close()
+
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "unix://" + sock
+	sl.ReadBufferSize = 1024
+
+	acc := &testutil.Accumulator{}
+	err = sl.Start(acc)
+	require.NoError(t, err)
+
+	client, err := net.Dial("unix", sock)
+	require.NoError(t, err)
+
+	testSocketListener(t, sl, client)
+
+	sl.Stop()
+	_, err = os.Stat(sock)
+	assert.True(t, os.IsNotExist(err), "socket not removed on stop")
+}
+
+func TestSocketListener_unixgram(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	sock := filepath.Join(tmpdir, "sl.TestSocketListener_unixgram.sock")
+
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "unixgram://" + sock
+	sl.ReadBufferSize = 1024
+
+	acc := &testutil.Accumulator{}
+	err = sl.Start(acc)
+	require.NoError(t, err)
+
+	client, err := net.Dial("unixgram", sock)
+	require.NoError(t, err)
+
+	testSocketListener(t, sl, client)
+
+	sl.Stop()
+	_, err = os.Stat(sock)
+	assert.True(t, os.IsNotExist(err), "socket not removed on stop")
+}
+
+func TestSocketListener_lengthPrefixed(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.Framing = "length-prefixed"
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	buf := &bytes.Buffer{}
+	for _, msg := range []string{
+		"test,foo=bar v=1i 123456789",
+		"test,foo=baz v=2i 123456790\ntest,foo=zab v=3i 123456791\n",
+	} {
+		binary.Write(buf, binary.BigEndian, uint32(len(msg)))
+		buf.WriteString(msg)
+	}
+	_, err = client.Write(buf.Bytes())
+	require.NoError(t, err)
+
+	acc.Wait(3)
+	acc.Lock()
+	defer acc.Unlock()
+	require.Len(t, acc.Metrics, 3)
+	assert.Equal(t, map[string]string{"foo": "bar"}, acc.Metrics[0].Tags)
+	assert.Equal(t, map[string]interface{}{"v": int64(3)}, acc.Metrics[2].Fields)
+}
+
+func TestSocketListener_maxMessageSize(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.Framing = "length-prefixed"
+	sl.MaxMessageSize = internal.Size{Size: 64}
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	// the connection is closed on a message larger than the maximum
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, uint32(1<<31))
+	_, err = client.Write(buf.Bytes())
+	require.NoError(t, err)
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = client.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+
+	acc.Lock()
+	defer acc.Unlock()
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "exceeds the maximum of 64")
+}
+
+func TestSocketListener_maxConnections(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{Name: "inputs.socket_listener"}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.MaxConnections = 1
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	addr := sl.Closer.(net.Listener).Addr().String()
+	client, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("test,foo=bar v=1i 123456789\n"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	refused, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer refused.Close()
+	refused.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf, err := ioutil.ReadAll(refused)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"Telegraf maximum concurrent connections (1) reached, closing.\n"+
+			"You may want to increase max_connections in"+
+			" the Telegraf socket listener configuration.\n",
+		string(buf))
+
+	refused.Write([]byte("test,foo=baz v=2i 123456790\n"))
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, uint64(1), acc.NMetrics())
+}
+
+func TestSocketListener_badConfig(t *testing.T) {
+	pki := testutil.NewPKI()
+	for _, sl := range []*SocketListener{
+		{ServiceAddress: ":8094"},
+		{ServiceAddress: "foo://:8094"},
+		{ServiceAddress: "tcp://127.0.0.1:0", Framing: "foo"},
+		{ServiceAddress: "udp://127.0.0.1:0", TLSCert: pki.ServerCertPath(), TLSKey: pki.ServerKeyPath()},
+	} {
+		assert.Error(t, sl.Start(&testutil.Accumulator{}), sl.ServiceAddress)
+	}
+}
+
+func testSocketListener(t *testing.T, sl *SocketListener, client net.Conn) {
+	mstr12 := "test,foo=bar v=1i 123456789\ntest,foo=baz v=2i 123456790\n"
+	mstr3 := "test,foo=zab v=3i 123456791"
+	client.Write([]byte(mstr12))
+	client.Write([]byte(mstr3))
+	if _, ok := client.LocalAddr().(*net.UDPAddr); !ok {
+		// stream connection. needs trailing newline to terminate mstr3
+		client.Write([]byte{'\n'})
+	}
+
+	acc := sl.Accumulator.(*testutil.Accumulator)
+	acc.Wait(3)
+
+	acc.Lock()
+	defer acc.Unlock()
+	require.Len(t, acc.Metrics, 3)
+
+	m := acc.Metrics[0]
+	assert.Equal(t, "test", m.Measurement)
+	assert.Equal(t, map[string]string{"foo": "bar"}, m.Tags)
+	assert.Equal(t, map[string]interface{}{"v": int64(1)}, m.Fields)
+	assert.True(t, time.Unix(0, 123456789).Equal(m.Time))
+
+	m = acc.Metrics[1]
+	assert.Equal(t, map[string]string{"foo": "baz"}, m.Tags)
+	assert.Equal(t, map[string]interface{}{"v": int64(2)}, m.Fields)
+
+	m = acc.Metrics[2]
+	assert.Equal(t, map[string]string{"foo": "zab"}, m.Tags)
+	assert.Equal(t, map[string]interface{}{"v": int64(3)}, m.Fields)
+
+	assert.Empty(t, acc.Errors)
+}
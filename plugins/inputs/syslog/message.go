@@ -0,0 +1,350 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var severities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// sdElement is an element of the structured data of an RFC 5424 message.
+type sdElement struct {
+	id     string
+	params []sdParam
+}
+
+type sdParam struct {
+	name  string
+	value string
+}
+
+// message is a parsed syslog message. Fields which are absent from the
+// message are empty.
+type message struct {
+	facility int
+	severity int
+	// version is 1 for RFC 5424 messages, and 0 for RFC 3164 messages.
+	version   int
+	timestamp time.Time
+	hostname  string
+	appname   string
+	procid    string
+	msgid     string
+	sd        []sdElement
+	message   string
+}
+
+// parseMessage parses an RFC 5424 or RFC 3164 message. now is used to fill in
+// the year of RFC 3164 timestamps, which have none.
+func parseMessage(b []byte, now time.Time) (*message, error) {
+	m := &message{}
+	rest, err := m.parsePriority(b)
+	if err != nil {
+		return nil, err
+	}
+	// an RFC 5424 message has a version following the priority, where an
+	// RFC 3164 message has a timestamp.
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits < len(rest) && rest[digits] == ' ' {
+		err = m.parseRFC5424(rest)
+	} else {
+		err = m.parseRFC3164(rest, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parsePriority parses the <PRI> prefix common to both formats.
+func (m *message) parsePriority(b []byte) ([]byte, error) {
+	if len(b) < 3 || b[0] != '<' {
+		return nil, fmt.Errorf("expected priority at start of message")
+	}
+	end := bytes.IndexByte(b[:min(len(b), 5)], '>')
+	if end < 2 {
+		return nil, fmt.Errorf("invalid priority")
+	}
+	pri, err := strconv.Atoi(string(b[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, fmt.Errorf("invalid priority %q", b[1:end])
+	}
+	m.facility = pri / 8
+	m.severity = pri % 8
+	return b[end+1:], nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parseRFC5424 parses the rest of an RFC 5424 message following the priority:
+//
+//	VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP
+//	STRUCTURED-DATA [SP MSG]
+func (m *message) parseRFC5424(b []byte) error {
+	fields := make([]string, 6)
+	for i := range fields {
+		sp := bytes.IndexByte(b, ' ')
+		if sp == -1 {
+			return fmt.Errorf("expected %d header fields, got %d", len(fields)+1, i+1)
+		}
+		fields[i] = string(b[:sp])
+		b = b[sp+1:]
+	}
+
+	version, err := strconv.Atoi(fields[0])
+	if err != nil || version != 1 {
+		return fmt.Errorf("unsupported version %q", fields[0])
+	}
+	m.version = version
+
+	if fields[1] != "-" {
+		if m.timestamp, err = time.Parse(time.RFC3339Nano, fields[1]); err != nil {
+			return fmt.Errorf("invalid timestamp %q", fields[1])
+		}
+	}
+	m.hostname = nilValue(fields[2])
+	m.appname = nilValue(fields[3])
+	m.procid = nilValue(fields[4])
+	m.msgid = nilValue(fields[5])
+
+	if b, err = m.parseStructuredData(b); err != nil {
+		return err
+	}
+	if len(b) > 0 {
+		if b[0] != ' ' {
+			return fmt.Errorf("expected space after structured data")
+		}
+		// the message may start with a UTF-8 byte order mark.
+		b = bytes.TrimPrefix(b[1:], []byte("\xef\xbb\xbf"))
+		m.message = string(bytes.TrimRight(b, "\r\n"))
+	}
+	return nil
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// parseStructuredData parses the structured data of an RFC 5424 message,
+// returning the remainder of the message.
+func (m *message) parseStructuredData(b []byte) ([]byte, error) {
+	if len(b) > 0 && b[0] == '-' {
+		return b[1:], nil
+	}
+	for len(b) > 0 && b[0] == '[' {
+		var el sdElement
+		b = b[1:]
+		end := bytes.IndexAny(b, " ]")
+		if end <= 0 {
+			return nil, fmt.Errorf("invalid structured data element")
+		}
+		el.id = string(b[:end])
+		b = b[end:]
+
+		for len(b) > 0 && b[0] == ' ' {
+			b = b[1:]
+			eq := bytes.IndexByte(b, '=')
+			if eq <= 0 || len(b) < eq+2 || b[eq+1] != '"' {
+				return nil, fmt.Errorf("invalid structured data parameter in %s", el.id)
+			}
+			name := string(b[:eq])
+			b = b[eq+2:]
+
+			// the value is quoted, with ", \ and ] escaped by a backslash.
+			var value []byte
+			closed := false
+			for i := 0; i < len(b); i++ {
+				c := b[i]
+				if c == '\\' && i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']') {
+					value = append(value, b[i+1])
+					i++
+					continue
+				}
+				if c == '"' {
+					b = b[i+1:]
+					closed = true
+					break
+				}
+				value = append(value, c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated structured data parameter %s in %s", name, el.id)
+			}
+			el.params = append(el.params, sdParam{name: name, value: string(value)})
+		}
+		if len(b) == 0 || b[0] != ']' {
+			return nil, fmt.Errorf("unterminated structured data element %s", el.id)
+		}
+		b = b[1:]
+		m.sd = append(m.sd, el)
+	}
+	if m.sd == nil {
+		return nil, fmt.Errorf("expected structured data")
+	}
+	return b, nil
+}
+
+// rfc3164Layouts are the timestamp layouts accepted in RFC 3164 messages:
+// the standard one, and the RFC 3339 timestamps many senders use instead.
+var rfc3164Layouts = []string{time.StampMicro, time.Stamp, time.RFC3339Nano}
+
+// parseRFC3164 parses the rest of an RFC 3164 message following the priority:
+//
+//	TIMESTAMP SP HOSTNAME SP TAG[PID]: MSG
+//
+// As the format is loosely followed, the hostname and tag are optional, and
+// a message with no timestamp is taken to be all message.
+func (m *message) parseRFC3164(b []byte, now time.Time) error {
+	b = bytes.TrimRight(b, "\r\n")
+
+	rest := b
+	for _, layout := range rfc3164Layouts {
+		n := len(layout)
+		if layout == time.RFC3339Nano {
+			n = bytes.IndexByte(b, ' ')
+			if n == -1 {
+				n = len(b)
+			}
+		}
+		if len(b) < n {
+			continue
+		}
+		t, err := time.ParseInLocation(layout, string(b[:n]), now.Location())
+		if err != nil {
+			continue
+		}
+		if layout != time.RFC3339Nano {
+			// pick the year that puts the timestamp closest to now.
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.Sub(now) > 24*time.Hour {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+		m.timestamp = t
+		rest = bytes.TrimLeft(b[n:], " ")
+		break
+	}
+	if m.timestamp.IsZero() {
+		m.message = string(b)
+		return nil
+	}
+
+	// the hostname is followed by a space, and the tag by a colon or [pid].
+	if sp := bytes.IndexByte(rest, ' '); sp > 0 && !isTagWord(rest[:sp]) {
+		m.hostname = string(rest[:sp])
+		rest = rest[sp+1:]
+	}
+	if end := bytes.IndexAny(rest, "[: "); end > 0 && isTag(rest[:end+1]) {
+		m.appname = string(rest[:end])
+		rest = rest[end:]
+		if rest[0] == '[' {
+			if end := bytes.IndexByte(rest, ']'); end > 0 {
+				m.procid = string(rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+		rest = bytes.TrimPrefix(rest, []byte(":"))
+		rest = bytes.TrimLeft(rest, " ")
+	}
+	m.message = string(rest)
+	return nil
+}
+
+// isTag returns whether b is a tag followed by its terminator, ie "sshd["
+// or "cron:".
+func isTag(b []byte) bool {
+	if len(b) < 2 || len(b) > 49 {
+		return false
+	}
+	if end := b[len(b)-1]; end != ':' && end != '[' {
+		return false
+	}
+	for _, c := range b[:len(b)-1] {
+		if c == ' ' || c == ':' || c == '[' || c == ']' {
+			return false
+		}
+	}
+	return utf8.Valid(b)
+}
+
+// isTagWord returns whether the word w is a tag rather than a hostname.
+func isTagWord(w []byte) bool {
+	return w[len(w)-1] == ':' || bytes.IndexByte(w, '[') > 0
+}
+
+// tags returns the tags of the metric for the message.
+func (m *message) tags() map[string]string {
+	tags := map[string]string{
+		"severity": severities[m.severity],
+		"facility": facilityName(m.facility),
+	}
+	if m.hostname != "" {
+		tags["hostname"] = m.hostname
+	}
+	if m.appname != "" {
+		tags["appname"] = m.appname
+	}
+	return tags
+}
+
+func facilityName(facility int) string {
+	if facility < len(facilities) {
+		return facilities[facility]
+	}
+	return strconv.Itoa(facility)
+}
+
+// fields returns the fields of the metric for the message. Each structured
+// data element is a boolean field named after its id, and each parameter a
+// field named after the element id and parameter name, joined by separator.
+func (m *message) fields(separator string) map[string]interface{} {
+	fields := map[string]interface{}{
+		"facility_code": m.facility,
+		"severity_code": m.severity,
+	}
+	if m.version != 0 {
+		fields["version"] = m.version
+	}
+	if !m.timestamp.IsZero() {
+		fields["timestamp"] = m.timestamp.UnixNano()
+	}
+	if m.procid != "" {
+		fields["procid"] = m.procid
+	}
+	if m.msgid != "" {
+		fields["msgid"] = m.msgid
+	}
+	if m.message != "" {
+		fields["message"] = strings.TrimRight(m.message, " ")
+	}
+	for _, el := range m.sd {
+		fields[el.id] = true
+		for _, param := range el.params {
+			fields[el.id+separator+param.name] = param.value
+		}
+	}
+	return fields
+}
@@ -0,0 +1,458 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// maxMessageLength is the largest message accepted on streams, and the
+// largest datagram read.
+const maxMessageLength = 64 * 1024
+
+// maxLengthDigits is the number of digits of maxMessageLength, the longest
+// length accepted with octet-counting framing.
+var maxLengthDigits = len(strconv.Itoa(maxMessageLength))
+
+type Syslog struct {
+	Address string `toml:"server"`
+
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	KeepAlivePeriod *internal.Duration
+	ReadTimeout     *internal.Duration
+	MaxConnections  int
+
+	Framing          string
+	Trailer          string
+	SdparamSeparator string `toml:"sdparam_separator"`
+
+	sync.Mutex
+	// Lock for preventing a data race during resource cleanup
+	cleanup sync.Mutex
+	wg      sync.WaitGroup
+
+	done chan struct{}
+	// accept channel tracks how many active connections there are, if there
+	// is an available bool in accept, then we are below the maximum and can
+	// accept the connection
+	accept chan bool
+
+	listener   net.Listener
+	packetConn net.PacketConn
+	// track current connections so we can close them in Stop()
+	conns map[string]net.Conn
+
+	trailer byte
+	acc     telegraf.Accumulator
+	// now returns the current time, and is replaced in tests.
+	now func() time.Time
+}
+
+const sampleConfig = `
+  ## Address to listen on, as "protocol://address". The protocol is one of
+  ## tcp, tcp4, tcp6, udp, udp4, udp6 or unix.
+  ##   ie, server = "tcp://:6514"
+  ##       server = "udp://:514"
+  ##       server = "unix:///var/run/telegraf-syslog.sock"
+  server = "tcp://:6514"
+
+  ## TLS Config, for stream sockets only.
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## If set, clients must present a certificate signed by one of these CAs.
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Period between keep alive probes, for TCP sockets only.
+  ## 0 disables keep alive probes. Defaults to the OS configuration.
+  # keep_alive_period = "5m"
+
+  ## Timeout for reading a message from a connection. 0 means no timeout.
+  # read_timeout = "5s"
+
+  ## Maximum number of concurrent connections, for stream sockets only.
+  ## 0 means unlimited.
+  # max_connections = 1024
+
+  ## Framing of messages on stream sockets, per RFC 6587:
+  ##   "octet-counting": each message is prefixed by its length.
+  ##   "non-transparent": each message is terminated by the trailer.
+  ##   "auto": detected from the first byte of each message.
+  # framing = "auto"
+
+  ## Trailer terminating messages with non-transparent framing, "LF" or "NUL".
+  # trailer = "LF"
+
+  ## Separator joining the id of a structured data element to the names of
+  ## its parameters in the field names.
+  # sdparam_separator = "_"
+`
+
+func (s *Syslog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Syslog) Description() string {
+	return "Accepts syslog messages per RFC 5424 and RFC 3164"
+}
+
+// All the work is done in the Start() function, so this is just a dummy
+// function.
+func (s *Syslog) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts the syslog listener service.
+func (s *Syslog) Start(acc telegraf.Accumulator) error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, err := url.Parse(s.Address)
+	if err != nil {
+		return fmt.Errorf("invalid server address %q: %s", s.Address, err)
+	}
+	address := u.Host
+	if u.Scheme == "unix" {
+		address = u.Path
+	}
+
+	switch s.Framing {
+	case "", "auto", "octet-counting", "non-transparent":
+	default:
+		return fmt.Errorf("unknown framing %q", s.Framing)
+	}
+	switch strings.ToUpper(s.Trailer) {
+	case "", "LF":
+		s.trailer = '\n'
+	case "NUL":
+		s.trailer = 0
+	default:
+		return fmt.Errorf("unknown trailer %q", s.Trailer)
+	}
+
+	s.acc = acc
+	s.done = make(chan struct{})
+	if s.now == nil {
+		s.now = time.Now
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6", "unix":
+		tlsConfig, err := internal.GetServerTLSConfig(
+			s.TLSCert, s.TLSKey, s.TLSAllowedCACerts)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "unix" {
+			os.Remove(address)
+		}
+		l, err := net.Listen(u.Scheme, address)
+		if err != nil {
+			return err
+		}
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+		}
+		s.listener = l
+
+		s.conns = make(map[string]net.Conn)
+		if s.MaxConnections > 0 {
+			s.accept = make(chan bool, s.MaxConnections)
+			for i := 0; i < s.MaxConnections; i++ {
+				s.accept <- true
+			}
+		}
+		s.wg.Add(1)
+		go s.listenStream()
+	case "udp", "udp4", "udp6":
+		pc, err := net.ListenPacket(u.Scheme, address)
+		if err != nil {
+			return err
+		}
+		s.packetConn = pc
+		s.wg.Add(1)
+		go s.listenPacket()
+	default:
+		return fmt.Errorf("unknown protocol %q in %q", u.Scheme, s.Address)
+	}
+
+	log.Printf("I! Started syslog listener service on %s\n", s.Address)
+	return nil
+}
+
+// Stop cleans up all resources
+func (s *Syslog) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	close(s.done)
+
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.packetConn != nil {
+		s.packetConn.Close()
+	}
+
+	var conns []net.Conn
+	s.cleanup.Lock()
+	for _, conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.cleanup.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	s.wg.Wait()
+	log.Println("I! Stopped syslog listener service on ", s.Address)
+}
+
+// addr returns the address the listener is bound to.
+func (s *Syslog) addr() net.Addr {
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	return s.packetConn.LocalAddr()
+}
+
+// listenPacket reads messages from datagrams, one message per datagram.
+func (s *Syslog) listenPacket() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxMessageLength)
+	for {
+		n, addr, err := s.packetConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			s.acc.AddError(err)
+			return
+		}
+		s.store(buf[:n], addr)
+	}
+}
+
+// listenStream accepts connections to the stream listener.
+func (s *Syslog) listenStream() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			s.acc.AddError(err)
+			return
+		}
+
+		if s.accept != nil {
+			select {
+			case <-s.accept:
+			default:
+				// We are over the connection limit, refuse & close.
+				conn.Close()
+				log.Printf("I! Refused syslog connection from %s, maximum "+
+					"connections (%d) reached", conn.RemoteAddr(), s.MaxConnections)
+				continue
+			}
+		}
+
+		if tc, ok := conn.(*net.TCPConn); ok && s.KeepAlivePeriod != nil {
+			if s.KeepAlivePeriod.Duration == 0 {
+				tc.SetKeepAlive(false)
+			} else {
+				tc.SetKeepAlive(true)
+				tc.SetKeepAlivePeriod(s.KeepAlivePeriod.Duration)
+			}
+		}
+
+		s.wg.Add(1)
+		// generate a random id for this connection
+		id := internal.RandomString(6)
+		s.remember(id, conn)
+		go s.handle(conn, id)
+	}
+}
+
+// handle reads the messages of a single connection
+func (s *Syslog) handle(conn net.Conn, id string) {
+	defer func() {
+		s.wg.Done()
+		conn.Close()
+		s.forget(id)
+		if s.accept != nil {
+			// Add one connection potential back to channel when this one closes
+			s.accept <- true
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		if s.ReadTimeout != nil && s.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+		}
+		msg, err := s.readFrame(r)
+		if len(msg) > 0 {
+			s.store(msg, conn.RemoteAddr())
+		}
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			if err != io.EOF {
+				s.acc.AddError(fmt.Errorf("reading from %s: %s", conn.RemoteAddr(), err))
+			}
+			return
+		}
+	}
+}
+
+// readFrame reads the next message from a stream.
+func (s *Syslog) readFrame(r *bufio.Reader) ([]byte, error) {
+	octetCounting := s.Framing == "octet-counting"
+	if s.Framing == "" || s.Framing == "auto" {
+		// with octet-counting a message starts with its length, otherwise
+		// with its priority.
+		b, err := r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		octetCounting = b[0] >= '0' && b[0] <= '9'
+	}
+
+	if octetCounting {
+		// the length is read up to its maximum number of digits, so that a
+		// frame without a space after its length is rejected.
+		var length []byte
+		for {
+			c, err := r.ReadByte()
+			if err != nil {
+				if err == io.EOF && len(length) > 0 {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			if c == ' ' {
+				break
+			}
+			length = append(length, c)
+			if c < '0' || c > '9' || len(length) > maxLengthDigits {
+				return nil, fmt.Errorf("invalid message length %q", length)
+			}
+		}
+		n, err := strconv.Atoi(string(length))
+		if err != nil || n <= 0 || n > maxMessageLength {
+			return nil, fmt.Errorf("invalid message length %q", length)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	// the message is read up to its maximum length, so that a frame
+	// without a trailer is rejected.
+	var msg []byte
+	for {
+		line, err := r.ReadSlice(s.trailer)
+		msg = append(msg, line...)
+		if err == bufio.ErrBufferFull {
+			if len(msg) > maxMessageLength {
+				return nil, fmt.Errorf("message longer than %d bytes", maxMessageLength)
+			}
+			continue
+		}
+		msg = bytes.TrimSuffix(msg, []byte{s.trailer})
+		msg = bytes.TrimSuffix(msg, []byte{'\r'})
+		if len(msg) > maxMessageLength {
+			return nil, fmt.Errorf("message longer than %d bytes", maxMessageLength)
+		}
+		return msg, err
+	}
+}
+
+// store parses a message and adds it to the accumulator.
+func (s *Syslog) store(b []byte, addr net.Addr) {
+	b = bytes.TrimRight(b, "\x00\r\n")
+	if len(b) == 0 {
+		return
+	}
+
+	now := s.now()
+	m, err := parseMessage(b, now)
+	if err != nil {
+		s.acc.AddError(fmt.Errorf("parsing message from %s: %s", addr, err))
+		return
+	}
+
+	tags := m.tags()
+	if addr != nil {
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			tags["source"] = host
+		}
+	}
+	separator := s.SdparamSeparator
+	if separator == "" {
+		separator = "_"
+	}
+	s.acc.AddFields("syslog", m.fields(separator), tags, now)
+}
+
+// forget a connection
+func (s *Syslog) forget(id string) {
+	s.cleanup.Lock()
+	defer s.cleanup.Unlock()
+	delete(s.conns, id)
+}
+
+// remember a connection
+func (s *Syslog) remember(id string, conn net.Conn) {
+	s.cleanup.Lock()
+	defer s.cleanup.Unlock()
+	s.conns[id] = conn
+}
+
+func init() {
+	inputs.Add("syslog", func() telegraf.Input {
+		return &Syslog{
+			Address:          "tcp://:6514",
+			Framing:          "auto",
+			Trailer:          "LF",
+			SdparamSeparator: "_",
+		}
+	})
+}
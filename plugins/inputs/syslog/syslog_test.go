@@ -0,0 +1,356 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var defaultTime = time.Date(2017, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+func TestParseRFC5424(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    string
+		tags   map[string]string
+		fields map[string]interface{}
+	}{
+		{
+			name: "full",
+			msg:  `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] ` + "\xef\xbb\xbf" + `An application event log entry...`,
+			tags: map[string]string{
+				"severity": "notice",
+				"facility": "local4",
+				"hostname": "mymachine.example.com",
+				"appname":  "evntslog",
+			},
+			fields: map[string]interface{}{
+				"version":                       1,
+				"timestamp":                     time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC).UnixNano(),
+				"procid":                        "1234",
+				"msgid":                         "ID47",
+				"message":                       "An application event log entry...",
+				"facility_code":                 20,
+				"severity_code":                 5,
+				"exampleSDID@32473":             true,
+				"exampleSDID@32473_iut":         "3",
+				"exampleSDID@32473_eventSource": "Application",
+				"exampleSDID@32473_eventID":     "1011",
+				"examplePriority@32473":         true,
+				"examplePriority@32473_class":   "high",
+			},
+		},
+		{
+			name: "nil values",
+			msg:  `<34>1 - - - - - -`,
+			tags: map[string]string{
+				"severity": "crit",
+				"facility": "auth",
+			},
+			fields: map[string]interface{}{
+				"version":       1,
+				"facility_code": 4,
+				"severity_code": 2,
+			},
+		},
+		{
+			name: "escaped structured data",
+			msg:  `<13>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - [id a="\"q\\\]" b="]"] hello`,
+			tags: map[string]string{
+				"severity": "notice",
+				"facility": "user",
+				"hostname": "192.0.2.1",
+				"appname":  "myproc",
+			},
+			fields: map[string]interface{}{
+				"version":       1,
+				"timestamp":     time.Date(2003, 8, 24, 12, 14, 15, 3000, time.UTC).UnixNano(),
+				"procid":        "8710",
+				"message":       "hello",
+				"facility_code": 1,
+				"severity_code": 5,
+				"id":            true,
+				"id_a":          `"q\]`,
+				"id_b":          "]",
+			},
+		},
+	}
+	for _, tt := range tests {
+		m, err := parseMessage([]byte(tt.msg), defaultTime)
+		if !assert.NoError(t, err, tt.name) {
+			continue
+		}
+		assert.Equal(t, tt.tags, m.tags(), tt.name)
+		assert.Equal(t, tt.fields, m.fields("_"), tt.name)
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	tests := []struct {
+		msg      string
+		time     time.Time
+		hostname string
+		appname  string
+		procid   string
+		message  string
+	}{
+		{
+			msg:      `<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`,
+			time:     time.Date(2016, 10, 11, 22, 14, 15, 0, time.UTC),
+			hostname: "mymachine",
+			appname:  "su",
+			message:  "'su root' failed for lonvick on /dev/pts/8",
+		},
+		{
+			msg:     `<34>Mar  4 11:59:00 sshd[4123]: Accepted publickey`,
+			time:    time.Date(2017, 3, 4, 11, 59, 0, 0, time.UTC),
+			appname: "sshd",
+			procid:  "4123",
+			message: "Accepted publickey",
+		},
+		{
+			msg:      `<34>2017-03-04T11:00:00.5Z host.example.com cron[1]: job done`,
+			time:     time.Date(2017, 3, 4, 11, 0, 0, 500000000, time.UTC),
+			hostname: "host.example.com",
+			appname:  "cron",
+			procid:   "1",
+			message:  "job done",
+		},
+		{
+			msg:      `<34>Mar  4 11:59:00.123456 host just a message`,
+			time:     time.Date(2017, 3, 4, 11, 59, 0, 123456000, time.UTC),
+			hostname: "host",
+			message:  "just a message",
+		},
+		{
+			msg:     `<34>no timestamp at all`,
+			message: "no timestamp at all",
+		},
+	}
+	for _, tt := range tests {
+		m, err := parseMessage([]byte(tt.msg), defaultTime)
+		if !assert.NoError(t, err, tt.msg) {
+			continue
+		}
+		assert.Equal(t, 0, m.version, tt.msg)
+		assert.Equal(t, 4, m.facility, tt.msg)
+		assert.Equal(t, 2, m.severity, tt.msg)
+		assert.True(t, tt.time.Equal(m.timestamp), "%s: %s", tt.msg, m.timestamp)
+		assert.Equal(t, tt.hostname, m.hostname, tt.msg)
+		assert.Equal(t, tt.appname, m.appname, tt.msg)
+		assert.Equal(t, tt.procid, m.procid, tt.msg)
+		assert.Equal(t, tt.message, m.message, tt.msg)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, msg := range []string{
+		"",
+		"no priority",
+		"<192>1 - - - - - -",
+		"<1x>1 - - - - - -",
+		"<1>2 - - - - - -",
+		"<1>1 - - - - -",
+		"<1>1 notatime - - - - -",
+		"<1>1 - - - - - [id",
+		`<1>1 - - - - - [id a="1]`,
+		`<1>1 - - - - - [id a=1]`,
+		"<1>1 - - - - - nosd",
+		"<1>1 - - - - - [id]msg",
+	} {
+		_, err := parseMessage([]byte(msg), defaultTime)
+		assert.Error(t, err, msg)
+	}
+}
+
+func newTestSyslog(address string) *Syslog {
+	return &Syslog{
+		Address: address,
+		now:     func() time.Time { return defaultTime },
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	s := newTestSyslog("udp://127.0.0.1:0")
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<13>1 - host app - - - hello\n"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("garbage"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	acc.AssertContainsTaggedFields(t, "syslog",
+		map[string]interface{}{
+			"version":       1,
+			"message":       "hello",
+			"facility_code": 1,
+			"severity_code": 5,
+		},
+		map[string]string{
+			"severity": "notice",
+			"facility": "user",
+			"hostname": "host",
+			"appname":  "app",
+			"source":   "127.0.0.1",
+		},
+	)
+}
+
+func TestSyslogTCPFraming(t *testing.T) {
+	tests := []struct {
+		framing string
+		trailer string
+		data    string
+	}{
+		{"auto", "", "28 <13>1 - host app - - - first<13>1 - host app - - - second\n"},
+		{"octet-counting", "", "28 <13>1 - host app - - - first29 <13>1 - host app - - - second"},
+		{"non-transparent", "LF", "<13>1 - host app - - - first\r\n\n<13>1 - host app - - - second"},
+		{"non-transparent", "NUL", "<13>1 - host app - - - first\x00<13>1 - host app - - - second\x00"},
+	}
+	for _, tt := range tests {
+		s := newTestSyslog("tcp://127.0.0.1:0")
+		s.Framing = tt.framing
+		s.Trailer = tt.trailer
+		acc := &testutil.Accumulator{}
+		require.NoError(t, s.Start(acc))
+
+		conn, err := net.Dial("tcp", s.addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(tt.data))
+		require.NoError(t, err)
+		conn.Close()
+		acc.Wait(2)
+		s.Stop()
+
+		require.Len(t, acc.Metrics, 2, tt.framing)
+		assert.Equal(t, "first", acc.Metrics[0].Fields["message"], tt.framing)
+		assert.Equal(t, "second", acc.Metrics[1].Fields["message"], tt.framing)
+		assert.Empty(t, acc.Errors, tt.framing)
+	}
+}
+
+func TestSyslogTCPInvalidFrame(t *testing.T) {
+	tests := []struct {
+		framing string
+		data    string
+		err     string
+	}{
+		{"octet-counting", "1234567 <13>1 - host app - - - first", "invalid message length"},
+		{"octet-counting", "12a <13>1 - host app - - - first", "invalid message length"},
+		{"octet-counting", strings.Repeat("1", 1024), "invalid message length"},
+		{"non-transparent", strings.Repeat("a", maxMessageLength+1) + "\n", "message longer than"},
+	}
+	for _, tt := range tests {
+		s := newTestSyslog("tcp://127.0.0.1:0")
+		s.Framing = tt.framing
+		acc := &testutil.Accumulator{}
+		require.NoError(t, s.Start(acc))
+
+		conn, err := net.Dial("tcp", s.addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(tt.data))
+		require.NoError(t, err)
+
+		// the connection is closed on an invalid frame
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		assert.Error(t, err, tt.framing)
+		conn.Close()
+		s.Stop()
+
+		acc.Lock()
+		require.Len(t, acc.Errors, 1, tt.framing)
+		assert.Contains(t, acc.Errors[0].Error(), tt.err, tt.framing)
+		acc.Unlock()
+		assert.Empty(t, acc.Metrics, tt.framing)
+	}
+}
+
+func TestSyslogTLS(t *testing.T) {
+	pki := testutil.NewPKI()
+	s := newTestSyslog("tcp://127.0.0.1:0")
+	s.TLSCert = pki.ServerCertPath()
+	s.TLSKey = pki.ServerKeyPath()
+	s.TLSAllowedCACerts = []string{pki.CACertPath()}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	conn, err := tls.Dial("tcp", s.addr().String(), pki.ClientTLSConfig())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "<165>1 - host app - - [id a=\"1\"] hello\n")
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	acc.AssertContainsFields(t, "syslog", map[string]interface{}{
+		"version":       1,
+		"message":       "hello",
+		"facility_code": 20,
+		"severity_code": 5,
+		"id":            true,
+		"id_a":          "1",
+	})
+
+	// clients without a certificate are refused.
+	config := pki.ClientTLSConfig()
+	config.Certificates = nil
+	conn, err = tls.Dial("tcp", s.addr().String(), config)
+	if err == nil {
+		defer conn.Close()
+		_, err = conn.Write([]byte("<13>1 - - - - - -\n"))
+		if err == nil {
+			_, err = conn.Read(make([]byte, 1))
+		}
+	}
+	assert.Error(t, err)
+}
+
+func TestSyslogMaxConnections(t *testing.T) {
+	s := newTestSyslog("tcp://127.0.0.1:0")
+	s.MaxConnections = 1
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<13>1 - - - - - - first\n"))
+	require.NoError(t, err)
+	acc.Wait(1)
+
+	refused, err := net.Dial("tcp", s.addr().String())
+	require.NoError(t, err)
+	defer refused.Close()
+	refused.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = refused.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestSyslogBadConfig(t *testing.T) {
+	for _, s := range []*Syslog{
+		{Address: "foo://:6514"},
+		{Address: "tcp://127.0.0.1:0", Framing: "foo"},
+		{Address: "tcp://127.0.0.1:0", Trailer: "CR"},
+		{Address: "tcp://127.0.0.1:0", TLSAllowedCACerts: []string{"ca.pem"}},
+	} {
+		assert.Error(t, s.Start(&testutil.Accumulator{}), s.Address)
+	}
+}
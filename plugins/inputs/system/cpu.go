@@ -42,6 +42,12 @@ func (_ *CPUStats) SampleConfig() string {
 	return sampleConfig
 }
 
+// GathersDeltas reports that the usage percentages are computed from the cpu
+// times of the previous gather.
+func (_ *CPUStats) GathersDeltas() bool {
+	return true
+}
+
 func (s *CPUStats) Gather(acc telegraf.Accumulator) error {
 	times, err := s.ps.CPUTimes(s.PerCPU, s.TotalCPU)
 	if err != nil {
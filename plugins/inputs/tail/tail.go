@@ -2,7 +2,6 @@ package tail
 
 import (
 	"fmt"
-	"log"
 	"sync"
 
 	"github.com/hpcloud/tail"
@@ -17,6 +16,8 @@ type Tail struct {
 	Files         []string
 	FromBeginning bool
 
+	Log telegraf.Logger `toml:"-"`
+
 	tailers []*tail.Tail
 	parser  parsers.Parser
 	wg      sync.WaitGroup
@@ -81,7 +82,7 @@ func (t *Tail) Start(acc telegraf.Accumulator) error {
 	for _, filepath := range t.Files {
 		g, err := globpath.Compile(filepath)
 		if err != nil {
-			log.Printf("E! Error Glob %s failed to compile, %s", filepath, err)
+			t.Log.Errorf("Glob %s failed to compile, %s", filepath, err)
 		}
 		for file, _ := range g.Match() {
 			tailer, err := tail.TailFile(file,
@@ -118,15 +119,15 @@ func (t *Tail) receiver(tailer *tail.Tail) {
 	var line *tail.Line
 	for line = range tailer.Lines {
 		if line.Err != nil {
-			log.Printf("E! Error tailing file %s, Error: %s\n",
-				tailer.Filename, err)
+			t.Log.Errorf("Error tailing file %s, Error: %s",
+				tailer.Filename, line.Err)
 			continue
 		}
 		m, err = t.parser.ParseLine(line.Text)
 		if err == nil {
 			t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
 		} else {
-			log.Printf("E! Malformed log line in %s: [%s], Error: %s\n",
+			t.Log.Errorf("Malformed log line in %s: [%s], Error: %s",
 				tailer.Filename, line.Text, err)
 		}
 	}
@@ -136,12 +137,12 @@ func (t *Tail) Stop() {
 	t.Lock()
 	defer t.Unlock()
 
-	for _, t := range t.tailers {
-		err := t.Stop()
+	for _, tailer := range t.tailers {
+		err := tailer.Stop()
 		if err != nil {
-			log.Printf("E! Error stopping tail on file %s\n", t.Filename)
+			t.Log.Errorf("Error stopping tail on file %s", tailer.Filename)
 		}
-		t.Cleanup()
+		tailer.Cleanup()
 	}
 	t.wg.Wait()
 }
@@ -21,6 +21,7 @@ func TestTailFromBeginning(t *testing.T) {
 	require.NoError(t, err)
 
 	tt := NewTail()
+	tt.Log = testutil.Logger{Name: "inputs.tail"}
 	tt.FromBeginning = true
 	tt.Files = []string{tmpfile.Name()}
 	p, _ := parsers.NewInfluxParser()
@@ -52,6 +53,7 @@ func TestTailFromEnd(t *testing.T) {
 	require.NoError(t, err)
 
 	tt := NewTail()
+	tt.Log = testutil.Logger{Name: "inputs.tail"}
 	tt.Files = []string{tmpfile.Name()}
 	p, _ := parsers.NewInfluxParser()
 	tt.SetParser(p)
@@ -83,6 +85,7 @@ func TestTailBadLine(t *testing.T) {
 	defer os.Remove(tmpfile.Name())
 
 	tt := NewTail()
+	tt.Log = testutil.Logger{Name: "inputs.tail"}
 	tt.FromBeginning = true
 	tt.Files = []string{tmpfile.Name()}
 	p, _ := parsers.NewInfluxParser()
@@ -1,63 +1,31 @@
 package tcp_listener
 
 import (
-	"bufio"
-	"fmt"
-	"log"
-	"net"
-	"sync"
-
 	"github.com/influxdata/telegraf"
-	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/socket_listener"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
 
+// TcpListener is kept for existing configurations, and is implemented by the
+// socket_listener input listening on a TCP socket.
 type TcpListener struct {
-	ServiceAddress         string
+	ServiceAddress string
+	// AllowedPendingMessages is deprecated and ignored, as messages are
+	// parsed as they are read.
 	AllowedPendingMessages int
 	MaxTCPConnections      int `toml:"max_tcp_connections"`
 
-	sync.Mutex
-	// Lock for preventing a data race during resource cleanup
-	cleanup sync.Mutex
-	wg      sync.WaitGroup
-
-	in   chan []byte
-	done chan struct{}
-	// accept channel tracks how many active connections there are, if there
-	// is an available bool in accept, then we are below the maximum and can
-	// accept the connection
-	accept chan bool
-	// drops tracks the number of dropped metrics.
-	drops int
-	// malformed tracks the number of malformed packets
-	malformed int
-
-	// track the listener here so we can close it in Stop()
-	listener *net.TCPListener
-	// track current connections so we can close them in Stop()
-	conns map[string]*net.TCPConn
+	parser   parsers.Parser
+	listener *socket_listener.SocketListener
 
-	parser parsers.Parser
-	acc    telegraf.Accumulator
+	Log telegraf.Logger `toml:"-"`
 }
 
-var dropwarn = "E! Error: tcp_listener message queue full. " +
-	"We have dropped %d messages so far. " +
-	"You may want to increase allowed_pending_messages in the config\n"
-
-var malformedwarn = "E! tcp_listener has received %d malformed packets" +
-	" thus far."
-
 const sampleConfig = `
   ## Address and port to host TCP listener on
   # service_address = ":8094"
 
-  ## Number of TCP messages allowed to queue up. Once filled, the
-  ## TCP listener will start dropping packets.
-  # allowed_pending_messages = 10000
-
   ## Maximum number of concurrent TCP connections to allow
   # max_tcp_connections = 250
 
@@ -88,198 +56,32 @@ func (t *TcpListener) SetParser(parser parsers.Parser) {
 
 // Start starts the tcp listener service.
 func (t *TcpListener) Start(acc telegraf.Accumulator) error {
-	t.Lock()
-	defer t.Unlock()
-
-	t.acc = acc
-	t.in = make(chan []byte, t.AllowedPendingMessages)
-	t.done = make(chan struct{})
-	t.accept = make(chan bool, t.MaxTCPConnections)
-	t.conns = make(map[string]*net.TCPConn)
-	for i := 0; i < t.MaxTCPConnections; i++ {
-		t.accept <- true
+	if t.AllowedPendingMessages != 0 {
+		t.Log.Warn("allowed_pending_messages is " +
+			"deprecated and ignored, messages are parsed as they are read")
 	}
-
-	// Start listener
-	var err error
-	address, _ := net.ResolveTCPAddr("tcp", t.ServiceAddress)
-	t.listener, err = net.ListenTCP("tcp", address)
-	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
-		return err
+	t.listener = &socket_listener.SocketListener{
+		ServiceAddress: "tcp://" + t.ServiceAddress,
+		MaxConnections: t.MaxTCPConnections,
+		RefusalMessage: "Telegraf maximum concurrent TCP connections (%d)" +
+			" reached, closing.\nYou may want to increase max_tcp_connections in" +
+			" the Telegraf tcp listener configuration.\n",
+		Parser: t.parser,
+		Log:    t.Log,
 	}
-	log.Println("I! TCP server listening on: ", t.listener.Addr().String())
-
-	t.wg.Add(2)
-	go t.tcpListen()
-	go t.tcpParser()
-
-	log.Printf("I! Started TCP listener service on %s\n", t.ServiceAddress)
-	return nil
+	return t.listener.Start(acc)
 }
 
 // Stop cleans up all resources
 func (t *TcpListener) Stop() {
-	t.Lock()
-	defer t.Unlock()
-	close(t.done)
-	t.listener.Close()
-
-	// Close all open TCP connections
-	//  - get all conns from the t.conns map and put into slice
-	//  - this is so the forget() function doesnt conflict with looping
-	//    over the t.conns map
-	var conns []*net.TCPConn
-	t.cleanup.Lock()
-	for _, conn := range t.conns {
-		conns = append(conns, conn)
-	}
-	t.cleanup.Unlock()
-	for _, conn := range conns {
-		conn.Close()
-	}
-
-	t.wg.Wait()
-	close(t.in)
-	log.Println("I! Stopped TCP listener service on ", t.ServiceAddress)
-}
-
-// tcpListen listens for incoming TCP connections.
-func (t *TcpListener) tcpListen() error {
-	defer t.wg.Done()
-
-	for {
-		select {
-		case <-t.done:
-			return nil
-		default:
-			// Accept connection:
-			conn, err := t.listener.AcceptTCP()
-			if err != nil {
-				return err
-			}
-
-			select {
-			case <-t.accept:
-				// not over connection limit, handle the connection properly.
-				t.wg.Add(1)
-				// generate a random id for this TCPConn
-				id := internal.RandomString(6)
-				t.remember(id, conn)
-				go t.handler(conn, id)
-			default:
-				// We are over the connection limit, refuse & close.
-				t.refuser(conn)
-			}
-		}
-	}
-}
-
-// refuser refuses a TCP connection
-func (t *TcpListener) refuser(conn *net.TCPConn) {
-	// Tell the connection why we are closing.
-	fmt.Fprintf(conn, "Telegraf maximum concurrent TCP connections (%d)"+
-		" reached, closing.\nYou may want to increase max_tcp_connections in"+
-		" the Telegraf tcp listener configuration.\n", t.MaxTCPConnections)
-	conn.Close()
-	log.Printf("I! Refused TCP Connection from %s", conn.RemoteAddr())
-	log.Printf("I! WARNING: Maximum TCP Connections reached, you may want to" +
-		" adjust max_tcp_connections")
-}
-
-// handler handles a single TCP Connection
-func (t *TcpListener) handler(conn *net.TCPConn, id string) {
-	// connection cleanup function
-	defer func() {
-		t.wg.Done()
-		conn.Close()
-		// Add one connection potential back to channel when this one closes
-		t.accept <- true
-		t.forget(id)
-	}()
-
-	var n int
-	scanner := bufio.NewScanner(conn)
-	for {
-		select {
-		case <-t.done:
-			return
-		default:
-			if !scanner.Scan() {
-				return
-			}
-			n = len(scanner.Bytes())
-			if n == 0 {
-				continue
-			}
-			bufCopy := make([]byte, n)
-			copy(bufCopy, scanner.Bytes())
-
-			select {
-			case t.in <- bufCopy:
-			default:
-				t.drops++
-				if t.drops == 1 || t.drops%t.AllowedPendingMessages == 0 {
-					log.Printf(dropwarn, t.drops)
-				}
-			}
-		}
-	}
-}
-
-// tcpParser parses the incoming tcp byte packets
-func (t *TcpListener) tcpParser() error {
-	defer t.wg.Done()
-
-	var packet []byte
-	var metrics []telegraf.Metric
-	var err error
-	for {
-		select {
-		case <-t.done:
-			// drain input packets before finishing:
-			if len(t.in) == 0 {
-				return nil
-			}
-		case packet = <-t.in:
-			if len(packet) == 0 {
-				continue
-			}
-			metrics, err = t.parser.Parse(packet)
-			if err == nil {
-				for _, m := range metrics {
-					t.acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
-				}
-			} else {
-				t.malformed++
-				if t.malformed == 1 || t.malformed%1000 == 0 {
-					log.Printf(malformedwarn, t.malformed)
-				}
-			}
-		}
-	}
-}
-
-// forget a TCP connection
-func (t *TcpListener) forget(id string) {
-	t.cleanup.Lock()
-	defer t.cleanup.Unlock()
-	delete(t.conns, id)
-}
-
-// remember a TCP connection
-func (t *TcpListener) remember(id string, conn *net.TCPConn) {
-	t.cleanup.Lock()
-	defer t.cleanup.Unlock()
-	t.conns[id] = conn
+	t.listener.Stop()
 }
 
 func init() {
 	inputs.Add("tcp_listener", func() telegraf.Input {
 		return &TcpListener{
-			ServiceAddress:         ":8094",
-			AllowedPendingMessages: 10000,
-			MaxTCPConnections:      250,
+			ServiceAddress:    ":8094",
+			MaxTCPConnections: 250,
 		}
 	})
 }
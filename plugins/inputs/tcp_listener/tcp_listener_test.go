@@ -25,24 +25,12 @@ cpu_load_short,host=server06 value=12.0 1422568543702900257
 `
 )
 
-func newTestTcpListener() (*TcpListener, chan []byte) {
-	in := make(chan []byte, 1500)
-	listener := &TcpListener{
-		ServiceAddress:         ":8194",
-		AllowedPendingMessages: 10000,
-		MaxTCPConnections:      250,
-		in:                     in,
-		done:                   make(chan struct{}),
-	}
-	return listener, in
-}
-
 // benchmark how long it takes to accept & process 100,000 metrics:
 func BenchmarkTCP(b *testing.B) {
 	listener := TcpListener{
-		ServiceAddress:         ":8198",
-		AllowedPendingMessages: 100000,
-		MaxTCPConnections:      250,
+		ServiceAddress:    ":8198",
+		MaxTCPConnections: 250,
+		Log:               testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 	acc := &testutil.Accumulator{Discard: true}
@@ -54,7 +42,6 @@ func BenchmarkTCP(b *testing.B) {
 			panic(err)
 		}
 
-		time.Sleep(time.Millisecond * 25)
 		conn, err := net.Dial("tcp", "127.0.0.1:8198")
 		if err != nil {
 			panic(err)
@@ -63,16 +50,16 @@ func BenchmarkTCP(b *testing.B) {
 			fmt.Fprintf(conn, testMsg)
 		}
 		// wait for 100,000 metrics to get added to accumulator
-		time.Sleep(time.Millisecond)
+		acc.Wait(100000 * (n + 1))
 		listener.Stop()
 	}
 }
 
 func TestHighTrafficTCP(t *testing.T) {
 	listener := TcpListener{
-		ServiceAddress:         ":8199",
-		AllowedPendingMessages: 100000,
-		MaxTCPConnections:      250,
+		ServiceAddress:    ":8199",
+		MaxTCPConnections: 250,
+		Log:               testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 	acc := &testutil.Accumulator{}
@@ -81,13 +68,12 @@ func TestHighTrafficTCP(t *testing.T) {
 	err := listener.Start(acc)
 	require.NoError(t, err)
 
-	time.Sleep(time.Millisecond * 25)
 	conn, err := net.Dial("tcp", "127.0.0.1:8199")
 	require.NoError(t, err)
 	for i := 0; i < 100000; i++ {
 		fmt.Fprintf(conn, testMsg)
 	}
-	time.Sleep(time.Millisecond)
+	acc.Wait(100000)
 	listener.Stop()
 
 	assert.Equal(t, 100000, len(acc.Metrics))
@@ -95,9 +81,9 @@ func TestHighTrafficTCP(t *testing.T) {
 
 func TestConnectTCP(t *testing.T) {
 	listener := TcpListener{
-		ServiceAddress:         ":8194",
-		AllowedPendingMessages: 10000,
-		MaxTCPConnections:      250,
+		ServiceAddress:    ":8194",
+		MaxTCPConnections: 250,
+		Log:               testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -133,9 +119,9 @@ func TestConnectTCP(t *testing.T) {
 // Test that MaxTCPConections is respected
 func TestConcurrentConns(t *testing.T) {
 	listener := TcpListener{
-		ServiceAddress:         ":8195",
-		AllowedPendingMessages: 10000,
-		MaxTCPConnections:      2,
+		ServiceAddress:    ":8195",
+		MaxTCPConnections: 2,
+		Log:               testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -171,9 +157,9 @@ func TestConcurrentConns(t *testing.T) {
 // Test that MaxTCPConections is respected when max==1
 func TestConcurrentConns1(t *testing.T) {
 	listener := TcpListener{
-		ServiceAddress:         ":8196",
-		AllowedPendingMessages: 10000,
-		MaxTCPConnections:      1,
+		ServiceAddress:    ":8196",
+		MaxTCPConnections: 1,
+		Log:               testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -207,9 +193,9 @@ func TestConcurrentConns1(t *testing.T) {
 // Test that MaxTCPConections is respected
 func TestCloseConcurrentConns(t *testing.T) {
 	listener := TcpListener{
-		ServiceAddress:         ":8195",
-		AllowedPendingMessages: 10000,
-		MaxTCPConnections:      2,
+		ServiceAddress:    ":8195",
+		MaxTCPConnections: 2,
+		Log:               testutil.Logger{Name: "inputs.tcp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -225,21 +211,33 @@ func TestCloseConcurrentConns(t *testing.T) {
 	listener.Stop()
 }
 
-func TestRunParser(t *testing.T) {
-	var testmsg = []byte(testMsg)
+// sendMsg sends msg to a listener using parser, and returns the accumulator
+// the resulting metrics are added to.
+func sendMsg(t *testing.T, parser parsers.Parser, msg string, n int) *testutil.Accumulator {
+	listener := TcpListener{
+		ServiceAddress:    "127.0.0.1:8197",
+		MaxTCPConnections: 250,
+		Log:               testutil.Logger{Name: "inputs.tcp_listener"},
+	}
+	listener.parser = parser
 
-	listener, in := newTestTcpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
 
-	listener.parser, _ = parsers.NewInfluxParser()
-	listener.wg.Add(1)
-	go listener.tcpParser()
+	conn, err := net.Dial("tcp", "127.0.0.1:8197")
+	require.NoError(t, err)
+	fmt.Fprint(conn, msg)
+	conn.Close()
 
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
-	listener.Gather(&acc)
+	acc.Wait(n)
+	time.Sleep(time.Millisecond * 10)
+	return acc
+}
+
+func TestRunParser(t *testing.T) {
+	parser, _ := parsers.NewInfluxParser()
+	acc := sendMsg(t, parser, testMsg, 1)
 
 	if a := acc.NFields(); a != 1 {
 		t.Errorf("got %v, expected %v", a, 1)
@@ -252,60 +250,26 @@ func TestRunParser(t *testing.T) {
 }
 
 func TestRunParserInvalidMsg(t *testing.T) {
-	var testmsg = []byte("cpu_load_short")
-
-	listener, in := newTestTcpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
-
-	listener.parser, _ = parsers.NewInfluxParser()
-	listener.wg.Add(1)
-	go listener.tcpParser()
-
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
+	parser, _ := parsers.NewInfluxParser()
+	acc := sendMsg(t, parser, "cpu_load_short\n", 0)
 
 	if a := acc.NFields(); a != 0 {
 		t.Errorf("got %v, expected %v", a, 0)
 	}
+	assert.Len(t, acc.Errors, 1)
 }
 
 func TestRunParserGraphiteMsg(t *testing.T) {
-	var testmsg = []byte("cpu.load.graphite 12 1454780029")
-
-	listener, in := newTestTcpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
-
-	listener.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
-	listener.wg.Add(1)
-	go listener.tcpParser()
-
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
-	listener.Gather(&acc)
+	parser, _ := parsers.NewGraphiteParser("_", []string{}, nil)
+	acc := sendMsg(t, parser, "cpu.load.graphite 12 1454780029\n", 1)
 
 	acc.AssertContainsFields(t, "cpu_load_graphite",
 		map[string]interface{}{"value": float64(12)})
 }
 
 func TestRunParserJSONMsg(t *testing.T) {
-	var testmsg = []byte("{\"a\": 5, \"b\": {\"c\": 6}}\n")
-
-	listener, in := newTestTcpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
-
-	listener.parser, _ = parsers.NewJSONParser("udp_json_test", []string{}, nil)
-	listener.wg.Add(1)
-	go listener.tcpParser()
-
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
-	listener.Gather(&acc)
+	parser, _ := parsers.NewJSONParser("udp_json_test", []string{}, nil)
+	acc := sendMsg(t, parser, "{\"a\": 5, \"b\": {\"c\": 6}}\n", 1)
 
 	acc.AssertContainsFields(t, "udp_json_test",
 		map[string]interface{}{
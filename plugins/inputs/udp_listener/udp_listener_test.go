@@ -27,21 +27,10 @@ cpu_load_short,host=server06 value=12.0 1422568543702900257
 `
 )
 
-func newTestUdpListener() (*UdpListener, chan []byte) {
-	in := make(chan []byte, 1500)
-	listener := &UdpListener{
-		ServiceAddress:         ":8125",
-		AllowedPendingMessages: 10000,
-		in:   in,
-		done: make(chan struct{}),
-	}
-	return listener, in
-}
-
 func TestHighTrafficUDP(t *testing.T) {
 	listener := UdpListener{
-		ServiceAddress:         ":8126",
-		AllowedPendingMessages: 100000,
+		ServiceAddress: ":8126",
+		Log:            testutil.Logger{Name: "inputs.udp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 	acc := &testutil.Accumulator{}
@@ -59,7 +48,7 @@ func TestHighTrafficUDP(t *testing.T) {
 		time.Sleep(time.Microsecond)
 		fmt.Fprintf(conn, testMsgs)
 	}
-	time.Sleep(time.Millisecond)
+	acc.Wait(90000)
 	listener.Stop()
 
 	// this is not an exact science, since UDP packets can easily get lost or
@@ -70,8 +59,8 @@ func TestHighTrafficUDP(t *testing.T) {
 
 func TestConnectUDP(t *testing.T) {
 	listener := UdpListener{
-		ServiceAddress:         ":8127",
-		AllowedPendingMessages: 10000,
+		ServiceAddress: ":8127",
+		Log:            testutil.Logger{Name: "inputs.udp_listener"},
 	}
 	listener.parser, _ = parsers.NewInfluxParser()
 
@@ -104,22 +93,33 @@ func TestConnectUDP(t *testing.T) {
 	}
 }
 
-func TestRunParser(t *testing.T) {
-	log.SetOutput(ioutil.Discard)
-	var testmsg = []byte("cpu_load_short,host=server01 value=12.0 1422568543702900257")
+// sendMsg sends msg to a listener using parser, and returns the accumulator
+// the resulting metrics are added to.
+func sendMsg(t *testing.T, parser parsers.Parser, msg string, n int) *testutil.Accumulator {
+	listener := UdpListener{
+		ServiceAddress: "127.0.0.1:8125",
+		Log:            testutil.Logger{Name: "inputs.udp_listener"},
+	}
+	listener.parser = parser
 
-	listener, in := newTestUdpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
 
-	listener.parser, _ = parsers.NewInfluxParser()
-	listener.wg.Add(1)
-	go listener.udpParser()
+	conn, err := net.Dial("udp", "127.0.0.1:8125")
+	require.NoError(t, err)
+	fmt.Fprint(conn, msg)
+	conn.Close()
 
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
-	listener.Gather(&acc)
+	acc.Wait(n)
+	time.Sleep(time.Millisecond * 10)
+	return acc
+}
+
+func TestRunParser(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	parser, _ := parsers.NewInfluxParser()
+	acc := sendMsg(t, parser, "cpu_load_short,host=server01 value=12.0 1422568543702900257", 1)
 
 	if a := acc.NFields(); a != 1 {
 		t.Errorf("got %v, expected %v", a, 1)
@@ -133,41 +133,19 @@ func TestRunParser(t *testing.T) {
 
 func TestRunParserInvalidMsg(t *testing.T) {
 	log.SetOutput(ioutil.Discard)
-	var testmsg = []byte("cpu_load_short")
-
-	listener, in := newTestUdpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
-
-	listener.parser, _ = parsers.NewInfluxParser()
-	listener.wg.Add(1)
-	go listener.udpParser()
-
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
+	parser, _ := parsers.NewInfluxParser()
+	acc := sendMsg(t, parser, "cpu_load_short", 0)
 
 	if a := acc.NFields(); a != 0 {
 		t.Errorf("got %v, expected %v", a, 0)
 	}
+	assert.Len(t, acc.Errors, 1)
 }
 
 func TestRunParserGraphiteMsg(t *testing.T) {
 	log.SetOutput(ioutil.Discard)
-	var testmsg = []byte("cpu.load.graphite 12 1454780029")
-
-	listener, in := newTestUdpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
-
-	listener.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
-	listener.wg.Add(1)
-	go listener.udpParser()
-
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
-	listener.Gather(&acc)
+	parser, _ := parsers.NewGraphiteParser("_", []string{}, nil)
+	acc := sendMsg(t, parser, "cpu.load.graphite 12 1454780029", 1)
 
 	acc.AssertContainsFields(t, "cpu_load_graphite",
 		map[string]interface{}{"value": float64(12)})
@@ -175,20 +153,8 @@ func TestRunParserGraphiteMsg(t *testing.T) {
 
 func TestRunParserJSONMsg(t *testing.T) {
 	log.SetOutput(ioutil.Discard)
-	var testmsg = []byte("{\"a\": 5, \"b\": {\"c\": 6}}\n")
-
-	listener, in := newTestUdpListener()
-	acc := testutil.Accumulator{}
-	listener.acc = &acc
-	defer close(listener.done)
-
-	listener.parser, _ = parsers.NewJSONParser("udp_json_test", []string{}, nil)
-	listener.wg.Add(1)
-	go listener.udpParser()
-
-	in <- testmsg
-	time.Sleep(time.Millisecond * 25)
-	listener.Gather(&acc)
+	parser, _ := parsers.NewJSONParser("udp_json_test", []string{}, nil)
+	acc := sendMsg(t, parser, "{\"a\": 5, \"b\": {\"c\": 6}}\n", 1)
 
 	acc.AssertContainsFields(t, "udp_json_test",
 		map[string]interface{}{
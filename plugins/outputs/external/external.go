@@ -0,0 +1,106 @@
+package external
+
+import (
+	"errors"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const sampleConfig = `
+  ## Command of the external output, and its arguments. The output is a
+  ## program serving the external plugin protocol, such as one built with
+  ## the Go SDK in plugins/external/sdk.
+  command = ["/usr/local/bin/my-output", "--config", "/etc/my-output.conf"]
+
+  ## Environment variables set for the command, in addition to the ones of
+  ## telegraf, as "NAME=value".
+  # environment = []
+
+  ## Timeout for each request to the plugin to complete.
+  # timeout = "5s"
+
+  ## Delay before restarting the plugin once it exits.
+  # restart_delay = "10s"
+
+  ## Interval at which the plugin is health checked, the plugin being
+  ## restarted if it does not respond. Set to "0s" to disable health checks.
+  # health_check_interval = "30s"
+`
+
+// External is an output running as a separate process.
+type External struct {
+	Command             []string
+	Environment         []string
+	Timeout             internal.Duration
+	RestartDelay        internal.Duration
+	HealthCheckInterval internal.Duration
+
+	Log telegraf.Logger `toml:"-"`
+
+	process *external.Process
+}
+
+func NewExternal() *External {
+	return &External{
+		Timeout:             internal.Duration{Duration: 5 * time.Second},
+		RestartDelay:        internal.Duration{Duration: 10 * time.Second},
+		HealthCheckInterval: internal.Duration{Duration: 30 * time.Second},
+	}
+}
+
+func (e *External) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *External) Description() string {
+	return "Run an external output plugin as a separate process"
+}
+
+func (e *External) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("command is required")
+	}
+	return nil
+}
+
+func (e *External) Connect() error {
+	e.process = &external.Process{
+		Command:             e.Command,
+		Environment:         e.Environment,
+		Kind:                external.KindOutput,
+		Timeout:             e.Timeout.Duration,
+		RestartDelay:        e.RestartDelay.Duration,
+		HealthCheckInterval: e.HealthCheckInterval.Duration,
+		Log:                 e.Log,
+	}
+	return e.process.Start()
+}
+
+func (e *External) Close() error {
+	if e.process != nil {
+		e.process.Stop()
+	}
+	return nil
+}
+
+func (e *External) Write(metrics []telegraf.Metric) error {
+	lines, err := external.EncodeMetrics(metrics)
+	if err != nil {
+		return err
+	}
+	_, err = e.process.Request(external.Request{
+		Type:    external.RequestWrite,
+		Metrics: lines,
+	})
+	return err
+}
+
+func init() {
+	outputs.Add("external", func() telegraf.Output {
+		return NewExternal()
+	})
+}
@@ -0,0 +1,83 @@
+package external
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/external/sdk"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary runs as the external output when this environment
+// variable is set, to the file the output writes to.
+const pluginEnv = "TELEGRAF_TEST_EXTERNAL_OUTPUT"
+
+// testOutput writes the names of the metrics to a file once closed.
+type testOutput struct {
+	path  string
+	names []string
+}
+
+func (o *testOutput) SampleConfig() string { return "" }
+func (o *testOutput) Description() string  { return "" }
+func (o *testOutput) Connect() error       { return nil }
+func (o *testOutput) Close() error {
+	var contents string
+	for _, name := range o.names {
+		contents += name + "\n"
+	}
+	return ioutil.WriteFile(o.path, []byte(contents), 0600)
+}
+func (o *testOutput) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		o.names = append(o.names, m.Name())
+	}
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(pluginEnv); path != "" {
+		if err := sdk.Serve(&testOutput{path: path}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestInitNoCommand(t *testing.T) {
+	assert.Error(t, NewExternal().Init())
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics")
+
+	e := NewExternal()
+	e.Command = []string{os.Args[0]}
+	e.Environment = []string{pluginEnv + "=" + path}
+	e.Log = testutil.Logger{Name: "outputs.external"}
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Connect())
+
+	m1, _ := telegraf.NewMetric("cpu", nil,
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 1))
+	m2, _ := telegraf.NewMetric("mem", nil,
+		map[string]interface{}{"value": 2.0}, time.Unix(0, 2))
+	require.NoError(t, e.Write([]telegraf.Metric{m1, m2}))
+	require.NoError(t, e.Close())
+
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "cpu\nmem\n", string(contents))
+}
@@ -1,65 +1,221 @@
 package graphite
 
 import (
+	"crypto/tls"
 	"errors"
-	"log"
+	"fmt"
 	"math/rand"
 	"net"
 	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
+const (
+	defaultServer  = "localhost:2003"
+	defaultTimeout = 2 * time.Second
+
+	// delays before connecting again to a server which failed
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
 type Graphite struct {
 	// URL is only for backwards compatability
 	Servers  []string
 	Prefix   string
 	Template string
-	Timeout  int
-	conns    []net.Conn
+	Timeout  internal.Duration
+
+	// How metrics are distributed across servers, one of "random",
+	// "failover", "round_robin" or "consistent_hash"
+	Mode string
+	// Protocol to write metrics with, "plaintext" or "pickle"
+	Protocol string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	Log telegraf.Logger `toml:"-"`
+
+	servers   []*server
+	ring      *hashRing
+	next      int
+	tlsConfig *tls.Config
 }
 
 var sampleConfig = `
-  ## TCP endpoint for your graphite instance.
-  ## If multiple endpoints are configured, output will be load balanced.
-  ## Only one of the endpoints will be written to with each iteration.
+  ## TCP endpoints for your graphite instances, as "host:port". With the
+  ## consistent_hash mode, a carbon instance name can be appended, as in
+  ## carbon-relay destinations: "host:port:instance".
   servers = ["localhost:2003"]
+  ## How metrics are distributed across servers:
+  ##   random: each write goes to a random server, or the next one on error.
+  ##   failover: each write goes to the first server available, in order.
+  ##   round_robin: writes go to each server in turn, or the next on error.
+  ##   consistent_hash: each series always goes to the same server, which is
+  ##     chosen like carbon-relay does with RELAY_METHOD = consistent-hashing.
+  # mode = "random"
+  ## Protocol of the servers, "plaintext" (usually port 2003) or
+  ## "pickle" (usually port 2004).
+  # protocol = "plaintext"
   ## Prefix metrics name
   prefix = ""
   ## Graphite output template
   ## see https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   template = "host.tags.measurement.field"
-  ## timeout in seconds for the write connection to graphite
-  timeout = 2
+  ## timeout for connecting and writing to graphite
+  timeout = "2s"
+
+  ## Optional SSL Config, connections use TLS when any of these is set
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
 `
 
+// server is a graphite server, and its connection once established.
+type server struct {
+	address  string
+	host     string
+	instance string
+
+	conn net.Conn
+	// no connection is attempted before retryAt, after a failure
+	retryAt    time.Time
+	retryDelay time.Duration
+}
+
+// parseServer parses a server as "host:port", or "host:port:instance".
+func parseServer(s string) (*server, error) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return &server{address: s, host: host}, nil
+	}
+
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid graphite server %q, must be host:port", s)
+	}
+	host, _, err := net.SplitHostPort(s[:i])
+	if err != nil {
+		return nil, fmt.Errorf("invalid graphite server %q, must be host:port", s)
+	}
+	return &server{address: s[:i], host: host, instance: s[i+1:]}, nil
+}
+
 func (g *Graphite) Connect() error {
 	// Set default values
-	if g.Timeout <= 0 {
-		g.Timeout = 2
+	if g.Timeout.Duration <= 0 {
+		g.Timeout.Duration = defaultTimeout
 	}
 	if len(g.Servers) == 0 {
-		g.Servers = append(g.Servers, "localhost:2003")
-	}
-	// Get Connections
-	var conns []net.Conn
-	for _, server := range g.Servers {
-		conn, err := net.DialTimeout("tcp", server, time.Duration(g.Timeout)*time.Second)
-		if err == nil {
-			conns = append(conns, conn)
+		g.Servers = append(g.Servers, defaultServer)
+	}
+
+	switch g.Mode {
+	case "", "random", "failover", "round_robin", "consistent_hash":
+	default:
+		return fmt.Errorf("invalid graphite mode %q", g.Mode)
+	}
+	switch g.Protocol {
+	case "", "plaintext", "pickle":
+	default:
+		return fmt.Errorf("invalid graphite protocol %q", g.Protocol)
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(
+		g.SSLCert, g.SSLKey, g.SSLCA, g.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	g.tlsConfig = tlsConfig
+
+	g.Close()
+	g.servers = nil
+	for _, s := range g.Servers {
+		srv, err := parseServer(s)
+		if err != nil {
+			return err
+		}
+		g.servers = append(g.servers, srv)
+	}
+	if g.Mode == "consistent_hash" {
+		g.ring = newHashRing(g.servers)
+	}
+
+	// Get Connections, servers not reachable yet are connected to when
+	// written to.
+	for _, srv := range g.servers {
+		if err := g.connect(srv); err != nil {
+			g.Log.Error(err.Error())
 		}
 	}
-	g.conns = conns
 	return nil
 }
 
+// connect connects to srv, unless it is already connected or still waiting
+// to be retried after a failure.
+func (g *Graphite) connect(srv *server) error {
+	if srv.conn != nil {
+		return nil
+	}
+	if time.Now().Before(srv.retryAt) {
+		return fmt.Errorf("not connecting to %s before %s", srv.address,
+			srv.retryAt.Format(time.RFC3339))
+	}
+
+	dialer := &net.Dialer{Timeout: g.Timeout.Duration}
+	var conn net.Conn
+	var err error
+	if g.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", srv.address, g.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", srv.address)
+	}
+	if err != nil {
+		g.failed(srv)
+		return err
+	}
+	srv.conn = conn
+	srv.retryDelay = 0
+	return nil
+}
+
+// failed closes the connection to srv, and backs off connecting again.
+func (g *Graphite) failed(srv *server) {
+	if srv.conn != nil {
+		srv.conn.Close()
+		srv.conn = nil
+	}
+
+	srv.retryDelay *= 2
+	if srv.retryDelay < minRetryDelay {
+		srv.retryDelay = minRetryDelay
+	}
+	if srv.retryDelay > maxRetryDelay {
+		srv.retryDelay = maxRetryDelay
+	}
+	srv.retryAt = time.Now().Add(srv.retryDelay)
+}
+
 func (g *Graphite) Close() error {
 	// Closing all connections
-	for _, conn := range g.conns {
-		conn.Close()
+	for _, srv := range g.servers {
+		if srv.conn != nil {
+			srv.conn.Close()
+			srv.conn = nil
+		}
 	}
 	return nil
 }
@@ -72,7 +228,10 @@ func (g *Graphite) Description() string {
 	return "Configuration for Graphite server to send metrics to"
 }
 
-// Choose a random server in the cluster to write to until a successful write
+// Write the metrics to the servers chosen by the mode. With the consistent
+// hash mode, the metrics of each server are written to it, and an error is
+// returned if any of them failed. With the other modes, all metrics are
+// written to one server, trying the others in turn until a successful write
 // occurs, logging each unsuccessful. If all servers fail, return error.
 func (g *Graphite) Write(metrics []telegraf.Metric) error {
 	// Prepare data
@@ -85,40 +244,106 @@ func (g *Graphite) Write(metrics []telegraf.Metric) error {
 	for _, metric := range metrics {
 		gMetrics, err := s.Serialize(metric)
 		if err != nil {
-			log.Printf("E! Error serializing some metrics to graphite: %s", err.Error())
+			g.Log.Errorf("Error serializing some metrics: %s", err.Error())
 		}
 		bp = append(bp, gMetrics...)
 	}
-	graphitePoints := strings.Join(bp, "\n") + "\n"
+	if len(bp) == 0 {
+		return nil
+	}
 
-	// This will get set to nil if a successful write occurs
-	err = errors.New("Could not write to any Graphite server in cluster\n")
+	if g.Mode == "consistent_hash" {
+		return g.writeHashed(bp)
+	}
 
-	// Send data to a random server
-	p := rand.Perm(len(g.conns))
-	for _, n := range p {
-		if g.Timeout > 0 {
-			g.conns[n].SetWriteDeadline(time.Now().Add(time.Duration(g.Timeout) * time.Second))
+	data := g.encode(bp)
+	for _, n := range g.order() {
+		if g.send(g.servers[n], data) {
+			return nil
 		}
-		if _, e := g.conns[n].Write([]byte(graphitePoints)); e != nil {
-			// Error
-			log.Println("E! Graphite Error: " + e.Error())
-			// Let's try the next one
-		} else {
-			// Success
-			err = nil
-			break
+		// Let's try the next one
+	}
+	return errors.New("Could not write to any Graphite server in cluster\n")
+}
+
+// writeHashed writes each line to the server its series hashes to.
+func (g *Graphite) writeHashed(lines []string) error {
+	batches := make(map[*server][]string)
+	for _, line := range lines {
+		srv := g.ring.get(seriesName(line))
+		batches[srv] = append(batches[srv], line)
+	}
+
+	var failed []string
+	for _, srv := range g.servers {
+		if batch, ok := batches[srv]; ok && !g.send(srv, g.encode(batch)) {
+			failed = append(failed, srv.address)
 		}
 	}
-	// try to reconnect
-	if err != nil {
-		g.Connect()
+	if len(failed) > 0 {
+		return fmt.Errorf("Could not write to Graphite servers: %s",
+			strings.Join(failed, ", "))
 	}
-	return err
+	return nil
+}
+
+// order returns the indexes of the servers in the order to try them.
+func (g *Graphite) order() []int {
+	switch g.Mode {
+	case "failover":
+		order := make([]int, len(g.servers))
+		for i := range order {
+			order[i] = i
+		}
+		return order
+	case "round_robin":
+		order := make([]int, len(g.servers))
+		for i := range order {
+			order[i] = (g.next + i) % len(g.servers)
+		}
+		g.next = (g.next + 1) % len(g.servers)
+		return order
+	default:
+		return rand.Perm(len(g.servers))
+	}
+}
+
+// encode encodes lines in the plaintext format for the protocol.
+func (g *Graphite) encode(lines []string) []byte {
+	if g.Protocol == "pickle" {
+		return encodePickle(lines, g.Log)
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// send writes data to srv, returning whether the write succeeded.
+func (g *Graphite) send(srv *server, data []byte) bool {
+	if err := g.connect(srv); err != nil {
+		g.Log.Error(err.Error())
+		return false
+	}
+
+	srv.conn.SetWriteDeadline(time.Now().Add(g.Timeout.Duration))
+	if _, err := srv.conn.Write(data); err != nil {
+		g.Log.Error(err.Error())
+		g.failed(srv)
+		return false
+	}
+	return true
+}
+
+// seriesName returns the series name of a plaintext line.
+func seriesName(line string) string {
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		return line[:i]
+	}
+	return line
 }
 
 func init() {
 	outputs.Add("graphite", func() telegraf.Output {
-		return &Graphite{}
+		return &Graphite{
+			Timeout: internal.Duration{Duration: defaultTimeout},
+		}
 	})
 }
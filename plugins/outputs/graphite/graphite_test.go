@@ -2,6 +2,7 @@ package graphite
 
 import (
 	"bufio"
+	"crypto/tls"
 	"net"
 	"net/textproto"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,6 +21,7 @@ func TestGraphiteError(t *testing.T) {
 	g := Graphite{
 		Servers: []string{"127.0.0.1:2003", "127.0.0.1:12003"},
 		Prefix:  "my.prefix",
+		Log:     testutil.Logger{Name: "outputs.graphite"},
 	}
 	// Init metrics
 	m1, _ := telegraf.NewMetric(
@@ -49,6 +52,7 @@ func TestGraphiteOK(t *testing.T) {
 	// Init plugin
 	g := Graphite{
 		Prefix: "my.prefix",
+		Log:    testutil.Logger{Name: "outputs.graphite"},
 	}
 	// Init metrics
 	m1, _ := telegraf.NewMetric(
@@ -97,3 +101,230 @@ func TCPServer(t *testing.T, wg *sync.WaitGroup) {
 	assert.Equal(t, "my.prefix.192_168_0_1.my_measurement 3.14 1289430000", data3)
 	conn.Close()
 }
+
+// testServer is a graphite server receiving plaintext lines.
+type testServer struct {
+	listener net.Listener
+	lines    chan string
+}
+
+func newTestServer(t *testing.T, tlsConfig *tls.Config) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s := &testServer{listener: listener, lines: make(chan string, 100)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				tp := textproto.NewReader(bufio.NewReader(conn))
+				for {
+					line, err := tp.ReadLine()
+					if err != nil {
+						return
+					}
+					s.lines <- line
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *testServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+// received returns the lines received within a short time.
+func (s *testServer) received() []string {
+	var lines []string
+	for {
+		select {
+		case line := <-s.lines:
+			lines = append(lines, line)
+		case <-time.After(50 * time.Millisecond):
+			return lines
+		}
+	}
+}
+
+func testMetric(name string) telegraf.Metric {
+	m, _ := telegraf.NewMetric(
+		name,
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"value": float64(1)},
+		time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
+	)
+	return m
+}
+
+func TestGraphiteFailover(t *testing.T) {
+	s1, s2 := newTestServer(t, nil), newTestServer(t, nil)
+	defer s2.listener.Close()
+	down := s1.addr()
+	s1.listener.Close()
+
+	g := Graphite{
+		Servers: []string{down, s2.addr()},
+		Mode:    "failover",
+		Log:     testutil.Logger{Name: "outputs.graphite"},
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	require.NoError(t, g.Write([]telegraf.Metric{testMetric("cpu")}))
+	require.NoError(t, g.Write([]telegraf.Metric{testMetric("mem")}))
+	assert.Equal(t, []string{
+		"server01.cpu 1 1289430000",
+		"server01.mem 1 1289430000",
+	}, s2.received())
+}
+
+func TestGraphiteRoundRobin(t *testing.T) {
+	s1, s2 := newTestServer(t, nil), newTestServer(t, nil)
+	defer s1.listener.Close()
+	defer s2.listener.Close()
+
+	g := Graphite{
+		Servers: []string{s1.addr(), s2.addr()},
+		Mode:    "round_robin",
+		Log:     testutil.Logger{Name: "outputs.graphite"},
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	for _, name := range []string{"cpu", "mem", "disk"} {
+		require.NoError(t, g.Write([]telegraf.Metric{testMetric(name)}))
+	}
+	assert.Equal(t, []string{
+		"server01.cpu 1 1289430000",
+		"server01.disk 1 1289430000",
+	}, s1.received())
+	assert.Equal(t, []string{"server01.mem 1 1289430000"}, s2.received())
+}
+
+func TestGraphiteConsistentHash(t *testing.T) {
+	s1, s2 := newTestServer(t, nil), newTestServer(t, nil)
+	defer s1.listener.Close()
+	defer s2.listener.Close()
+
+	g := Graphite{
+		Servers: []string{s1.addr(), s2.addr()},
+		Mode:    "consistent_hash",
+		Log:     testutil.Logger{Name: "outputs.graphite"},
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	var metrics []telegraf.Metric
+	names := []string{"cpu", "mem", "disk", "net", "swap", "system"}
+	for _, name := range names {
+		metrics = append(metrics, testMetric(name))
+	}
+	require.NoError(t, g.Write(metrics))
+
+	received := map[*server][]string{
+		g.servers[0]: s1.received(),
+		g.servers[1]: s2.received(),
+	}
+	assert.Equal(t, len(names), len(received[g.servers[0]])+len(received[g.servers[1]]))
+	for srv, lines := range received {
+		for _, line := range lines {
+			assert.Equal(t, srv, g.ring.get(seriesName(line)), line)
+		}
+	}
+}
+
+func TestHashRingMatchesCarbon(t *testing.T) {
+	var servers []*server
+	for _, s := range []string{"10.0.0.1:2004:a", "10.0.0.2:2004:b", "10.0.0.3:2004"} {
+		srv, err := parseServer(s)
+		require.NoError(t, err)
+		servers = append(servers, srv)
+	}
+	r := newHashRing(servers)
+
+	// as computed by carbon's ConsistentHashRing
+	tests := map[string]string{
+		"a.b.c":                  "('10.0.0.1', 'a')",
+		"servers.web01.cpu.idle": "('10.0.0.3', None)",
+		"foo":                    "('10.0.0.3', None)",
+		"x.y.z.1":                "('10.0.0.2', 'b')",
+		"x.y.z.2":                "('10.0.0.1', 'a')",
+	}
+	for series, node := range tests {
+		assert.Equal(t, node, nodeKey(r.get(series)), series)
+	}
+}
+
+func TestEncodePickle(t *testing.T) {
+	b := encodePickle([]string{
+		"my.prefix.host.cpu 3.14 1289430000",
+		"a.b 42 1289430010",
+		`dropped "string" 1289430010`,
+	}, testutil.Logger{Name: "outputs.graphite"})
+	// pickle.loads() of the payload is:
+	// [('my.prefix.host.cpu', (1289430000, 3.14)), ('a.b', (1289430010, 42.0))]
+	expected := "\x00\x00\x00\x45\x80\x02](" +
+		"X\x12\x00\x00\x00my.prefix.host.cpuJ\xf0\x23\xdb\x4cG\x40\x09\x1e\xb8\x51\xeb\x85\x1f\x86\x86" +
+		"X\x03\x00\x00\x00a.bJ\xfa\x23\xdb\x4cG\x40\x45\x00\x00\x00\x00\x00\x00\x86\x86" +
+		"e."
+	assert.Equal(t, []byte(expected), b)
+}
+
+func TestGraphiteBackoff(t *testing.T) {
+	s := newTestServer(t, nil)
+	addr := s.addr()
+	s.listener.Close()
+
+	g := Graphite{
+		Servers: []string{addr},
+		Log:     testutil.Logger{Name: "outputs.graphite"},
+	}
+	require.NoError(t, g.Connect())
+	srv := g.servers[0]
+	assert.Equal(t, minRetryDelay, srv.retryDelay)
+
+	// no connection is attempted until the delay expired.
+	assert.Error(t, g.Write([]telegraf.Metric{testMetric("cpu")}))
+	assert.Equal(t, minRetryDelay, srv.retryDelay)
+
+	srv.retryAt = time.Now()
+	assert.Error(t, g.Write([]telegraf.Metric{testMetric("cpu")}))
+	assert.Equal(t, 2*minRetryDelay, srv.retryDelay)
+}
+
+func TestGraphiteInvalidConfig(t *testing.T) {
+	g := Graphite{Mode: "broadcast"}
+	assert.Error(t, g.Connect())
+
+	g = Graphite{Protocol: "udp"}
+	assert.Error(t, g.Connect())
+
+	g = Graphite{Servers: []string{"localhost"}}
+	assert.Error(t, g.Connect())
+}
+
+func TestGraphiteTLS(t *testing.T) {
+	pki := testutil.NewPKI()
+	s := newTestServer(t, pki.ServerTLSConfig())
+	defer s.listener.Close()
+
+	g := Graphite{
+		Servers: []string{s.addr()},
+		SSLCA:   pki.CACertPath(),
+		SSLCert: pki.ClientCertPath(),
+		SSLKey:  pki.ClientKeyPath(),
+		Log:     testutil.Logger{Name: "outputs.graphite"},
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	require.NoError(t, g.Write([]telegraf.Metric{testMetric("cpu")}))
+	assert.Equal(t, []string{"server01.cpu 1 1289430000"}, s.received())
+}
@@ -0,0 +1,72 @@
+package graphite
+
+import (
+	"crypto/md5"
+	"fmt"
+	"sort"
+)
+
+// replicas is the number of positions of each server on the ring.
+const replicas = 100
+
+// hashRing distributes series across servers by consistent hashing, the same
+// way the carbon_ch hash type of carbon-relay does, so that telegraf and
+// carbon-relay send each series to the same carbon instance.
+type hashRing struct {
+	entries []ringEntry
+}
+
+type ringEntry struct {
+	position int
+	server   *server
+}
+
+type byPosition []ringEntry
+
+func (a byPosition) Len() int           { return len(a) }
+func (a byPosition) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byPosition) Less(i, j int) bool { return a[i].position < a[j].position }
+
+func newHashRing(servers []*server) *hashRing {
+	r := &hashRing{}
+	taken := make(map[int]bool)
+	for _, srv := range servers {
+		key := nodeKey(srv)
+		for i := 0; i < replicas; i++ {
+			position := ringPosition(fmt.Sprintf("%s:%d", key, i))
+			for taken[position] {
+				position++
+			}
+			taken[position] = true
+			r.entries = append(r.entries, ringEntry{position: position, server: srv})
+		}
+	}
+	sort.Sort(byPosition(r.entries))
+	return r
+}
+
+// get returns the server of the series.
+func (r *hashRing) get(series string) *server {
+	position := ringPosition(series)
+	i := sort.Search(len(r.entries), func(i int) bool {
+		return r.entries[i].position >= position
+	})
+	return r.entries[i%len(r.entries)].server
+}
+
+// nodeKey returns the key carbon hashes a server by, the python
+// representation of its (host, instance) tuple.
+func nodeKey(srv *server) string {
+	instance := "None"
+	if srv.instance != "" {
+		instance = "'" + srv.instance + "'"
+	}
+	return fmt.Sprintf("('%s', %s)", srv.host, instance)
+}
+
+// ringPosition returns the position of key on the ring, the first 16 bits
+// of its md5 hash.
+func ringPosition(key string) int {
+	sum := md5.Sum([]byte(key))
+	return int(sum[0])<<8 | int(sum[1])
+}
@@ -0,0 +1,78 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// pickle opcodes, of protocol 2
+const (
+	opProto      = 0x80
+	opEmptyList  = ']'
+	opMark       = '('
+	opAppends    = 'e'
+	opBinUnicode = 'X'
+	opBinInt     = 'J'
+	opBinFloat   = 'G'
+	opTuple2     = 0x86
+	opStop       = '.'
+)
+
+// encodePickle encodes plaintext lines as the message received by the carbon
+// pickle receiver: the length of the payload as a 4 byte big endian integer,
+// followed by the pickled list of (path, (timestamp, value)) tuples.
+// Lines which values are not numbers are dropped, and logged with log.
+func encodePickle(lines []string, log telegraf.Logger) []byte {
+	var p bytes.Buffer
+	p.Write([]byte{opProto, 2, opEmptyList, opMark})
+
+	var b [8]byte
+	for _, line := range lines {
+		parts := strings.Split(line, " ")
+		if len(parts) != 3 {
+			log.Errorf("Dropping invalid line: %s", line)
+			continue
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			log.Debugf("Dropping line with a value which is not a number: %s", line)
+			continue
+		}
+		timestamp, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			log.Errorf("Dropping invalid line: %s", line)
+			continue
+		}
+
+		p.WriteByte(opBinUnicode)
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(parts[0])))
+		p.Write(b[:4])
+		p.WriteString(parts[0])
+
+		if timestamp >= math.MinInt32 && timestamp <= math.MaxInt32 {
+			p.WriteByte(opBinInt)
+			binary.LittleEndian.PutUint32(b[:4], uint32(int32(timestamp)))
+			p.Write(b[:4])
+		} else {
+			p.WriteByte(opBinFloat)
+			binary.BigEndian.PutUint64(b[:], math.Float64bits(float64(timestamp)))
+			p.Write(b[:])
+		}
+
+		p.WriteByte(opBinFloat)
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(value))
+		p.Write(b[:])
+
+		p.Write([]byte{opTuple2, opTuple2})
+	}
+	p.Write([]byte{opAppends, opStop})
+
+	out := make([]byte, 4, 4+p.Len())
+	binary.BigEndian.PutUint32(out, uint32(p.Len()))
+	return append(out, p.Bytes()...)
+}
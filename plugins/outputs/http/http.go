@@ -0,0 +1,259 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+var sampleConfig = `
+  ## URL is the address to send metrics to
+  url = "http://127.0.0.1:8080/metric"
+
+  ## Timeout for HTTP message
+  # timeout = "5s"
+
+  ## HTTP method, one of: "POST" or "PUT"
+  # method = "POST"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Optional path to a file containing a bearer token, sent in the
+  ## Authorization header.
+  # bearer_token = "/path/to/bearer/token"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
+  ## compress body or "identity" to apply no encoding.
+  # content_encoding = "identity"
+
+  ## Status codes of responses to a successful write. Defaults to any 2xx
+  ## status code.
+  # success_status_codes = [200, 204]
+
+  ## Additional HTTP headers
+  # [outputs.http.headers]
+  #   # Should be set manually to "application/json" for json data_format
+  #   Content-Type = "text/plain; charset=utf-8"
+
+  ## Data format to output.
+  ## Each data format has it's own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  # data_format = "influx"
+`
+
+const (
+	defaultClientTimeout = 5 * time.Second
+	defaultContentType   = "text/plain; charset=utf-8"
+	defaultMethod        = http.MethodPost
+)
+
+type HTTP struct {
+	URL                string
+	Timeout            internal.Duration
+	Method             string
+	Username           string
+	Password           string
+	BearerToken        string `toml:"bearer_token"`
+	Headers            map[string]string
+	ContentEncoding    string `toml:"content_encoding"`
+	SuccessStatusCodes []int  `toml:"success_status_codes"`
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client     *http.Client
+	serializer serializers.Serializer
+}
+
+func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
+	h.serializer = serializer
+}
+
+func (h *HTTP) Connect() error {
+	if h.URL == "" {
+		return fmt.Errorf("url is a required field for http output")
+	}
+
+	if h.Method == "" {
+		h.Method = defaultMethod
+	}
+	h.Method = strings.ToUpper(h.Method)
+	if h.Method != http.MethodPost && h.Method != http.MethodPut {
+		return fmt.Errorf("invalid method [%s] %s", h.URL, h.Method)
+	}
+
+	switch h.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content encoding %q", h.ContentEncoding)
+	}
+
+	if h.Timeout.Duration == 0 {
+		h.Timeout.Duration = defaultClientTimeout
+	}
+
+	tlsCfg, err := internal.GetTLSConfig(
+		h.SSLCert, h.SSLKey, h.SSLCA, h.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+
+	h.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: h.Timeout.Duration,
+	}
+	return nil
+}
+
+func (h *HTTP) Close() error {
+	return nil
+}
+
+func (h *HTTP) Description() string {
+	return "A plugin that can transmit metrics over HTTP"
+}
+
+func (h *HTTP) SampleConfig() string {
+	return sampleConfig
+}
+
+// Write sends the metrics in the body of one request. Requests which fail
+// with a server error or are throttled are returned as errors, so that the
+// metrics are kept by the running output and written again later. Metrics
+// rejected by the server for any other reason would be rejected again, and
+// are dropped, as are metrics which cannot be serialized.
+func (h *HTTP) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, metric := range metrics {
+		values, err := h.serializer.Serialize(metric)
+		if err != nil {
+			log.Printf("E! Could not serialize metric: %v", err)
+			continue
+		}
+		for _, value := range values {
+			buf.WriteString(value)
+			buf.WriteByte('\n')
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	return h.write(buf.Bytes())
+}
+
+func (h *HTTP) write(reqBody []byte) error {
+	var body io.Reader = bytes.NewReader(reqBody)
+	if h.ContentEncoding == "gzip" {
+		var zbuf bytes.Buffer
+		zw := gzip.NewWriter(&zbuf)
+		if _, err := zw.Write(reqBody); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = &zbuf
+	}
+
+	req, err := http.NewRequest(h.Method, h.URL, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", defaultContentType)
+	if h.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range h.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	if h.Username != "" || h.Password != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	if h.BearerToken != "" {
+		token, err := ioutil.ReadFile(h.BearerToken)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error writing to %s: %s", h.URL, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if h.isSuccess(resp.StatusCode) {
+		return nil
+	}
+
+	err = fmt.Errorf("when writing to [%s] received status code: %d, body: %s",
+		h.URL, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	log.Printf("E! Dropping metrics rejected by the server: %s", err)
+	return nil
+}
+
+func (h *HTTP) isSuccess(code int) bool {
+	if len(h.SuccessStatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, c := range h.SuccessStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	outputs.Add("http", func() telegraf.Output {
+		return &HTTP{
+			Timeout: internal.Duration{Duration: defaultClientTimeout},
+			Method:  defaultMethod,
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHTTP(url string) *HTTP {
+	h := &HTTP{
+		URL:     url,
+		Timeout: internal.Duration{Duration: time.Second},
+	}
+	s, _ := serializers.NewInfluxSerializer()
+	h.SetSerializer(s)
+	return h
+}
+
+func expectedBody(t *testing.T, h *HTTP, metrics []telegraf.Metric) string {
+	var body string
+	for _, m := range metrics {
+		values, err := h.serializer.Serialize(m)
+		require.NoError(t, err)
+		for _, v := range values {
+			body += v + "\n"
+		}
+	}
+	return body
+}
+
+func TestHTTPWrite(t *testing.T) {
+	var req *http.Request
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL + "/metric")
+	h.Headers = map[string]string{"X-Custom": "value", "Content-Type": "application/json"}
+	require.NoError(t, h.Connect())
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1.0), testutil.TestMetric(2.0)}
+	require.NoError(t, h.Write(metrics))
+
+	require.NotNil(t, req)
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "/metric", req.URL.Path)
+	assert.Equal(t, "value", req.Header.Get("X-Custom"))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "", req.Header.Get("Content-Encoding"))
+	assert.Equal(t, "", req.Header.Get("Authorization"))
+	assert.Equal(t, expectedBody(t, h, metrics), string(body))
+}
+
+// failingSerializer fails to serialize the metrics named "invalid".
+type failingSerializer struct {
+	serializers.Serializer
+}
+
+func (s failingSerializer) Serialize(m telegraf.Metric) ([]string, error) {
+	if m.Name() == "invalid" {
+		return nil, fmt.Errorf("invalid metric")
+	}
+	return s.Serializer.Serialize(m)
+}
+
+func TestHTTPWriteSerializeError(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.SetSerializer(failingSerializer{h.serializer})
+	require.NoError(t, h.Connect())
+
+	invalid := testutil.TestMetric(1.0, "invalid")
+	valid := testutil.TestMetric(2.0)
+	// metrics which cannot be serialized are dropped, not written again
+	require.NoError(t, h.Write([]telegraf.Metric{invalid, valid}))
+	assert.Equal(t, expectedBody(t, h, []telegraf.Metric{valid}), string(body))
+}
+
+func TestHTTPWriteMethod(t *testing.T) {
+	var method string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.Method = "put"
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	assert.Equal(t, "PUT", method)
+
+	h.Method = "GET"
+	assert.Error(t, h.Connect())
+}
+
+func TestHTTPWriteGzip(t *testing.T) {
+	var encoding string
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ = ioutil.ReadAll(zr)
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.ContentEncoding = "gzip"
+	require.NoError(t, h.Connect())
+
+	metrics := testutil.MockMetrics()
+	require.NoError(t, h.Write(metrics))
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, expectedBody(t, h, metrics), string(body))
+
+	h.ContentEncoding = "deflate"
+	assert.Error(t, h.Connect())
+}
+
+func TestHTTPWriteAuth(t *testing.T) {
+	var user, pass, auth string
+	var ok bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok = r.BasicAuth()
+		auth = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.Username = "telegraf"
+	h.Password = "secret"
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	assert.True(t, ok)
+	assert.Equal(t, "telegraf", user)
+	assert.Equal(t, "secret", pass)
+
+	token, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(token.Name())
+	token.WriteString("abc123\n")
+	token.Close()
+
+	h = newTestHTTP(ts.URL)
+	h.BearerToken = token.Name()
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	assert.Equal(t, "Bearer abc123", auth)
+}
+
+func TestHTTPWriteStatusCodes(t *testing.T) {
+	var status int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		status       int
+		successCodes []int
+		// whether the write returns an error, for the metrics to be retried
+		retried bool
+	}{
+		{http.StatusOK, nil, false},
+		{http.StatusAccepted, nil, false},
+		{http.StatusAccepted, []int{200}, false},
+		{http.StatusInternalServerError, nil, true},
+		{http.StatusServiceUnavailable, nil, true},
+		{http.StatusTooManyRequests, nil, true},
+		// rejected metrics are dropped rather than retried.
+		{http.StatusBadRequest, nil, false},
+		{http.StatusNotFound, nil, false},
+	}
+	for _, tt := range tests {
+		status = tt.status
+		h := newTestHTTP(ts.URL)
+		h.SuccessStatusCodes = tt.successCodes
+		require.NoError(t, h.Connect())
+
+		err := h.Write(testutil.MockMetrics())
+		if tt.retried {
+			assert.Error(t, err, "status %d", tt.status)
+		} else {
+			assert.NoError(t, err, "status %d", tt.status)
+		}
+	}
+}
+
+func TestHTTPWriteTLS(t *testing.T) {
+	pki := testutil.NewPKI()
+	var called bool
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	ts.TLS = pki.ServerTLSConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	h := newTestHTTP(ts.URL)
+	h.SSLCA = pki.CACertPath()
+	h.SSLCert = pki.ClientCertPath()
+	h.SSLKey = pki.ClientKeyPath()
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+	assert.True(t, called)
+
+	// without a client certificate the server refuses the connection.
+	h = newTestHTTP(ts.URL)
+	h.SSLCA = pki.CACertPath()
+	require.NoError(t, h.Connect())
+	assert.Error(t, h.Write(testutil.MockMetrics()))
+}
+
+func TestHTTPConnectNoURL(t *testing.T) {
+	h := newTestHTTP("")
+	assert.Error(t, h.Connect())
+}
@@ -92,7 +92,6 @@ func (i *Instrumental) Write(metrics []telegraf.Metric) error {
 	var points []string
 	var metricType string
 	var toSerialize telegraf.Metric
-	var newTags map[string]string
 
 	for _, metric := range metrics {
 		// Pull the metric_type out of the metric's tags. We don't want the type
@@ -106,16 +105,9 @@ func (i *Instrumental) Write(metrics []telegraf.Metric) error {
 		//
 		//  increment some_prefix.host.tag1.tag2.tag3.counter.field value timestamp
 		//
-		newTags = metric.Tags()
-		metricType = newTags["metric_type"]
-		delete(newTags, "metric_type")
-
-		toSerialize, _ = telegraf.NewMetric(
-			metric.Name(),
-			newTags,
-			metric.Fields(),
-			metric.Time(),
-		)
+		metricType, _ = metric.GetTag("metric_type")
+		toSerialize = metric.Copy()
+		toSerialize.RemoveTag("metric_type")
 
 		stats, err := s.Serialize(toSerialize)
 		if err != nil {
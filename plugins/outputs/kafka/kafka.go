@@ -3,6 +3,8 @@ package kafka
 import (
 	"crypto/tls"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
@@ -17,8 +19,20 @@ type Kafka struct {
 	Brokers []string
 	// Kafka topic
 	Topic string
+	// Tag whose value is used as the topic, when present
+	TopicTag string `toml:"topic_tag"`
+	// Suffix appended to the topic
+	TopicSuffix TopicSuffix `toml:"topic_suffix"`
 	// Routing Key Tag
 	RoutingTag string `toml:"routing_tag"`
+	// Partition key method, "" for the routing tag or "hash_id"
+	PartitionKey string `toml:"partition_key"`
+	// Send all metrics of a write to a topic and key as one message
+	Batch bool
+	// Use an asynchronous producer
+	Async bool
+	// Maximum number of unacknowledged messages of the asynchronous producer
+	MaxInFlight int `toml:"max_in_flight"`
 	// Compression Codec Tag
 	CompressionCodec int
 	// RequiredAcks Tag
@@ -44,21 +58,93 @@ type Kafka struct {
 	// Skip SSL verification
 	InsecureSkipVerify bool
 
-	tlsConfig tls.Config
-	producer  sarama.SyncProducer
+	tlsConfig     tls.Config
+	producer      sarama.SyncProducer
+	asyncProducer sarama.AsyncProducer
 
 	serializer serializers.Serializer
 }
 
+// TopicSuffix builds a suffix appended to the topic of each metric, from its
+// measurement name or from the values of tags.
+type TopicSuffix struct {
+	// Method is one of "measurement" or "tags"
+	Method    string
+	Keys      []string
+	Separator string
+}
+
+func (ts *TopicSuffix) validate() error {
+	switch ts.Method {
+	case "", "measurement":
+	case "tags":
+		if len(ts.Keys) == 0 {
+			return fmt.Errorf("topic_suffix method \"tags\" requires keys")
+		}
+	default:
+		return fmt.Errorf("unknown topic_suffix method %q", ts.Method)
+	}
+	return nil
+}
+
+func (ts *TopicSuffix) suffix(metric telegraf.Metric) string {
+	switch ts.Method {
+	case "measurement":
+		return ts.Separator + metric.Name()
+	case "tags":
+		var suffix string
+		tags := metric.Tags()
+		for _, key := range ts.Keys {
+			if value, ok := tags[key]; ok && value != "" {
+				suffix += ts.Separator + value
+			}
+		}
+		return suffix
+	}
+	return ""
+}
+
 var sampleConfig = `
   ## URLs of kafka brokers
   brokers = ["localhost:9092"]
   ## Kafka topic for producer messages
   topic = "telegraf"
+  ## Telegraf tag to use as the topic
+  ##  ie, if this tag exists, it's value will be used as the topic instead of
+  ##  the topic option
+  # topic_tag = ""
+
+  ## Optional suffix appended to the topic of each metric, either the
+  ## measurement name or the values of the given tags joined by the
+  ## separator, skipping tags the metric doesn't have.
+  ##   method = "measurement": telegraf_cpu, telegraf_mem, ...
+  ##   method = "tags", keys = ["host", "env"]: telegraf_server01_prod, ...
+  # [outputs.kafka.topic_suffix]
+  #   method = "measurement"
+  #   keys = ["host", "env"]
+  #   separator = "_"
+
   ## Telegraf tag to use as a routing key
   ##  ie, if this tag exists, it's value will be used as the routing key
   routing_tag = "host"
 
+  ## Method to key messages by, overriding the routing tag:
+  ##   "hash_id": hash of the measurement name and tags, so that each series
+  ##              is always written to the same partition
+  # partition_key = "hash_id"
+
+  ## Send all metrics of a write with the same topic and key in a single
+  ## message, for data formats that can hold multiple metrics.
+  # batch = false
+
+  ## Use an asynchronous producer, sending messages without waiting for each
+  ## to be acknowledged in turn. A write still fails, and is retried, if any
+  ## of its messages fails.
+  # async = false
+  ## Maximum number of messages waiting to be acknowledged by the
+  ## asynchronous producer
+  # max_in_flight = 1000
+
   ## CompressionCodec represents the various compression codecs recognized by
   ## Kafka in messages.
   ##  0 : No compression
@@ -104,6 +190,15 @@ func (k *Kafka) SetSerializer(serializer serializers.Serializer) {
 }
 
 func (k *Kafka) Connect() error {
+	if err := k.TopicSuffix.validate(); err != nil {
+		return err
+	}
+	switch k.PartitionKey {
+	case "", "hash_id":
+	default:
+		return fmt.Errorf("unknown partition_key %q", k.PartitionKey)
+	}
+
 	config := sarama.NewConfig()
 
 	config.Producer.RequiredAcks = sarama.RequiredAcks(k.RequiredAcks)
@@ -128,6 +223,17 @@ func (k *Kafka) Connect() error {
 		config.Net.TLS.Enable = true
 	}
 
+	if k.Async {
+		config.Producer.Return.Successes = true
+		config.Producer.Return.Errors = true
+		producer, err := sarama.NewAsyncProducer(k.Brokers, config)
+		if err != nil {
+			return err
+		}
+		k.asyncProducer = producer
+		return nil
+	}
+
 	producer, err := sarama.NewSyncProducer(k.Brokers, config)
 	if err != nil {
 		return err
@@ -137,6 +243,9 @@ func (k *Kafka) Connect() error {
 }
 
 func (k *Kafka) Close() error {
+	if k.asyncProducer != nil {
+		return k.asyncProducer.Close()
+	}
 	return k.producer.Close()
 }
 
@@ -153,28 +262,116 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	msgs, err := k.messages(metrics)
+	if err != nil {
+		return err
+	}
+
+	if k.asyncProducer != nil {
+		err = k.sendAsync(msgs)
+	} else {
+		err = k.producer.SendMessages(msgs)
+	}
+	if err != nil {
+		return fmt.Errorf("FAILED to send kafka message: %s\n", err)
+	}
+	return nil
+}
+
+func (k *Kafka) topic(metric telegraf.Metric) string {
+	topic := k.Topic
+	if k.TopicTag != "" {
+		if t, ok := metric.Tags()[k.TopicTag]; ok && t != "" {
+			topic = t
+		}
+	}
+	return topic + k.TopicSuffix.suffix(metric)
+}
+
+func (k *Kafka) key(metric telegraf.Metric) string {
+	if k.PartitionKey == "hash_id" {
+		return strconv.FormatUint(metric.HashID(), 16)
+	}
+	return metric.Tags()[k.RoutingTag]
+}
+
+// messages serializes the metrics into producer messages, either one message
+// per serialized value or, in batch mode, one message per topic and key.
+func (k *Kafka) messages(metrics []telegraf.Metric) ([]*sarama.ProducerMessage, error) {
+	var msgs []*sarama.ProducerMessage
+	// indexes of batch messages by topic and key
+	batches := make(map[[2]string]int)
+	var values [][]string
+
 	for _, metric := range metrics {
-		values, err := k.serializer.Serialize(metric)
+		serialized, err := k.serializer.Serialize(metric)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		var pubErr error
-		for _, value := range values {
-			m := &sarama.ProducerMessage{
-				Topic: k.Topic,
-				Value: sarama.StringEncoder(value),
-			}
-			if h, ok := metric.Tags()[k.RoutingTag]; ok {
-				m.Key = sarama.StringEncoder(h)
+		topic, key := k.topic(metric), k.key(metric)
+		if k.Batch {
+			i, ok := batches[[2]string{topic, key}]
+			if !ok {
+				i = len(msgs)
+				batches[[2]string{topic, key}] = i
+				msgs = append(msgs, newMessage(topic, key))
+				values = append(values, nil)
 			}
+			values[i] = append(values[i], serialized...)
+			continue
+		}
 
-			_, _, pubErr = k.producer.SendMessage(m)
+		for _, value := range serialized {
+			m := newMessage(topic, key)
+			m.Value = sarama.StringEncoder(value)
+			msgs = append(msgs, m)
 		}
+	}
+
+	for i, v := range values {
+		msgs[i].Value = sarama.StringEncoder(strings.Join(v, "\n"))
+	}
+	return msgs, nil
+}
 
-		if pubErr != nil {
-			return fmt.Errorf("FAILED to send kafka message: %s\n", pubErr)
+func newMessage(topic, key string) *sarama.ProducerMessage {
+	m := &sarama.ProducerMessage{Topic: topic}
+	if key != "" {
+		m.Key = sarama.StringEncoder(key)
+	}
+	return m
+}
+
+// sendAsync sends the messages with the asynchronous producer, keeping at
+// most MaxInFlight messages unacknowledged, and waits for all of them to be
+// acknowledged. The errors of failed messages are returned together.
+func (k *Kafka) sendAsync(msgs []*sarama.ProducerMessage) error {
+	var errs sarama.ProducerErrors
+	var inFlight int
+	for len(msgs) > 0 || inFlight > 0 {
+		// a nil input channel blocks, only waiting for acknowledgements.
+		var input chan<- *sarama.ProducerMessage
+		var next *sarama.ProducerMessage
+		if len(msgs) > 0 && (k.MaxInFlight <= 0 || inFlight < k.MaxInFlight) {
+			input = k.asyncProducer.Input()
+			next = msgs[0]
 		}
+
+		select {
+		case input <- next:
+			msgs = msgs[1:]
+			inFlight++
+		case <-k.asyncProducer.Successes():
+			inFlight--
+		case err := <-k.asyncProducer.Errors():
+			errs = append(errs, err)
+			inFlight--
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
@@ -184,6 +381,7 @@ func init() {
 		return &Kafka{
 			MaxRetry:     3,
 			RequiredAcks: -1,
+			MaxInFlight:  1000,
 		}
 	})
 }
@@ -2,9 +2,14 @@ package kafka
 
 import (
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +34,247 @@ func TestConnectAndWrite(t *testing.T) {
 	err = k.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+type fakeSyncProducer struct {
+	msgs []*sarama.ProducerMessage
+	err  error
+}
+
+func (p *fakeSyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	return 0, 0, p.SendMessages([]*sarama.ProducerMessage{msg})
+}
+
+func (p *fakeSyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *fakeSyncProducer) Close() error {
+	return nil
+}
+
+// fakeAsyncProducer acknowledges each message as it is input, failing those
+// whose value is "fail".
+type fakeAsyncProducer struct {
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+
+	msgs        []*sarama.ProducerMessage
+	maxInFlight int
+}
+
+func newFakeAsyncProducer() *fakeAsyncProducer {
+	p := &fakeAsyncProducer{
+		input:     make(chan *sarama.ProducerMessage),
+		successes: make(chan *sarama.ProducerMessage, 100),
+		errors:    make(chan *sarama.ProducerError, 100),
+	}
+	go func() {
+		for msg := range p.input {
+			p.msgs = append(p.msgs, msg)
+			if n := len(p.successes) + len(p.errors) + 1; n > p.maxInFlight {
+				p.maxInFlight = n
+			}
+			if v, _ := msg.Value.Encode(); string(v) == "fail" {
+				p.errors <- &sarama.ProducerError{Msg: msg, Err: sarama.ErrOutOfBrokers}
+			} else {
+				p.successes <- msg
+			}
+		}
+		close(p.successes)
+		close(p.errors)
+	}()
+	return p
+}
+
+func (p *fakeAsyncProducer) AsyncClose() {
+	close(p.input)
+}
+
+func (p *fakeAsyncProducer) Close() error {
+	p.AsyncClose()
+	return nil
+}
+
+func (p *fakeAsyncProducer) Input() chan<- *sarama.ProducerMessage {
+	return p.input
+}
+
+func (p *fakeAsyncProducer) Successes() <-chan *sarama.ProducerMessage {
+	return p.successes
+}
+
+func (p *fakeAsyncProducer) Errors() <-chan *sarama.ProducerError {
+	return p.errors
+}
+
+func newTestKafka() *Kafka {
+	s, _ := serializers.NewInfluxSerializer()
+	return &Kafka{
+		Topic:      "telegraf",
+		serializer: s,
+	}
+}
+
+func testMetric(name string, tags map[string]string) telegraf.Metric {
+	m, _ := telegraf.NewMetric(name, tags,
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	return m
+}
+
+func encode(t *testing.T, e sarama.Encoder) string {
+	if e == nil {
+		return ""
+	}
+	b, err := e.Encode()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestTopic(t *testing.T) {
+	m := testMetric("cpu", map[string]string{"host": "a", "env": "prod", "team": "x"})
+	tests := []struct {
+		topicTag string
+		suffix   TopicSuffix
+		expected string
+	}{
+		{"", TopicSuffix{}, "telegraf"},
+		{"team", TopicSuffix{}, "x"},
+		{"missing", TopicSuffix{}, "telegraf"},
+		{"", TopicSuffix{Method: "measurement", Separator: "_"}, "telegraf_cpu"},
+		{"", TopicSuffix{Method: "tags", Keys: []string{"host", "missing", "env"}, Separator: "."}, "telegraf.a.prod"},
+		{"team", TopicSuffix{Method: "measurement", Separator: "-"}, "x-cpu"},
+	}
+	for _, tt := range tests {
+		k := newTestKafka()
+		k.TopicTag = tt.topicTag
+		k.TopicSuffix = tt.suffix
+		assert.Equal(t, tt.expected, k.topic(m))
+	}
+}
+
+func TestMessages(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testMetric("cpu", map[string]string{"host": "a"}),
+		testMetric("cpu", map[string]string{"host": "b"}),
+		testMetric("cpu", map[string]string{"host": "a"}),
+		testMetric("mem", nil),
+	}
+
+	k := newTestKafka()
+	k.RoutingTag = "host"
+	msgs, err := k.messages(metrics)
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+	for i, m := range metrics {
+		values, _ := k.serializer.Serialize(m)
+		assert.Equal(t, "telegraf", msgs[i].Topic)
+		assert.Equal(t, m.Tags()["host"], encode(t, msgs[i].Key))
+		assert.Equal(t, values[0], encode(t, msgs[i].Value))
+	}
+
+	k.PartitionKey = "hash_id"
+	msgs, err = k.messages(metrics)
+	require.NoError(t, err)
+	assert.Equal(t, encode(t, msgs[0].Key), encode(t, msgs[2].Key))
+	assert.NotEqual(t, encode(t, msgs[0].Key), encode(t, msgs[1].Key))
+	assert.NotEqual(t, "", encode(t, msgs[3].Key))
+}
+
+func TestMessagesBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testMetric("cpu", map[string]string{"host": "a"}),
+		testMetric("cpu", map[string]string{"host": "b"}),
+		testMetric("mem", map[string]string{"host": "a"}),
+	}
+
+	k := newTestKafka()
+	k.RoutingTag = "host"
+	k.Batch = true
+	msgs, err := k.messages(metrics)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	v0, _ := k.serializer.Serialize(metrics[0])
+	v1, _ := k.serializer.Serialize(metrics[1])
+	v2, _ := k.serializer.Serialize(metrics[2])
+	assert.Equal(t, "a", encode(t, msgs[0].Key))
+	assert.Equal(t, v0[0]+"\n"+v2[0], encode(t, msgs[0].Value))
+	assert.Equal(t, "b", encode(t, msgs[1].Key))
+	assert.Equal(t, v1[0], encode(t, msgs[1].Value))
+}
+
+func TestWriteSync(t *testing.T) {
+	p := &fakeSyncProducer{}
+	k := newTestKafka()
+	k.producer = p
+
+	require.NoError(t, k.Write(testutil.MockMetrics()))
+	assert.Len(t, p.msgs, 1)
+
+	p.err = sarama.ErrOutOfBrokers
+	assert.Error(t, k.Write(testutil.MockMetrics()))
+}
+
+func TestWriteAsync(t *testing.T) {
+	p := newFakeAsyncProducer()
+	defer p.Close()
+
+	k := newTestKafka()
+	k.MaxInFlight = 2
+	k.asyncProducer = p
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		metrics = append(metrics, testutil.TestMetric(i))
+	}
+	require.NoError(t, k.Write(metrics))
+	assert.Len(t, p.msgs, 10)
+	assert.True(t, p.maxInFlight <= 2)
+}
+
+type failSerializer struct{}
+
+func (failSerializer) Serialize(metric telegraf.Metric) ([]string, error) {
+	if metric.Name() == "fail" {
+		return []string{"fail"}, nil
+	}
+	return []string{metric.Name()}, nil
+}
+
+func TestWriteAsyncError(t *testing.T) {
+	p := newFakeAsyncProducer()
+	defer p.Close()
+
+	k := newTestKafka()
+	k.serializer = failSerializer{}
+	k.asyncProducer = p
+
+	// the write fails when any of its messages fails, so that all of the
+	// metrics are retried.
+	metrics := []telegraf.Metric{
+		testMetric("cpu", nil),
+		testMetric("fail", nil),
+		testMetric("mem", nil),
+	}
+	assert.Error(t, k.Write(metrics))
+	assert.Len(t, p.msgs, 3)
+}
+
+func TestConnectInvalidConfig(t *testing.T) {
+	k := newTestKafka()
+	k.TopicSuffix = TopicSuffix{Method: "tags"}
+	assert.Error(t, k.Connect())
+
+	k = newTestKafka()
+	k.TopicSuffix = TopicSuffix{Method: "foo"}
+	assert.Error(t, k.Connect())
+
+	k = newTestKafka()
+	k.PartitionKey = "foo"
+	assert.Error(t, k.Connect())
+}
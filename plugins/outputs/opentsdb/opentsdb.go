@@ -1,15 +1,19 @@
 package opentsdb
 
 import (
+	"bytes"
 	"fmt"
-	"log"
 	"net"
+	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
@@ -20,20 +24,58 @@ type OpenTSDB struct {
 	Port int
 
 	HttpBatchSize int
+	// Number of times datapoints rejected by the Http API are sent again
+	HttpRetries int `toml:"http_retries"`
+	// Timeout of Http requests
+	Timeout internal.Duration
+	// Content-Encoding of Http request bodies, "gzip" or "identity"
+	ContentEncoding string `toml:"content_encoding"`
+
+	// HTTP Basic Auth Credentials
+	Username string
+	Password string
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	// How metric names and tags are sanitized, "basic", "strict" or "none"
+	Sanitize string
+	// Replacement of invalid characters by the strict sanitization
+	SanitizeReplacement string `toml:"sanitize_replacement"`
 
 	Debug bool
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+	// datapoints rejected by the Http API, to be sent again
+	retries []*HttpMetric
 }
 
 var sanitizedChars = strings.NewReplacer("@", "-", "*", "-", " ", "_",
 	`%`, "-", "#", "-", "$", "-", ":", "_")
 
+const (
+	defaultHttpBatchSize = 50
+	defaultHttpRetries   = 3
+	defaultTimeout       = 5 * time.Second
+)
+
 var sampleConfig = `
   ## prefix for metrics keys
   prefix = "my.specific.prefix."
 
   ## DNS name of the OpenTSDB server
   ## Using "opentsdb.example.com" or "tcp://opentsdb.example.com" will use the
-  ## telnet API. "http://opentsdb.example.com" will use the Http API.
+  ## telnet API. "http://opentsdb.example.com" or
+  ## "https://opentsdb.example.com" will use the Http API, which is
+  ## recommended since OpenTSDB 2.0.
   host = "opentsdb.example.com"
 
   ## Port of the OpenTSDB server
@@ -43,6 +85,36 @@ var sampleConfig = `
   ## Not used with telnet API.
   httpBatchSize = 50
 
+  ## Number of times data points rejected by OpenTSDB are sent again with
+  ## the next writes, before being dropped. Not used with telnet API.
+  # http_retries = 3
+
+  ## Timeout of Http requests
+  # timeout = "5s"
+
+  ## Content-Encoding of Http request bodies, "gzip" or "identity".
+  # content_encoding = "gzip"
+
+  ## Optional HTTP Basic Auth Credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Optional SSL Config, used with https hosts
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Sanitization of metric names, tag keys and tag values:
+  ##   basic: replaces the most common invalid characters, "@*%#$" with "-"
+  ##     and spaces and ":" with "_".
+  ##   strict: replaces any character OpenTSDB doesn't allow, anything else
+  ##     than letters, digits and "-_./", with sanitize_replacement.
+  ##   none: sends names unchanged.
+  # sanitize = "basic"
+  # sanitize_replacement = "_"
+
   ## Debug true - Prints OpenTSDB communication
   debug = false
 `
@@ -65,6 +137,23 @@ func (o *OpenTSDB) Connect() error {
 		return fmt.Errorf("Error in parsing host url: %s", err.Error())
 	}
 
+	switch o.Sanitize {
+	case "", "basic", "strict", "none":
+	default:
+		return fmt.Errorf("OpenTSDB: invalid sanitize %q", o.Sanitize)
+	}
+	switch o.ContentEncoding {
+	case "", "gzip", "identity":
+	default:
+		return fmt.Errorf("OpenTSDB: invalid content encoding %q", o.ContentEncoding)
+	}
+
+	if u.Scheme == "http" || u.Scheme == "https" {
+		if err := o.initClient(); err != nil {
+			return err
+		}
+	}
+
 	uri := fmt.Sprintf("%s:%d", u.Host, o.Port)
 	tcpAddr, err := net.ResolveTCPAddr("tcp", uri)
 	if err != nil {
@@ -78,6 +167,27 @@ func (o *OpenTSDB) Connect() error {
 	return nil
 }
 
+func (o *OpenTSDB) initClient() error {
+	if o.Timeout.Duration == 0 {
+		o.Timeout.Duration = defaultTimeout
+	}
+
+	tlsCfg, err := internal.GetTLSConfig(
+		o.SSLCert, o.SSLKey, o.SSLCA, o.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+
+	o.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: o.Timeout.Duration,
+	}
+	return nil
+}
+
 func (o *OpenTSDB) Write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
@@ -90,24 +200,43 @@ func (o *OpenTSDB) Write(metrics []telegraf.Metric) error {
 
 	if u.Scheme == "" || u.Scheme == "tcp" {
 		return o.WriteTelnet(metrics, u)
-	} else if u.Scheme == "http" {
+	} else if u.Scheme == "http" || u.Scheme == "https" {
 		return o.WriteHttp(metrics, u)
 	} else {
 		return fmt.Errorf("Unknown scheme in host parameter.")
 	}
 }
 
+// WriteHttp writes the metrics with the Http API. Datapoints rejected by
+// OpenTSDB are sent again with the next writes, up to HttpRetries times.
 func (o *OpenTSDB) WriteHttp(metrics []telegraf.Metric, u *url.URL) error {
-	http := openTSDBHttp{
+	if o.client == nil {
+		if err := o.initClient(); err != nil {
+			return err
+		}
+	}
+
+	api := openTSDBHttp{
+		Scheme:    u.Scheme,
 		Host:      u.Host,
 		Port:      o.Port,
 		BatchSize: o.HttpBatchSize,
+		Gzip:      o.ContentEncoding != "identity",
+		Username:  o.Username,
+		Password:  o.Password,
 		Debug:     o.Debug,
+		client:    o.client,
+		log:       o.Log,
 	}
 
+	// rejected datapoints are sent first, and kept if the write fails.
+	retries := o.retries
+	o.retries = nil
+	datapoints := append([]*HttpMetric(nil), retries...)
+
 	for _, m := range metrics {
 		now := m.UnixNano() / 1000000000
-		tags := cleanTags(m.Tags())
+		tags := o.sanitizeTags(m.Tags())
 
 		for fieldName, value := range m.Fields() {
 			switch value.(type) {
@@ -115,28 +244,42 @@ func (o *OpenTSDB) WriteHttp(metrics []telegraf.Metric, u *url.URL) error {
 			case uint64:
 			case float64:
 			default:
-				log.Printf("D! OpenTSDB does not support metric value: [%s] of type [%T].\n", value, value)
+				o.Log.Debugf("Unsupported metric value: [%s] of type [%T]", value, value)
 				continue
 			}
 
-			metric := &HttpMetric{
-				Metric: sanitizedChars.Replace(fmt.Sprintf("%s%s_%s",
+			datapoints = append(datapoints, &HttpMetric{
+				Metric: o.sanitize(fmt.Sprintf("%s%s_%s",
 					o.Prefix, m.Name(), fieldName)),
 				Tags:      tags,
 				Timestamp: now,
 				Value:     value,
-			}
+			})
+		}
+	}
 
-			if err := http.sendDataPoint(metric); err != nil {
-				return err
-			}
+	for _, metric := range datapoints {
+		if err := api.sendDataPoint(metric); err != nil {
+			o.retries = retries
+			return err
 		}
 	}
 
-	if err := http.flush(); err != nil {
+	if err := api.flush(); err != nil {
+		o.retries = retries
 		return err
 	}
 
+	for _, metric := range api.rejected {
+		if metric.retries >= o.HttpRetries {
+			o.Log.Errorf("Dropping data point %s, rejected %d times",
+				metric.key(), metric.retries+1)
+			continue
+		}
+		metric.retries++
+		o.retries = append(o.retries, metric)
+	}
+
 	return nil
 }
 
@@ -152,17 +295,17 @@ func (o *OpenTSDB) WriteTelnet(metrics []telegraf.Metric, u *url.URL) error {
 
 	for _, m := range metrics {
 		now := m.UnixNano() / 1000000000
-		tags := ToLineFormat(cleanTags(m.Tags()))
+		tags := ToLineFormat(o.sanitizeTags(m.Tags()))
 
 		for fieldName, value := range m.Fields() {
 			metricValue, buildError := buildValue(value)
 			if buildError != nil {
-				log.Printf("E! OpenTSDB: %s\n", buildError.Error())
+				o.Log.Error(buildError.Error())
 				continue
 			}
 
 			messageLine := fmt.Sprintf("put %s %v %s %s\n",
-				sanitizedChars.Replace(fmt.Sprintf("%s%s_%s", o.Prefix, m.Name(), fieldName)),
+				o.sanitize(fmt.Sprintf("%s%s_%s", o.Prefix, m.Name(), fieldName)),
 				now, metricValue, tags)
 
 			_, err := connection.Write([]byte(messageLine))
@@ -175,14 +318,39 @@ func (o *OpenTSDB) WriteTelnet(metrics []telegraf.Metric, u *url.URL) error {
 	return nil
 }
 
-func cleanTags(tags map[string]string) map[string]string {
+func (o *OpenTSDB) sanitizeTags(tags map[string]string) map[string]string {
 	tagSet := make(map[string]string, len(tags))
 	for k, v := range tags {
-		tagSet[sanitizedChars.Replace(k)] = sanitizedChars.Replace(v)
+		tagSet[o.sanitize(k)] = o.sanitize(v)
 	}
 	return tagSet
 }
 
+func (o *OpenTSDB) sanitize(s string) string {
+	switch o.Sanitize {
+	case "strict":
+		return strictSanitize(s, o.SanitizeReplacement)
+	case "none":
+		return s
+	default:
+		return sanitizedChars.Replace(s)
+	}
+}
+
+// strictSanitize replaces each character OpenTSDB doesn't allow in metric
+// names and tags with replacement.
+func strictSanitize(s string, replacement string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_./", r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteString(replacement)
+		}
+	}
+	return b.String()
+}
+
 func buildValue(v interface{}) (string, error) {
 	var retv string
 	switch p := v.(type) {
@@ -224,6 +392,13 @@ func (o *OpenTSDB) Close() error {
 
 func init() {
 	outputs.Add("opentsdb", func() telegraf.Output {
-		return &OpenTSDB{}
+		return &OpenTSDB{
+			HttpBatchSize:       defaultHttpBatchSize,
+			HttpRetries:         defaultHttpRetries,
+			Timeout:             internal.Duration{Duration: defaultTimeout},
+			ContentEncoding:     "gzip",
+			Sanitize:            "basic",
+			SanitizeReplacement: "_",
+		}
 	})
 }
@@ -7,10 +7,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+
+	"github.com/influxdata/telegraf"
 )
 
 type HttpMetric struct {
@@ -18,21 +19,50 @@ type HttpMetric struct {
 	Timestamp int64             `json:"timestamp"`
 	Value     interface{}       `json:"value"`
 	Tags      map[string]string `json:"tags"`
+
+	// number of times the datapoint was sent again after being rejected
+	retries int
+}
+
+// key identifies the datapoint in the failures reported by OpenTSDB.
+func (m *HttpMetric) key() string {
+	return fmt.Sprintf("%s %d %s", m.Metric, m.Timestamp, ToLineFormat(m.Tags))
+}
+
+// putResponse is the response of /api/put?details when datapoints failed.
+type putResponse struct {
+	Failed int `json:"failed"`
+	Errors []struct {
+		Datapoint HttpMetric `json:"datapoint"`
+		Error     string     `json:"error"`
+	} `json:"errors"`
 }
 
 type openTSDBHttp struct {
+	Scheme    string
 	Host      string
 	Port      int
 	BatchSize int
+	Gzip      bool
+	Username  string
+	Password  string
 	Debug     bool
 
+	client        *http.Client
+	log           telegraf.Logger
 	metricCounter int
 	body          requestBody
+	// datapoints of the current batch
+	batch []*HttpMetric
+	// datapoints rejected by OpenTSDB
+	rejected []*HttpMetric
 }
 
 type requestBody struct {
 	b bytes.Buffer
 	g *gzip.Writer
+	// writer of the body, g or b
+	bw io.Writer
 
 	dbgB bytes.Buffer
 
@@ -42,20 +72,25 @@ type requestBody struct {
 	empty bool
 }
 
-func (r *requestBody) reset(debug bool) {
+func (r *requestBody) reset(debug bool, compress bool) {
 	r.b.Reset()
 	r.dbgB.Reset()
 
-	if r.g == nil {
+	if !compress {
+		r.g = nil
+		r.bw = &r.b
+	} else if r.g == nil {
 		r.g = gzip.NewWriter(&r.b)
+		r.bw = r.g
 	} else {
 		r.g.Reset(&r.b)
+		r.bw = r.g
 	}
 
 	if debug {
-		r.w = io.MultiWriter(r.g, &r.dbgB)
+		r.w = io.MultiWriter(r.bw, &r.dbgB)
 	} else {
-		r.w = r.g
+		r.w = r.bw
 	}
 
 	r.enc = json.NewEncoder(r.w)
@@ -82,6 +117,9 @@ func (r *requestBody) addMetric(metric *HttpMetric) error {
 func (r *requestBody) close() error {
 	io.WriteString(r.w, "]")
 
+	if r.g == nil {
+		return nil
+	}
 	if err := r.g.Close(); err != nil {
 		return fmt.Errorf("Error when closing gzip writer: %s", err.Error())
 	}
@@ -91,12 +129,14 @@ func (r *requestBody) close() error {
 
 func (o *openTSDBHttp) sendDataPoint(metric *HttpMetric) error {
 	if o.metricCounter == 0 {
-		o.body.reset(o.Debug)
+		o.body.reset(o.Debug, o.Gzip)
+		o.batch = o.batch[:0]
 	}
 
 	if err := o.body.addMetric(metric); err != nil {
 		return err
 	}
+	o.batch = append(o.batch, metric)
 
 	o.metricCounter++
 	if o.metricCounter == o.BatchSize {
@@ -118,21 +158,23 @@ func (o *openTSDBHttp) flush() error {
 	o.body.close()
 
 	u := url.URL{
-		Scheme: "http",
-		Host:   fmt.Sprintf("%s:%d", o.Host, o.Port),
-		Path:   "/api/put",
-	}
-
-	if o.Debug {
-		u.RawQuery = "details"
+		Scheme:   o.Scheme,
+		Host:     fmt.Sprintf("%s:%d", o.Host, o.Port),
+		Path:     "/api/put",
+		RawQuery: "details",
 	}
 
 	req, err := http.NewRequest("POST", u.String(), &o.body.b)
 	if err != nil {
 		return fmt.Errorf("Error when building request: %s", err.Error())
 	}
-	req.Header.Set("Content-Type", "applicaton/json")
-	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Type", "application/json")
+	if o.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if o.Username != "" || o.Password != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
 
 	if o.Debug {
 		dump, err := httputil.DumpRequestOut(req, false)
@@ -144,27 +186,37 @@ func (o *openTSDBHttp) flush() error {
 		fmt.Printf("Body:\n%s\n\n", o.body.dbgB.String())
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := o.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("Error when sending metrics: %s", err.Error())
 	}
 	defer resp.Body.Close()
 
 	if o.Debug {
-		dump, err := httputil.DumpResponse(resp, true)
+		dump, err := httputil.DumpResponse(resp, false)
 		if err != nil {
 			return fmt.Errorf("Error when dumping response: %s", err.Error())
 		}
 
 		fmt.Printf("Received response\n%s\n\n", dump)
-	} else {
-		// Important so http client reuse connection for next request if need be.
-		io.Copy(ioutil.Discard, resp.Body)
+	}
+
+	// Reading the whole body also lets the http client reuse the connection
+	// for the next request.
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error when reading response: %s", err.Error())
+	}
+	if o.Debug {
+		fmt.Printf("%s\n\n", body)
 	}
 
 	if resp.StatusCode/100 != 2 {
 		if resp.StatusCode/100 == 4 {
-			log.Printf("E! Received %d status code. Dropping metrics to avoid overflowing buffer.",
+			if o.reject(body) {
+				return nil
+			}
+			o.log.Errorf("Received %d status code. Dropping metrics to avoid overflowing buffer.",
 				resp.StatusCode)
 		} else {
 			return fmt.Errorf("Error when sending metrics. Received status %d",
@@ -174,3 +226,28 @@ func (o *openTSDBHttp) flush() error {
 
 	return nil
 }
+
+// reject adds the datapoints of the batch reported as failed in the details
+// of the response to the rejected datapoints. It returns false if the
+// response holds no details.
+func (o *openTSDBHttp) reject(body []byte) bool {
+	var details putResponse
+	if err := json.Unmarshal(body, &details); err != nil || len(details.Errors) == 0 {
+		return false
+	}
+
+	batch := make(map[string]*HttpMetric, len(o.batch))
+	for _, m := range o.batch {
+		batch[m.key()] = m
+	}
+
+	for _, e := range details.Errors {
+		key := e.Datapoint.key()
+		o.log.Errorf("Rejected data point %s: %s", key, e.Error)
+		if m, ok := batch[key]; ok {
+			o.rejected = append(o.rejected, m)
+			delete(batch, key)
+		}
+	}
+	return true
+}
@@ -1,21 +1,28 @@
 package opentsdb
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
-	//"github.com/stretchr/testify/require"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestCleanTags(t *testing.T) {
+func TestSanitizeTags(t *testing.T) {
 	var tagtests = []struct {
 		ptIn    map[string]string
 		outTags map[string]string
@@ -37,14 +44,34 @@ func TestCleanTags(t *testing.T) {
 			map[string]string{},
 		},
 	}
+	o := &OpenTSDB{}
 	for _, tt := range tagtests {
-		tags := cleanTags(tt.ptIn)
+		tags := o.sanitizeTags(tt.ptIn)
 		if !reflect.DeepEqual(tags, tt.outTags) {
 			t.Errorf("\nexpected %+v\ngot %+v\n", tt.outTags, tags)
 		}
 	}
 }
 
+func TestSanitize(t *testing.T) {
+	var tests = []struct {
+		sanitize string
+		in       string
+		out      string
+	}{
+		{"", "Sp%ci@l Chars", "Sp-ci-l_Chars"},
+		{"basic", "cpu=total,ü", "cpu=total,ü"},
+		{"strict", "cpu=total,ü", "cpu_total_ü"},
+		{"strict", "a.b-c_d/e", "a.b-c_d/e"},
+		{"strict", "g$t  repl#ced", "g_t__repl_ced"},
+		{"none", "g$t repl#ced", "g$t repl#ced"},
+	}
+	for _, tt := range tests {
+		o := &OpenTSDB{Sanitize: tt.sanitize, SanitizeReplacement: "_"}
+		assert.Equal(t, tt.out, o.sanitize(tt.in), tt.sanitize)
+	}
+}
+
 func TestBuildTagsTelnet(t *testing.T) {
 	var tagtests = []struct {
 		ptIn    map[string]string
@@ -106,6 +133,7 @@ func BenchmarkHttpSend(b *testing.B) {
 		Port:          port,
 		Prefix:        "",
 		HttpBatchSize: BatchSize,
+		Log:           testutil.Logger{Name: "outputs.opentsdb"},
 	}
 
 	b.ResetTimer()
@@ -114,6 +142,185 @@ func BenchmarkHttpSend(b *testing.B) {
 	}
 }
 
+// testAPI is an OpenTSDB Http API rejecting the datapoints of the metrics
+// named in reject.
+type testAPI struct {
+	sync.Mutex
+	reject   map[string]bool
+	requests []*http.Request
+	puts     [][]HttpMetric
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.Lock()
+	defer a.Unlock()
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+
+	var put []HttpMetric
+	if err := json.NewDecoder(body).Decode(&put); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a.requests = append(a.requests, r)
+	a.puts = append(a.puts, put)
+
+	var resp putResponse
+	for _, m := range put {
+		if a.reject[m.Metric] {
+			resp.Failed++
+			resp.Errors = append(resp.Errors, struct {
+				Datapoint HttpMetric `json:"datapoint"`
+				Error     string     `json:"error"`
+			}{m, "Unknown metric"})
+		}
+	}
+	if resp.Failed == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newTestOpenTSDB(t *testing.T, ts *httptest.Server) *OpenTSDB {
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	host, p, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(p)
+	require.NoError(t, err)
+
+	return &OpenTSDB{
+		Host:          u.Scheme + "://" + host,
+		Port:          port,
+		HttpBatchSize: 50,
+		HttpRetries:   1,
+		Log:           testutil.Logger{Name: "outputs.opentsdb"},
+	}
+}
+
+func newTestMetric(name string, value interface{}) telegraf.Metric {
+	m, _ := telegraf.NewMetric(name,
+		map[string]string{"host": "server 01"},
+		map[string]interface{}{"value": value},
+		time.Unix(1289430000, 0),
+	)
+	return m
+}
+
+func putMetrics(put []HttpMetric) []string {
+	var names []string
+	for _, m := range put {
+		names = append(names, m.Metric)
+	}
+	return names
+}
+
+func TestWriteHttp(t *testing.T) {
+	api := &testAPI{}
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	o := newTestOpenTSDB(t, ts)
+	o.Username = "telegraf"
+	o.Password = "secret"
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write([]telegraf.Metric{newTestMetric("cpu", 1.5)}))
+
+	require.Equal(t, 1, len(api.requests))
+	req := api.requests[0]
+	assert.Equal(t, "/api/put", req.URL.Path)
+	assert.Equal(t, "details", req.URL.RawQuery)
+	assert.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+	user, pass, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "telegraf", user)
+	assert.Equal(t, "secret", pass)
+	assert.Equal(t, []HttpMetric{{
+		Metric:    "cpu_value",
+		Timestamp: 1289430000,
+		Value:     1.5,
+		Tags:      map[string]string{"host": "server_01"},
+	}}, api.puts[0])
+
+	o.ContentEncoding = "identity"
+	require.NoError(t, o.Write([]telegraf.Metric{newTestMetric("cpu", 1.5)}))
+	require.Equal(t, 2, len(api.requests))
+	assert.Equal(t, "", api.requests[1].Header.Get("Content-Encoding"))
+}
+
+func TestWriteHttpRetriesRejected(t *testing.T) {
+	api := &testAPI{reject: map[string]bool{"unknown_value": true}}
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	o := newTestOpenTSDB(t, ts)
+	require.NoError(t, o.Write([]telegraf.Metric{
+		newTestMetric("cpu", 1.0),
+		newTestMetric("unknown", 2.0),
+	}))
+
+	// only the rejected datapoint is sent again, along with the new ones.
+	require.NoError(t, o.Write([]telegraf.Metric{newTestMetric("mem", 3.0)}))
+	require.NoError(t, o.Write([]telegraf.Metric{newTestMetric("disk", 4.0)}))
+
+	require.Equal(t, 3, len(api.puts))
+	assert.Equal(t, []string{"cpu_value", "unknown_value"}, putMetrics(api.puts[0]))
+	assert.Equal(t, []string{"unknown_value", "mem_value"}, putMetrics(api.puts[1]))
+	assert.Equal(t, []string{"disk_value"}, putMetrics(api.puts[2]))
+}
+
+func TestWriteHttpServerError(t *testing.T) {
+	var fail bool
+	api := &testAPI{reject: map[string]bool{"unknown_value": true}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		api.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	o := newTestOpenTSDB(t, ts)
+	require.NoError(t, o.Write([]telegraf.Metric{newTestMetric("unknown", 1.0)}))
+
+	fail = true
+	assert.Error(t, o.Write([]telegraf.Metric{newTestMetric("cpu", 1.0)}))
+
+	// rejected datapoints are kept when a write fails.
+	fail = false
+	require.NoError(t, o.Write([]telegraf.Metric{newTestMetric("cpu", 1.0)}))
+	require.Equal(t, 2, len(api.puts))
+	assert.Equal(t, []string{"unknown_value", "cpu_value"}, putMetrics(api.puts[1]))
+}
+
+func TestWriteHttps(t *testing.T) {
+	pki := testutil.NewPKI()
+	api := &testAPI{}
+	ts := httptest.NewUnstartedServer(api)
+	ts.TLS = pki.ServerTLSConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	o := newTestOpenTSDB(t, ts)
+	o.SSLCA = pki.CACertPath()
+	o.SSLCert = pki.ClientCertPath()
+	o.SSLKey = pki.ClientKeyPath()
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write([]telegraf.Metric{newTestMetric("cpu", 1.0)}))
+	assert.Equal(t, 1, len(api.puts))
+}
+
 // func TestWrite(t *testing.T) {
 // 	if testing.Short() {
 // 		t.Skip("Skipping integration test in short mode")
@@ -0,0 +1,222 @@
+package socket_writer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type SocketWriter struct {
+	Address         string
+	KeepAlivePeriod *internal.Duration
+	Timeout         internal.Duration
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	serializers.Serializer
+
+	net.Conn
+}
+
+func (sw *SocketWriter) Description() string {
+	return "Generic socket writer capable of handling multiple socket types."
+}
+
+func (sw *SocketWriter) SampleConfig() string {
+	return `
+  ## URL to connect to
+  # address = "tcp://127.0.0.1:8094"
+  # address = "tcp://example.com:http"
+  # address = "tcp4://127.0.0.1:8094"
+  # address = "tcp6://127.0.0.1:8094"
+  # address = "tcp6://[2001:db8::1]:8094"
+  # address = "udp://127.0.0.1:8094"
+  # address = "udp4://127.0.0.1:8094"
+  # address = "udp6://127.0.0.1:8094"
+  # address = "unix:///tmp/telegraf.sock"
+  # address = "unixgram:///tmp/telegraf.sock"
+
+  ## Optional SSL Config, for stream sockets only.
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Period between keep alive probes, for TCP sockets only.
+  ## 0 disables keep alive probes. Defaults to the OS configuration.
+  # keep_alive_period = "5m"
+
+  ## Timeout for connecting and writing. Defaults to no timeout.
+  # timeout = "5s"
+
+  ## Data format to generate.
+  ## Each data format has it's own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  # data_format = "influx"
+`
+}
+
+func (sw *SocketWriter) SetSerializer(s serializers.Serializer) {
+	sw.Serializer = s
+}
+
+func (sw *SocketWriter) Connect() error {
+	spl := strings.SplitN(sw.Address, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid address: %s", sw.Address)
+	}
+	protocol, address := spl[0], spl[1]
+
+	tlsConfig, err := internal.GetTLSConfig(
+		sw.SSLCert, sw.SSLKey, sw.SSLCA, sw.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: sw.Timeout.Duration}
+	if sw.KeepAlivePeriod != nil {
+		dialer.KeepAlive = sw.KeepAlivePeriod.Duration
+	}
+	var c net.Conn
+	switch protocol {
+	case "tcp", "tcp4", "tcp6", "unix":
+		if tlsConfig != nil {
+			c, err = tls.DialWithDialer(dialer, protocol, address, tlsConfig)
+		} else {
+			c, err = dialer.Dial(protocol, address)
+		}
+	case "udp", "udp4", "udp6", "unixgram":
+		if tlsConfig != nil {
+			return fmt.Errorf("SSL is not supported on %s sockets", protocol)
+		}
+		c, err = dialer.Dial(protocol, address)
+	default:
+		return fmt.Errorf("unknown protocol '%s' in '%s'", protocol, sw.Address)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := sw.disableKeepAlive(c); err != nil {
+		log.Printf("E! Unable to disable keep alive (%s): %s", sw.Address, err)
+	}
+
+	sw.Conn = c
+	return nil
+}
+
+// disableKeepAlive disables keep alive probes on a TCP connection if the
+// keep alive period is 0. Positive periods are set when dialing.
+func (sw *SocketWriter) disableKeepAlive(c net.Conn) error {
+	if sw.KeepAlivePeriod == nil || sw.KeepAlivePeriod.Duration != 0 {
+		return nil
+	}
+	if tcpc, ok := c.(*net.TCPConn); ok {
+		return tcpc.SetKeepAlive(false)
+	}
+	return nil
+}
+
+// isStream returns whether the connection is to a stream socket, rather than
+// a datagram socket.
+func (sw *SocketWriter) isStream() bool {
+	switch sw.Conn.(type) {
+	case *net.UDPConn:
+		return false
+	case *net.UnixConn:
+		return !strings.HasPrefix(sw.Address, "unixgram://")
+	}
+	return true
+}
+
+// Write writes the given metrics to the destination.
+// If an error is encountered, it is up to the caller to retry the same write
+// again later.
+// Not parallel safe.
+func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
+	if sw.Conn == nil {
+		// previous write failed with permanent error and socket was closed.
+		if err := sw.Connect(); err != nil {
+			return err
+		}
+	}
+
+	// stream sockets are written to in one go, while on datagram sockets each
+	// metric is a datagram.
+	stream := sw.isStream()
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		values, err := sw.Serialize(m)
+		if err != nil {
+			log.Printf("D! Could not serialize metric: %v", err)
+			continue
+		}
+		for _, value := range values {
+			buf.WriteString(value)
+			buf.WriteByte('\n')
+		}
+		if !stream {
+			if err := sw.write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+	}
+	if stream && buf.Len() > 0 {
+		return sw.write(buf.Bytes())
+	}
+	return nil
+}
+
+func (sw *SocketWriter) write(b []byte) error {
+	if sw.Timeout.Duration > 0 {
+		sw.SetWriteDeadline(time.Now().Add(sw.Timeout.Duration))
+	}
+	if _, err := sw.Conn.Write(b); err != nil {
+		// close the connection so that it is reestablished on the next write.
+		if nerr, ok := err.(net.Error); !ok || !nerr.Temporary() {
+			sw.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection. Noop if already closed.
+func (sw *SocketWriter) Close() error {
+	if sw.Conn == nil {
+		return nil
+	}
+	err := sw.Conn.Close()
+	sw.Conn = nil
+	return err
+}
+
+func newSocketWriter() *SocketWriter {
+	s, _ := serializers.NewInfluxSerializer()
+	return &SocketWriter{
+		Serializer: s,
+	}
+}
+
+func init() {
+	outputs.Add("socket_writer", func() telegraf.Output { return newSocketWriter() })
+}
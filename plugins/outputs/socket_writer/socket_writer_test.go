@@ -0,0 +1,230 @@
+package socket_writer
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketWriter_tcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "tcp://" + listener.Addr().String()
+	sw.KeepAlivePeriod = &internal.Duration{Duration: time.Minute}
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+
+	testSocketWriter_stream(t, sw, lconn)
+}
+
+func TestSocketWriter_tls(t *testing.T) {
+	pki := testutil.NewPKI()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", pki.ServerTLSConfig())
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "tcp://" + listener.Addr().String()
+	sw.SSLCA = pki.CACertPath()
+	sw.SSLCert = pki.ClientCertPath()
+	sw.SSLKey = pki.ClientKeyPath()
+
+	// the handshake completes once the listener side reads.
+	errs := make(chan error, 1)
+	go func() { errs <- sw.Connect() }()
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lconn.(*tls.Conn).Handshake()
+	}()
+	require.NoError(t, <-errs)
+	wg.Wait()
+
+	testSocketWriter_stream(t, sw, lconn)
+}
+
+func TestSocketWriter_udp(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "udp://" + listener.LocalAddr().String()
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	testSocketWriter_packet(t, sw, listener)
+}
+
+func TestSocketWriter_unix(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	sock := filepath.Join(tmpdir, "sw.TestSocketWriter_unix.sock")
+
+	listener, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "unix://" + sock
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+
+	testSocketWriter_stream(t, sw, lconn)
+}
+
+func TestSocketWriter_unixgram(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpdir)
+	sock := filepath.Join(tmpdir, "sw.TestSocketWriter_unixgram.sock")
+
+	listener, err := net.ListenPacket("unixgram", sock)
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "unixgram://" + sock
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	testSocketWriter_packet(t, sw, listener)
+}
+
+func testSocketWriter_stream(t *testing.T, sw *SocketWriter, lconn net.Conn) {
+	metrics := []telegraf.Metric{}
+	metrics = append(metrics, testutil.TestMetric(1, "test"))
+	mbs1out, _ := sw.Serialize(metrics[0])
+	metrics = append(metrics, testutil.TestMetric(2, "test"))
+	mbs2out, _ := sw.Serialize(metrics[1])
+
+	err := sw.Write(metrics)
+	require.NoError(t, err)
+
+	scnr := bufio.NewScanner(lconn)
+	require.True(t, scnr.Scan())
+	assert.Equal(t, mbs1out[0], scnr.Text())
+	require.True(t, scnr.Scan())
+	assert.Equal(t, mbs2out[0], scnr.Text())
+}
+
+func testSocketWriter_packet(t *testing.T, sw *SocketWriter, lconn net.PacketConn) {
+	metrics := []telegraf.Metric{}
+	metrics = append(metrics, testutil.TestMetric(1, "test"))
+	mbs1out, _ := sw.Serialize(metrics[0])
+	metrics = append(metrics, testutil.TestMetric(2, "test"))
+	mbs2out, _ := sw.Serialize(metrics[1])
+
+	err := sw.Write(metrics)
+	require.NoError(t, err)
+
+	// each metric is a datagram.
+	buf := make([]byte, 256)
+	for _, expected := range []string{mbs1out[0], mbs2out[0]} {
+		n, _, err := lconn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Equal(t, expected+"\n", string(buf[:n]))
+	}
+}
+
+func TestSocketWriter_Write_err(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "tcp://" + listener.Addr().String()
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "testerr")}
+
+	// close the other end of the socket to generate an error. The first
+	// writes may succeed before the connection is known to be reset.
+	listener.Close()
+	lconn.Close()
+	for i := 0; i < 10 && err == nil; i++ {
+		err = sw.Write(metrics)
+		time.Sleep(time.Millisecond * 10)
+	}
+	require.Error(t, err)
+	assert.Nil(t, sw.Conn)
+}
+
+func TestSocketWriter_Write_reconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "tcp://" + listener.Addr().String()
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+	lconn.Close()
+	sw.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	var lerr error
+	go func() {
+		lconn, lerr = listener.Accept()
+		wg.Done()
+	}()
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "testerr")}
+	err = sw.Write(metrics)
+	require.NoError(t, err)
+
+	wg.Wait()
+	assert.NoError(t, lerr)
+
+	mbsout, _ := sw.Serialize(metrics[0])
+	buf := make([]byte, 256)
+	n, err := lconn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, mbsout[0]+"\n", string(buf[:n]))
+}
+
+func TestSocketWriter_badConfig(t *testing.T) {
+	pki := testutil.NewPKI()
+	for _, sw := range []*SocketWriter{
+		{Address: "127.0.0.1:8094"},
+		{Address: "foo://127.0.0.1:8094"},
+		{Address: "udp://127.0.0.1:8094", SSLCA: pki.CACertPath()},
+	} {
+		assert.Error(t, sw.Connect(), sw.Address)
+	}
+}
@@ -2,6 +2,7 @@ package nagios
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -74,20 +75,21 @@ func (p *NagiosParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 			tags["unit"] = string(perf[0][3])
 		}
 		fields := make(map[string]interface{})
-		fields["value"] = perf[0][2]
+		value, err := strconv.ParseFloat(string(perf[0][2]), 64)
+		if err != nil {
+			continue
+		}
+		fields["value"] = value
 		// TODO should we set empty field
 		// if metric if there is no data ?
-		if perf[0][4] != nil {
-			fields["warning"] = perf[0][4]
-		}
-		if perf[0][5] != nil {
-			fields["critical"] = perf[0][5]
-		}
-		if perf[0][6] != nil {
-			fields["min"] = perf[0][6]
-		}
-		if perf[0][7] != nil {
-			fields["max"] = perf[0][7]
+		// Thresholds given as ranges aren't numbers, and are not set.
+		for i, key := range []string{"warning", "critical", "min", "max"} {
+			if perf[0][i+4] == nil {
+				continue
+			}
+			if v, err := strconv.ParseFloat(string(perf[0][i+4]), 64); err == nil {
+				fields[key] = v
+			}
 		}
 		// Create metric
 		metric, err := telegraf.NewMetric(fieldName, tags, fields, time.Now().UTC())
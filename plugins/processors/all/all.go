@@ -1,5 +1,6 @@
 package all
 
 import (
+	_ "github.com/influxdata/telegraf/plugins/processors/external"
 	_ "github.com/influxdata/telegraf/plugins/processors/printer"
 )
@@ -0,0 +1,125 @@
+package external
+
+import (
+	"errors"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/external"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Command of the external processor, and its arguments. The processor is
+  ## a program serving the external plugin protocol, such as one built with
+  ## the Go SDK in plugins/external/sdk.
+  command = ["/usr/local/bin/my-processor", "--config", "/etc/my-processor.conf"]
+
+  ## Environment variables set for the command, in addition to the ones of
+  ## telegraf, as "NAME=value".
+  # environment = []
+
+  ## Timeout for each request to the plugin to complete. The metrics are
+  ## passed on unchanged if the plugin does not respond in time.
+  # timeout = "5s"
+
+  ## Delay before restarting the plugin once it exits.
+  # restart_delay = "10s"
+
+  ## Interval at which the plugin is health checked, the plugin being
+  ## restarted if it does not respond. Set to "0s" to disable health checks.
+  # health_check_interval = "30s"
+`
+
+// External is a processor running as a separate process. The process is
+// started with the first metrics to process.
+type External struct {
+	Command             []string
+	Environment         []string
+	Timeout             internal.Duration
+	RestartDelay        internal.Duration
+	HealthCheckInterval internal.Duration
+
+	Log telegraf.Logger `toml:"-"`
+
+	process *external.Process
+}
+
+func NewExternal() *External {
+	return &External{
+		Timeout:             internal.Duration{Duration: 5 * time.Second},
+		RestartDelay:        internal.Duration{Duration: 10 * time.Second},
+		HealthCheckInterval: internal.Duration{Duration: 30 * time.Second},
+	}
+}
+
+func (e *External) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *External) Description() string {
+	return "Run an external processor plugin as a separate process"
+}
+
+func (e *External) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("command is required")
+	}
+	return nil
+}
+
+// Apply sends the metrics to the plugin, returning the metrics it returns.
+// The metrics are returned unchanged if the plugin fails.
+func (e *External) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if e.process == nil {
+		process := &external.Process{
+			Command:             e.Command,
+			Environment:         e.Environment,
+			Kind:                external.KindProcessor,
+			Timeout:             e.Timeout.Duration,
+			RestartDelay:        e.RestartDelay.Duration,
+			HealthCheckInterval: e.HealthCheckInterval.Duration,
+			Log:                 e.Log,
+		}
+		if err := process.Start(); err != nil {
+			e.Log.Errorf("Error starting plugin: %s", err)
+			return in
+		}
+		e.process = process
+	}
+
+	lines, err := external.EncodeMetrics(in)
+	if err != nil {
+		e.Log.Errorf("Error encoding metrics: %s", err)
+		return in
+	}
+	resp, err := e.process.Request(external.Request{
+		Type:    external.RequestApply,
+		Metrics: lines,
+	})
+	if err != nil {
+		e.Log.Errorf("Error applying plugin: %s", err)
+		return in
+	}
+	out, err := external.DecodeMetrics(resp.Metrics)
+	if err != nil {
+		e.Log.Errorf("Error in metrics from plugin: %s", err)
+		return in
+	}
+	return out
+}
+
+// Stop stops the plugin.
+func (e *External) Stop() {
+	if e.process != nil {
+		e.process.Stop()
+		e.process = nil
+	}
+}
+
+func init() {
+	processors.Add("external", func() telegraf.Processor {
+		return NewExternal()
+	})
+}
@@ -0,0 +1,77 @@
+package external
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/external/sdk"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary runs as the external processor when this environment
+// variable is set.
+const pluginEnv = "TELEGRAF_TEST_EXTERNAL_PROCESSOR"
+
+// testProcessor tags the metrics.
+type testProcessor struct{}
+
+func (p *testProcessor) SampleConfig() string { return "" }
+func (p *testProcessor) Description() string  { return "" }
+func (p *testProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.AddTag("processed", "true")
+	}
+	return in
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(pluginEnv) != "" {
+		if err := sdk.Serve(&testProcessor{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newMetric() telegraf.Metric {
+	m, _ := telegraf.NewMetric("cpu", map[string]string{"host": "a"},
+		map[string]interface{}{"value": 1.0}, time.Unix(0, 1))
+	return m
+}
+
+func TestInitNoCommand(t *testing.T) {
+	assert.Error(t, NewExternal().Init())
+}
+
+func TestApply(t *testing.T) {
+	e := NewExternal()
+	e.Command = []string{os.Args[0]}
+	e.Environment = []string{pluginEnv + "=1"}
+	e.Log = testutil.Logger{Name: "processors.external"}
+	require.NoError(t, e.Init())
+	defer e.Stop()
+
+	out := e.Apply(newMetric())
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{"host": "a", "processed": "true"},
+		out[0].Tags())
+	assert.Equal(t, int64(1), out[0].Time().UnixNano())
+}
+
+func TestApplyStartError(t *testing.T) {
+	e := NewExternal()
+	e.Command = []string{"/nonexistent/processor"}
+	e.Log = testutil.Logger{Name: "processors.external"}
+
+	in := newMetric()
+	out := e.Apply(in)
+	require.Len(t, out, 1)
+	assert.Equal(t, in, out[0])
+}
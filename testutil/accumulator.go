@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -39,6 +41,13 @@ func (a *Accumulator) NMetrics() uint64 {
 	return atomic.LoadUint64(&a.nMetrics)
 }
 
+// Wait waits for at least n metrics to be added, for up to five seconds.
+func (a *Accumulator) Wait(n int) {
+	for i := 0; i < 500 && a.NMetrics() < uint64(n); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func (a *Accumulator) ClearMetrics() {
 	atomic.StoreUint64(&a.nMetrics, 0)
 	a.Lock()
@@ -120,6 +129,48 @@ func (a *Accumulator) AddError(err error) {
 	a.Unlock()
 }
 
+// WithTracking returns a TrackingAccumulator adding metrics to a, which
+// reports each group as delivered as soon as it is added.
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &TrackingAccumulator{
+		Accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// TrackingAccumulator defines a mocked out tracking accumulator
+type TrackingAccumulator struct {
+	*Accumulator
+	delivered chan telegraf.DeliveryInfo
+	lastID    uint64
+}
+
+func (a *TrackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	for _, m := range group {
+		a.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	id := telegraf.TrackingID(atomic.AddUint64(&a.lastID, 1))
+	a.delivered <- &deliveryInfo{id: id, delivered: true}
+	return id
+}
+
+func (a *TrackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (d *deliveryInfo) ID() telegraf.TrackingID {
+	return d.id
+}
+
+func (d *deliveryInfo) Delivered() bool {
+	return d.delivered
+}
+
 func (a *Accumulator) SetPrecision(precision, interval time.Duration) {
 	return
 }
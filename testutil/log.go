@@ -0,0 +1,56 @@
+package testutil
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is a telegraf.Logger for the tests of plugins, writing all the
+// messages with the log package.
+type Logger struct {
+	Name string
+}
+
+// Errorf logs an error message, formatted as by fmt.Printf.
+func (l Logger) Errorf(format string, args ...interface{}) {
+	l.print("E!", fmt.Sprintf(format, args...))
+}
+
+// Error logs an error message, formatted as by fmt.Print.
+func (l Logger) Error(args ...interface{}) {
+	l.print("E!", fmt.Sprint(args...))
+}
+
+// Warnf logs a warning message, formatted as by fmt.Printf.
+func (l Logger) Warnf(format string, args ...interface{}) {
+	l.print("W!", fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warning message, formatted as by fmt.Print.
+func (l Logger) Warn(args ...interface{}) {
+	l.print("W!", fmt.Sprint(args...))
+}
+
+// Infof logs an information message, formatted as by fmt.Printf.
+func (l Logger) Infof(format string, args ...interface{}) {
+	l.print("I!", fmt.Sprintf(format, args...))
+}
+
+// Info logs an information message, formatted as by fmt.Print.
+func (l Logger) Info(args ...interface{}) {
+	l.print("I!", fmt.Sprint(args...))
+}
+
+// Debugf logs a debug message, formatted as by fmt.Printf.
+func (l Logger) Debugf(format string, args ...interface{}) {
+	l.print("D!", fmt.Sprintf(format, args...))
+}
+
+// Debug logs a debug message, formatted as by fmt.Print.
+func (l Logger) Debug(args ...interface{}) {
+	l.print("D!", fmt.Sprint(args...))
+}
+
+func (l Logger) print(level, msg string) {
+	log.Printf("%s [%s] %s", level, l.Name, msg)
+}
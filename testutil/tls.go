@@ -0,0 +1,64 @@
+package testutil
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"runtime"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// PKI is a set of certificates for testing TLS: a CA, and a server and a
+// client certificate signed by it. The server certificate is valid for
+// localhost and 127.0.0.1.
+type PKI struct {
+	path string
+}
+
+// NewPKI returns the test certificates in testutil/pki.
+func NewPKI() *PKI {
+	_, file, _, _ := runtime.Caller(0)
+	return &PKI{path: filepath.Join(filepath.Dir(file), "pki")}
+}
+
+func (p *PKI) CACertPath() string {
+	return filepath.Join(p.path, "cacert.pem")
+}
+
+func (p *PKI) ServerCertPath() string {
+	return filepath.Join(p.path, "servercert.pem")
+}
+
+func (p *PKI) ServerKeyPath() string {
+	return filepath.Join(p.path, "serverkey.pem")
+}
+
+func (p *PKI) ClientCertPath() string {
+	return filepath.Join(p.path, "clientcert.pem")
+}
+
+func (p *PKI) ClientKeyPath() string {
+	return filepath.Join(p.path, "clientkey.pem")
+}
+
+// ServerTLSConfig returns the configuration of a server requiring client
+// certificates signed by the CA.
+func (p *PKI) ServerTLSConfig() *tls.Config {
+	c, err := internal.GetServerTLSConfig(p.ServerCertPath(), p.ServerKeyPath(),
+		[]string{p.CACertPath()})
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ClientTLSConfig returns the configuration of a client presenting the client
+// certificate and trusting the CA.
+func (p *PKI) ClientTLSConfig() *tls.Config {
+	c, err := internal.GetTLSConfig(p.ClientCertPath(), p.ClientKeyPath(),
+		p.CACertPath(), false)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}